@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// blobDir is the directory inside the uploads directory where the contents of
+// uploads are stored, once for each unique digest, when they are kept on local
+// disk.
+const blobDir = ".blobs"
+
+// migrateFile moves an upload stored in the old "<id>.<filename>" layout into
+// store, filling in its digest first if it is missing. It returns true if m
+// was changed.
+func migrateFile(dir string, store Store, m *Meta) (bool, error) {
+	old := filepath.Join(dir, m.filename())
+	if _, err := os.Stat(old); os.IsNotExist(err) {
+		if m.Digest == "" {
+			return false, err
+		}
+		// already migrated
+		return false, nil
+	}
+
+	changed := false
+	if m.Digest == "" {
+		if err := m.inspect(old); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	exists, err := store.Exists(m.Digest)
+	if err != nil {
+		return changed, err
+	}
+	if exists {
+		log.Printf("cache: %s is a duplicate, keeping one copy", m.filename())
+		return changed, os.Remove(old)
+	}
+	return changed, store.Import(m.Digest, old)
+}
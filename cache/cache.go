@@ -2,159 +2,331 @@
 package cache
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"mime"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/crypto/sha3"
+
+	"ktkr.us/pkg/airlift/thumb"
 )
 
 const SHASize = 64
 
+// sniffLen is the number of leading bytes used to detect content types.
+const sniffLen = 512
+
 // Config represents the configurable behavior that Cache needs to operate.
 type Config interface {
 	MaxAge() int
 	MaxSize() int64
 	MaxCount() int
+	StripMetadata() bool
 	Refresh()
 	ProcessHash(buf []byte) string
 }
 
 // Cache is an extremely naïve, map-based, fully in-memory key-value store
-// configured as a file cache. Only file locations are stored in memory.
-// Persistence is achieved through the file system. It is concurrent-access
-// safe through locking.
+// configured as a file cache. Only file metadata is stored in memory.
+// Persistence is achieved through the file system and an index journal kept
+// in the upload directory; the contents of files are kept in a Store. It is
+// concurrent-access safe through locking.
 type Cache struct {
 	// OnRemove is an arbitrary callback that is called whenever a file is
 	// successfully removed.
 	OnRemove func(id string)
 
 	*sync.RWMutex
-	size  int64                  // the total size of the files
-	dir   string                 // path of directory where files are stored
-	files map[string]os.FileInfo // map[id]filename
+	size  int64                // the total size of the unique blobs
+	dir   string               // path of directory where files are staged
+	store Store                // where the contents of files are kept
+	local bool                 // store is a LocalStore that moves with dir
+	index *index               // persistent metadata journal
+	files map[string]*Meta     // map[id]metadata
+	refs  map[string]int       // map[digest]number of uploads sharing the blob
+	tombs map[string]time.Time // map[id]time of expiry, for expired uploads
+	wake  chan struct{}        // tells WatchAges to recalculate its timer
+
+	// map[digest]number of uploads about to refer to the blob, which must not
+	// be removed in the meantime
+	pending map[string]int
+
+	partialMu   sync.Mutex      // guards chunked upload state on disk
+	partialBusy map[string]bool // chunked uploads currently receiving data
 }
 
-// New initializes and returns a new cache object rooted in dirPath.
-func New(dirPath string) (*Cache, error) {
+// New initializes and returns a new cache object rooted in dirPath that keeps
+// the contents of files in store. If store is nil, they are kept on local disk
+// in dirPath.
+func New(dirPath string, store Store) (*Cache, error) {
 	c := &Cache{
-		nil,
-		new(sync.RWMutex),
-		0,
-		dirPath,
-		make(map[string]os.FileInfo),
+		RWMutex:     new(sync.RWMutex),
+		store:       store,
+		local:       store == nil,
+		pending:     make(map[string]int),
+		wake:        make(chan struct{}, 1),
+		partialBusy: make(map[string]bool),
 	}
-
-	os.MkdirAll(dirPath, 0755)
-	dir, err := os.Open(dirPath)
-	if err != nil {
+	if err := c.load(dirPath); err != nil {
 		return nil, err
 	}
-	defer dir.Close()
-	fis, err := dir.Readdir(0)
+	return c, nil
+}
+
+// load reads the metadata index from dirPath, replacing the cache contents.
+func (c *Cache) load(dirPath string) error {
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+	ix, files, tombs, err := openIndex(dirPath)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	store := c.store
+	if c.local {
+		store = &LocalStore{Dir: filepath.Join(dirPath, blobDir)}
 	}
 
-	for _, fi := range fis {
-		c.size += fi.Size()
-		name := fi.Name()
-		id := strings.Split(name, ".")[0]
-		if id == "" {
-			log.Println("hidden file ", name)
+	var size int64
+	refs := make(map[string]int)
+	for id, m := range files {
+		changed, err := migrateFile(dirPath, store, m)
+		if err != nil {
+			log.Printf("cache: moving %s into blob store: %v (dropping it)", m.filename(), err)
+			delete(files, id)
+			ix.del(id)
 			continue
 		}
-		c.files[id] = fi
+		if changed {
+			ix.put(m)
+		}
+		if refs[m.Digest] == 0 {
+			size += m.Size
+		}
+		refs[m.Digest]++
 	}
 
-	return c, nil
+	if c.index != nil {
+		c.index.Close()
+	}
+	c.dir = dirPath
+	c.store = store
+	c.index = ix
+	c.files = files
+	c.refs = refs
+	c.tombs = tombs
+	c.size = size
+	log.Printf("cache: loaded %d uploads from index", len(files))
+	return nil
 }
 
 // Size returns the total number of bytes taken up by files in the cache.
+// Identical files are only counted once.
 func (c *Cache) Size() int64 {
 	c.RLock()
 	defer c.RUnlock()
 	return c.size
 }
 
-func (c *Cache) filePath(id string) string {
-	fi := c.files[id]
-	if fi == nil {
-		return ""
+// SizeOwnedBy returns the total number of bytes taken up by files uploaded by
+// the named user.
+func (c *Cache) SizeOwnedBy(owner string) int64 {
+	c.RLock()
+	defer c.RUnlock()
+	var n int64
+	for _, m := range c.files {
+		if m.Owner == owner {
+			n += m.Size
+		}
 	}
-	return filepath.Join(c.dir, fi.Name())
+	return n
 }
 
-// GetFile returns the path to the file with the given ID in the cache.
-func (c *Cache) Get(id string) string {
+// Open opens the contents of the file with the given ID for reading.
+func (c *Cache) Open(id string) (io.ReadSeekCloser, error) {
 	c.RLock()
-	defer c.RUnlock()
-	return c.filePath(id)
+	m := c.files[id]
+	store := c.store
+	c.RUnlock()
+	if m == nil {
+		return nil, ErrNotFound
+	}
+	return store.Open(m.Digest)
+}
+
+// SignedURL returns a link that downloads the contents of the file with the
+// given ID straight from the store for the next ttl. ErrNotSigner is returned
+// if the store can't do that.
+func (c *Cache) SignedURL(id string, ttl time.Duration) (string, error) {
+	c.RLock()
+	m := c.files[id]
+	store := c.store
+	c.RUnlock()
+	if m == nil {
+		return "", ErrNotFound
+	}
+	signer, ok := store.(Signer)
+	if !ok {
+		return "", ErrNotSigner
+	}
+	return signer.SignedURL(m.Digest, m.Name, m.ContentType, ttl)
 }
 
-// Stat returns the os.FileInfo associated with a file.
-func (c *Cache) Stat(id string) os.FileInfo {
+// Name returns the original name of the file with the given ID, or the empty
+// string if it doesn't exist.
+func (c *Cache) Name(id string) string {
 	c.RLock()
 	defer c.RUnlock()
-	return c.files[id]
+	if m := c.files[id]; m != nil {
+		return m.Name
+	}
+	return ""
 }
 
-// Put copies a file to disk with the given filename and returns its hash.
-func (c *Cache) Put(content io.Reader, filename string, conf Config) (string, error) {
-	os.MkdirAll(c.dir, 0700)
-	dest := filepath.Join(c.dir, filename)
-	destFile, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
-	if err != nil {
-		os.Remove(dest)
-		return "", err
+// Stat returns a copy of the metadata associated with a file, or nil if it
+// doesn't exist.
+func (c *Cache) Stat(id string) *Meta {
+	c.RLock()
+	defer c.RUnlock()
+	m := c.files[id]
+	if m == nil {
+		return nil
 	}
+	mm := *m
+	return &mm
+}
 
-	defer destFile.Close()
+// summer hashes everything written to it and keeps the first few bytes for
+// content type detection.
+type summer struct {
+	sha  sha3.ShakeHash
+	head []byte
+	sum  []byte
+}
 
-	sha := sha3.NewShake256()
-	w := io.MultiWriter(destFile, sha)
-	_, err = io.Copy(w, content)
-	if err != nil {
-		os.Remove(dest)
-		return "", err
+func newSummer() *summer {
+	return &summer{sha: sha3.NewShake256(), head: make([]byte, 0, sniffLen)}
+}
+
+func (s *summer) Write(p []byte) (int, error) {
+	if n := sniffLen - len(s.head); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		s.head = append(s.head, p[:n]...)
 	}
+	return s.sha.Write(p)
+}
 
-	var (
-		buf  = make([]byte, SHASize)
-		hash string
-	)
+// next reads the next SHASize bytes of hash output. Nothing more can be
+// written once it has been called.
+func (s *summer) next() []byte {
+	buf := make([]byte, SHASize)
+	s.sha.Read(buf)
+	if s.sum == nil {
+		s.sum = buf
+	}
+	return buf
+}
 
-	for {
-		sha.Read(buf)
-		hash = conf.ProcessHash(buf)
+// digest returns the hex-encoded first SHASize bytes of the hash.
+func (s *summer) digest() string {
+	if s.sum == nil {
+		s.next()
+	}
+	return hex.EncodeToString(s.sum)
+}
 
-		if _, exist := c.files[hash]; exist {
-			log.Printf("cache: collision detected with ID '%s' - regenerating", hash)
-		} else {
-			break
+// contentType guesses the MIME type of the contents, falling back to the
+// file extension of name if sniffing comes up empty. Images are sniffed the
+// same way the thumbnail cache does, so the type tells whether an upload can
+// have a thumbnail.
+func (s *summer) contentType(name string) string {
+	t := thumb.Sniff(s.head)
+	if t == "" {
+		t = http.DetectContentType(s.head)
+	}
+	if t == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(filepath.Ext(name)); byExt != "" {
+			t = byExt
 		}
 	}
+	return t
+}
 
-	destPath := filepath.Join(c.dir, hash+"."+filename)
-	if err := os.Rename(dest, destPath); err != nil {
-		os.Remove(dest)
+// Put copies a file to disk and returns its ID. The Name, Lang, Addr, Client,
+// Owner, DeleteHash and limit fields of info are recorded as given; the rest of
+// the metadata is filled in from the contents. If conf says so, metadata is
+// stripped from images first, and what was removed is listed in Stripped. An
+// image that can't be stripped isn't kept, and the error wraps
+// ErrNotStripped.
+func (c *Cache) Put(content io.Reader, info *Meta, conf Config) (string, error) {
+	os.MkdirAll(c.dir, 0700)
+	destFile, err := ioutil.TempFile(c.dir, ".upload-")
+	if err != nil {
 		return "", err
 	}
+	dest := destFile.Name()
 
-	fi, err := os.Stat(destPath)
+	sum := newSummer()
+	w := io.MultiWriter(destFile, sum)
+	size, err := io.Copy(w, content)
+	destFile.Close()
 	if err != nil {
 		os.Remove(dest)
 		return "", err
 	}
 
+	return c.commit(dest, size, sum, info, conf)
+}
+
+// commit moves the fully written file at dest into the cache under a new ID
+// derived from sum, pruning old files as configured.
+func (c *Cache) commit(dest string, size int64, sum *summer, info *Meta, conf Config) (string, error) {
+	var stripped []string
+	if conf.StripMetadata() {
+		removed, newSize, newSum, err := stripMetadata(dest)
+		if err != nil {
+			// keeping the metadata would go against what was asked for
+			os.Remove(dest)
+			return "", fmt.Errorf("%w: %v", ErrNotStripped, err)
+		}
+		if removed != nil {
+			stripped, size, sum = removed, newSize, newSum
+		}
+	}
+
+	m := &Meta{
+		Name:        info.Name,
+		ContentType: sum.contentType(info.Name),
+		Lang:        info.Lang,
+		Uploaded:    time.Now(),
+		Size:        size,
+		Digest:      sum.digest(),
+		Addr:        info.Addr,
+		Client:      info.Client,
+		Owner:       info.Owner,
+		DeleteHash:  info.DeleteHash,
+
+		Expires:      info.Expires,
+		MaxDownloads: info.MaxDownloads,
+		Stripped:     stripped,
+	}
+
 	//conf := config.Get()
 	if conf.MaxSize() > 0 {
-		_, err = c.CutToSize(conf.MaxSize() * 1024 * 1024)
+		_, err := c.CutToSize(conf.MaxSize() * 1024 * 1024)
 		if err != nil {
 			os.Remove(dest)
 			return "", err
@@ -162,27 +334,91 @@ func (c *Cache) Put(content io.Reader, filename string, conf Config) (string, er
 	}
 
 	if conf.MaxCount() > 0 {
-		_, err = c.CutToCount(conf.MaxCount() - 1)
+		_, err := c.CutToCount(conf.MaxCount() - 1)
 		if err != nil {
 			os.Remove(dest)
 			return "", err
 		}
 	}
 
+	// the contents are moved into the store without holding the lock, since
+	// that may mean uploading them somewhere else
 	c.Lock()
-	c.files[hash] = fi
-	c.size += fi.Size()
+	store := c.store
+	stored := c.refs[m.Digest] > 0
+	c.pending[m.Digest]++
 	c.Unlock()
+	if !stored {
+		if err := store.Import(m.Digest, dest); err != nil {
+			os.Remove(dest)
+			c.Lock()
+			c.unpend(m.Digest)
+			c.Unlock()
+			return "", err
+		}
+	} else {
+		os.Remove(dest)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.unpend(m.Digest)
+
+	buf := sum.sum
+	for {
+		m.ID = conf.ProcessHash(buf)
+
+		_, exist := c.files[m.ID]
+		_, gone := c.tombs[m.ID]
+		if exist || gone {
+			log.Printf("cache: collision detected with ID '%s' - regenerating", m.ID)
+			buf = sum.next()
+		} else {
+			break
+		}
+	}
+
+	if err := c.index.put(m); err != nil {
+		log.Print("cache: writing index: ", err)
+	}
+	c.files[m.ID] = m
+	if c.refs[m.Digest] == 0 {
+		c.size += m.Size
+	}
+	c.refs[m.Digest]++
+
+	if !m.Expires.IsZero() {
+		c.Reschedule()
+	}
+
+	return m.ID, nil
+}
 
-	return hash, nil
+func (c *Cache) unpend(digest string) {
+	if c.pending[digest]--; c.pending[digest] <= 0 {
+		delete(c.pending, digest)
+	}
 }
 
+// removeFile removes the file with the given ID, and its contents if no other
+// file shares them.
 func (c *Cache) removeFile(id string) error {
-	size := c.files[id].Size()
-	if err := os.Remove(c.filePath(id)); err != nil {
-		return err
+	m := c.files[id]
+	if c.refs[m.Digest] <= 1 {
+		// a new upload with the same contents may be about to refer to them
+		if c.pending[m.Digest] == 0 {
+			if err := c.store.Remove(m.Digest); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		delete(c.refs, m.Digest)
+		c.size -= m.Size
+	} else {
+		c.refs[m.Digest]--
+	}
+	if err := c.index.del(id); err != nil {
+		log.Print("cache: writing index: ", err)
 	}
-	c.size -= size
 	delete(c.files, id)
 	if c.OnRemove != nil {
 		c.OnRemove(id)
@@ -197,15 +433,15 @@ func (c *Cache) Remove(id string) error {
 	return c.removeFile(id)
 }
 
-// RemoveOlderThan removes all files in the cache that were modified before t,
+// RemoveOlderThan removes all files in the cache that were uploaded before t,
 // returning the IDs of the deleted files.  If an error is encountered while
 // deleting a file, it will not advance any further.
 func (c *Cache) RemoveOlderThan(t time.Time) ([]string, error) {
 	c.Lock()
 	defer c.Unlock()
 	ids := []string{}
-	for id, fi := range c.files {
-		if fi.ModTime().Before(t) {
+	for id, m := range c.files {
+		if m.Uploaded.Before(t) {
 			if err := c.removeFile(id); err != nil {
 				return nil, err
 			}
@@ -220,39 +456,42 @@ func (c *Cache) RemoveOlderThan(t time.Time) ([]string, error) {
 func (c *Cache) MaybeRemoveOlderThan(t time.Time) int {
 	c.RLock()
 	defer c.RUnlock()
-	ids := c.SortedIDs()
+	ids := c.sorted(func(*Meta) bool { return true })
 	for i, id := range ids {
-		fi := c.files[id]
-		if fi != nil && !fi.ModTime().Before(t) {
+		m := c.files[id]
+		if m != nil && !m.Uploaded.Before(t) {
 			return i
 		}
 	}
 	return 0
 }
 
-// RemoveNewest removes the most recently modified item in the cache. It
+// RemoveNewest removes the most recently uploaded item in the cache. It
 // returns the ID of the file that was removed and an error if one was
 // encountered.
 func (c *Cache) RemoveNewest() (string, error) {
-	if len(c.files) == 0 {
-		return "", nil
-	}
-	c.RLock()
-	var (
-		newest   os.FileInfo
-		newestID string
-	)
-	for id, fi := range c.files {
-		if newest != nil && fi.ModTime().After(newest.ModTime()) {
-			newest = fi
-			newestID = id
-		}
-	}
-	c.RUnlock()
+	return c.removeNewest(func(*Meta) bool { return true })
+}
 
+// RemoveNewestOwnedBy removes the most recent file uploaded by the named user,
+// returning its ID.
+func (c *Cache) RemoveNewestOwnedBy(owner string) (string, error) {
+	return c.removeNewest(func(m *Meta) bool { return m.Owner == owner })
+}
+
+func (c *Cache) removeNewest(keep func(*Meta) bool) (string, error) {
 	c.Lock()
 	defer c.Unlock()
-	return newestID, c.removeFile(newestID)
+	var newest *Meta
+	for _, m := range c.files {
+		if keep(m) && (newest == nil || m.Uploaded.After(newest.Uploaded)) {
+			newest = m
+		}
+	}
+	if newest == nil {
+		return "", nil
+	}
+	return newest.ID, c.removeFile(newest.ID)
 }
 
 // CutToSize removes the oldest file in the cache until the total size is at
@@ -282,10 +521,16 @@ func (c *Cache) MaybeCutToSize(n int64) (m int) {
 	defer c.RUnlock()
 	target := c.size - n
 	s := int64(0)
-	ids := c.SortedIDs()
+	ids := c.sorted(func(*Meta) bool { return true })
+	// space is only freed once every file sharing a blob is gone
+	gone := make(map[string]int)
 	for i := 0; i < len(ids) && s <= target; i++ {
 		m++
-		s += c.files[ids[i]].Size()
+		f := c.files[ids[i]]
+		gone[f.Digest]++
+		if gone[f.Digest] == c.refs[f.Digest] {
+			s += f.Size
+		}
 	}
 	return
 }
@@ -313,12 +558,12 @@ func (c *Cache) removeOldest() (string, error) {
 		return "", nil
 	}
 	var (
-		oldest   os.FileInfo
+		oldest   *Meta
 		oldestID string
 	)
-	for id, fi := range c.files {
-		if oldest == nil || fi.ModTime().Before(oldest.ModTime()) {
-			oldest = fi
+	for id, m := range c.files {
+		if oldest == nil || m.Uploaded.Before(oldest.Uploaded) {
+			oldest = m
 			oldestID = id
 		}
 	}
@@ -337,23 +582,176 @@ func (c *Cache) RemoveAll() error {
 	return nil
 }
 
-// WatchAges starts a blocking server that constantly watches for files to
-// become stale, and then deletes them. It should be run in its own goroutine.
+// maxReaperSleep is the longest WatchAges will wait before checking the
+// configuration again.
+const maxReaperSleep = 24 * time.Hour
+
+// WatchAges starts a blocking server that deletes files as soon as they
+// expire, either by being older than the configured maximum age or by passing
+// their own expiry time. It should be run in its own goroutine.
 func (c *Cache) WatchAges(conf Config) {
+	timer := time.NewTimer(0)
 	for {
+		select {
+		case <-timer.C:
+		case <-c.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+
 		//conf := config.Get()
 		conf.Refresh()
-		before := time.Now()
+		now := time.Now()
 		if conf.MaxAge() > 0 {
-			cutoff := before.Add(-time.Duration(conf.MaxAge()) * 24 * time.Hour)
+			cutoff := now.Add(-time.Duration(conf.MaxAge()) * 24 * time.Hour)
 			if _, err := c.RemoveOlderThan(cutoff); err != nil {
 				log.Print(err)
 			}
 		}
-		after := time.Now()
-		// execute next on the nearest day
-		time.Sleep(before.AddDate(0, 0, 1).Truncate(24 * time.Hour).Sub(after))
+		if err := c.removeExpired(now); err != nil {
+			log.Print(err)
+		}
+
+		d := maxReaperSleep
+		if next := c.nextExpiry(conf); !next.IsZero() && next.Sub(now) < d {
+			d = next.Sub(now)
+		}
+		timer.Reset(d)
+	}
+}
+
+// Reschedule makes WatchAges take another look at when the next file expires.
+// It should be called after the configuration changes.
+func (c *Cache) Reschedule() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// nextExpiry returns the time when the next file is due to expire, or the
+// zero time if none will.
+func (c *Cache) nextExpiry(conf Config) time.Time {
+	c.RLock()
+	defer c.RUnlock()
+	var next time.Time
+	for _, m := range c.files {
+		t := m.Expires
+		if conf.MaxAge() > 0 {
+			aged := m.Uploaded.Add(time.Duration(conf.MaxAge()) * 24 * time.Hour)
+			if t.IsZero() || aged.Before(t) {
+				t = aged
+			}
+		}
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
 	}
+	return next
+}
+
+// removeExpired removes every file whose own expiry time is not after now.
+func (c *Cache) removeExpired(now time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	for id, m := range c.files {
+		if !m.Expires.IsZero() && !m.Expires.After(now) {
+			if err := c.expire(id); err != nil {
+				return err
+			}
+		}
+	}
+
+	cutoff := now.Add(-tombstoneAge)
+	for id, t := range c.tombs {
+		if t.Before(cutoff) {
+			delete(c.tombs, id)
+		}
+	}
+	return nil
+}
+
+// expire removes a file that has run out of time or downloads, leaving a
+// tombstone behind.
+func (c *Cache) expire(id string) error {
+	if err := c.removeFile(id); err != nil {
+		return err
+	}
+	now := time.Now()
+	c.tombs[id] = now
+	if err := c.index.tomb(id, now); err != nil {
+		log.Print("cache: writing index: ", err)
+	}
+	return nil
+}
+
+// Expire removes a file that has run out of time or downloads. Afterwards,
+// Gone will report true for its ID.
+func (c *Cache) Expire(id string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.files[id]; !ok {
+		return nil
+	}
+	return c.expire(id)
+}
+
+// Gone returns true if a file with the given ID used to exist but expired.
+func (c *Cache) Gone(id string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	_, ok := c.tombs[id]
+	return ok
+}
+
+var (
+	ErrNotFound = errors.New("ID not found")
+	ErrGone     = errors.New("this upload has expired")
+
+	// ErrNotStripped is returned for images whose metadata should be
+	// removed but couldn't be, such as ones that are damaged.
+	ErrNotStripped = errors.New("metadata couldn't be removed from the image")
+)
+
+// Hit records a download of the file with the given ID and returns its
+// metadata. If last is true, that was the final download allowed and the file
+// should be removed with Expire once it has been sent. ErrGone is returned if
+// the file has expired or has no downloads left.
+func (c *Cache) Hit(id string) (m *Meta, last bool, err error) {
+	c.Lock()
+	defer c.Unlock()
+
+	mm := c.files[id]
+	if mm == nil {
+		if _, ok := c.tombs[id]; ok {
+			return nil, false, ErrGone
+		}
+		return nil, false, ErrNotFound
+	}
+
+	if !mm.Expires.IsZero() && !mm.Expires.After(time.Now()) ||
+		mm.MaxDownloads > 0 && mm.Downloads >= mm.MaxDownloads {
+		if err := c.expire(id); err != nil {
+			log.Print(err)
+		}
+		return nil, false, ErrGone
+	}
+
+	if mm.MaxDownloads > 0 {
+		mm.Downloads++
+		if err := c.index.put(mm); err != nil {
+			log.Print("cache: writing index: ", err)
+		}
+		last = mm.Downloads == mm.MaxDownloads
+	}
+
+	m = new(Meta)
+	*m = *mm
+	return m, last, nil
 }
 
 // Len returns the number of files in the cache.
@@ -363,42 +761,54 @@ func (c *Cache) Len() int {
 	return len(c.files)
 }
 
-type byModtime struct {
-	ids []string
-	fis []os.FileInfo
-}
+type byUploaded []*Meta
+
+func (s byUploaded) Len() int           { return len(s) }
+func (s byUploaded) Less(i, j int) bool { return s[i].Uploaded.Before(s[j].Uploaded) }
+func (s byUploaded) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
-func (s byModtime) Len() int { return len(s.ids) }
-func (s byModtime) Less(i, j int) bool {
-	a := s.fis[i].ModTime()
-	b := s.fis[j].ModTime()
-	return a.Before(b)
+// SortedIDs returns a slice of every cached file's ID, sorted ascending by
+// upload time.
+func (c *Cache) SortedIDs() []string {
+	return c.sortedIDs(func(*Meta) bool { return true })
 }
-func (s byModtime) Swap(i, j int) {
-	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
-	s.fis[i], s.fis[j] = s.fis[j], s.fis[i]
+
+// SortedIDsOwnedBy is like SortedIDs, but only returns the files uploaded by
+// the named user.
+func (c *Cache) SortedIDsOwnedBy(owner string) []string {
+	return c.sortedIDs(func(m *Meta) bool { return m.Owner == owner })
 }
 
-// SortedIDs returns a slice of every cached file's ID, sorted descending by modification
-// time.
-func (c *Cache) SortedIDs() []string {
+func (c *Cache) sortedIDs(keep func(*Meta) bool) []string {
 	c.RLock()
 	defer c.RUnlock()
-	ids := make([]string, 0, len(c.files))
-	fis := make([]os.FileInfo, 0, len(c.files))
-	for id := range c.files {
-		ids = append(ids, id)
-		fis = append(fis, c.files[id])
+	return c.sorted(keep)
+}
+
+// sorted is sortedIDs for callers already holding the lock.
+func (c *Cache) sorted(keep func(*Meta) bool) []string {
+	ms := make(byUploaded, 0, len(c.files))
+	for _, m := range c.files {
+		if keep(m) {
+			ms = append(ms, m)
+		}
 	}
-	s := byModtime{ids, fis}
+	sort.Sort(ms)
 
-	sort.Sort(s)
-	return s.ids
+	ids := make([]string, len(ms))
+	for i, m := range ms {
+		ids[i] = m.ID
+	}
+	return ids
 }
 
-// SetDir sets the base directory where files will be stored on disk.
-func (c *Cache) SetDir(dir string) {
+// SetDir sets the base directory where files will be stored on disk and loads
+// the index found there.
+func (c *Cache) SetDir(dir string) error {
 	c.Lock()
-	c.dir = dir
-	c.Unlock()
+	defer c.Unlock()
+	if dir == c.dir {
+		return nil
+	}
+	return c.load(dir)
 }
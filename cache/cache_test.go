@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blobs returns the number of unique contents kept on local disk.
+func blobs(t *testing.T, c *Cache) int {
+	matches, err := filepath.Glob(filepath.Join(c.dir, blobDir, "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(matches)
+}
+
+func put(t *testing.T, c *Cache, contents string, info *Meta) string {
+	id, err := c.Put(strings.NewReader(contents), info, testConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestPutDuplicate(t *testing.T) {
+	c := newTestCache(t)
+	a := put(t, c, "the same thing", &Meta{Name: "a.txt"})
+	b := put(t, c, "the same thing", &Meta{Name: "b.txt"})
+	put(t, c, "something else", &Meta{Name: "c.txt"})
+
+	if a == b {
+		t.Fatalf("both copies have the ID %s", a)
+	}
+	if c.Stat(a).Digest != c.Stat(b).Digest {
+		t.Error("copies have different digests")
+	}
+	if want := int64(len("the same thing") + len("something else")); c.Size() != want {
+		t.Errorf("size is %d, want %d", c.Size(), want)
+	}
+	if n := blobs(t, c); n != 2 {
+		t.Errorf("%d blobs are stored, want 2", n)
+	}
+	if c.Len() != 3 {
+		t.Errorf("%d uploads are kept, want 3", c.Len())
+	}
+}
+
+func TestRemoveDuplicate(t *testing.T) {
+	c := newTestCache(t)
+	a := put(t, c, "the same thing", &Meta{Name: "a.txt"})
+	b := put(t, c, "the same thing", &Meta{Name: "b.txt"})
+
+	if err := c.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if n := blobs(t, c); n != 1 {
+		t.Fatalf("%d blobs are left after removing one copy, want 1", n)
+	}
+	if c.Size() != int64(len("the same thing")) {
+		t.Errorf("size is %d after removing one copy", c.Size())
+	}
+	f, err := c.Open(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil || string(got) != "the same thing" {
+		t.Errorf("the other copy reads %q, %v", got, err)
+	}
+
+	if err := c.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	if n := blobs(t, c); n != 0 {
+		t.Errorf("%d blobs are left after removing the last copy", n)
+	}
+	if c.Size() != 0 {
+		t.Errorf("size is %d after removing the last copy", c.Size())
+	}
+}
+
+func TestRemovePending(t *testing.T) {
+	c := newTestCache(t)
+	a := put(t, c, "the same thing", &Meta{Name: "a.txt"})
+	digest := c.Stat(a).Digest
+
+	// another upload of the same contents has seen the blob, but hasn't
+	// referred to it yet
+	c.Lock()
+	c.pending[digest]++
+	c.Unlock()
+
+	if err := c.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if n := blobs(t, c); n != 1 {
+		t.Fatalf("blob was removed while an upload was about to use it")
+	}
+
+	c.Lock()
+	c.unpend(digest)
+	c.Unlock()
+	put(t, c, "the same thing", &Meta{Name: "b.txt"})
+	if n := blobs(t, c); n != 1 {
+		t.Errorf("%d blobs are stored, want 1", n)
+	}
+	if c.Size() != int64(len("the same thing")) {
+		t.Errorf("size is %d", c.Size())
+	}
+}
+
+func TestHitLast(t *testing.T) {
+	c := newTestCache(t)
+	id := put(t, c, "twice", &Meta{Name: "a.txt", MaxDownloads: 2})
+	other := put(t, c, "unlimited", &Meta{Name: "b.txt"})
+
+	if _, last, err := c.Hit(id); err != nil || last {
+		t.Fatalf("first download: last is %v, %v", last, err)
+	}
+	m, last, err := c.Hit(id)
+	if err != nil || !last {
+		t.Fatalf("second download: last is %v, %v", last, err)
+	}
+	if m.Downloads != 2 {
+		t.Errorf("downloads is %d, want 2", m.Downloads)
+	}
+	if err := c.Expire(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Stat(id) != nil {
+		t.Error("upload is still there")
+	}
+	if !c.Gone(id) {
+		t.Error("upload isn't Gone")
+	}
+	if _, _, err := c.Hit(id); err != ErrGone {
+		t.Errorf("third download: got %v, want ErrGone", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, last, err := c.Hit(other); err != nil || last {
+			t.Errorf("unlimited upload: last is %v, %v", last, err)
+		}
+	}
+	if _, _, err := c.Hit("nothing"); err != ErrNotFound || c.Gone("nothing") {
+		t.Errorf("upload that never existed: got %v", err)
+	}
+}
+
+func TestHitWithoutExpire(t *testing.T) {
+	c := newTestCache(t)
+	counted := put(t, c, "once", &Meta{Name: "a.txt", MaxDownloads: 1})
+	expired := put(t, c, "late", &Meta{Name: "b.txt", Expires: time.Now().Add(-time.Second)})
+
+	// the last download was sent, but the server stopped before Expire
+	if _, last, err := c.Hit(counted); err != nil || !last {
+		t.Fatalf("download: last is %v, %v", last, err)
+	}
+	for _, id := range []string{counted, expired} {
+		if _, _, err := c.Hit(id); err != ErrGone {
+			t.Errorf("%s: got %v, want ErrGone", id, err)
+		}
+		if !c.Gone(id) || c.Stat(id) != nil {
+			t.Errorf("%s wasn't expired by the download", id)
+		}
+	}
+}
+
+func TestTombstoneNotReused(t *testing.T) {
+	c := newTestCache(t)
+	first := put(t, c, "the same thing", &Meta{Name: "a.txt", MaxDownloads: 1})
+	if err := c.Expire(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// the contents would be given the same ID again
+	if id := put(t, c, "the same thing", &Meta{Name: "a.txt"}); id == first {
+		t.Errorf("expired ID %s was used again", id)
+	}
+	if id := put(t, reopen(t, c.dir), "the same thing", &Meta{Name: "a.txt"}); id == first {
+		t.Errorf("expired ID %s was used again after reloading", id)
+	}
+	if !c.Gone(first) {
+		t.Error("expired upload isn't Gone")
+	}
+}
+
+func TestWatchAgesReschedule(t *testing.T) {
+	c := newTestCache(t)
+	later := put(t, c, "later", &Meta{Name: "a.txt", Expires: time.Now().Add(time.Hour)})
+	go c.WatchAges(testConfig{})
+
+	// give the reaper time to go to sleep until the later upload expires
+	time.Sleep(50 * time.Millisecond)
+	if next := c.nextExpiry(testConfig{}); !next.Equal(c.Stat(later).Expires) {
+		t.Errorf("next expiry is %v, want the later upload's", next)
+	}
+
+	sooner := put(t, c, "sooner", &Meta{Name: "b.txt", Expires: time.Now().Add(100 * time.Millisecond)})
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.Gone(sooner) {
+		if time.Now().After(deadline) {
+			t.Fatal("upload that expires sooner wasn't removed in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.Gone(later) || c.Stat(later) == nil {
+		t.Error("upload that expires later was removed")
+	}
+}
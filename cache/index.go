@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// indexName is the name of the metadata journal inside the uploads directory.
+// It starts with a dot so that it is never mistaken for an upload.
+const indexName = ".index"
+
+// Meta is the persistent metadata record kept for every upload.
+type Meta struct {
+	ID          string
+	Name        string    // original file name
+	ContentType string    // MIME type detected from the contents
+	Lang        string    // syntax highlighting language picked by the uploader
+	Uploaded    time.Time // time the upload completed
+	Size        int64
+	Digest      string // hex-encoded SHAKE256 digest of the contents
+	Addr        string // remote address of the uploader
+	Client      string // User-Agent of the uploading client
+	Owner       string // name of the uploading user, empty for older uploads
+	DeleteHash  string // hex-encoded SHA-256 of the upload's deletion token
+
+	// Expires is the time after which the upload is deleted, if not zero.
+	Expires time.Time
+	// MaxDownloads is how many times the upload may be downloaded before it
+	// is deleted, if not zero.
+	MaxDownloads int
+	Downloads    int
+
+	// Stripped lists the kinds of metadata that were removed from the
+	// image, if any.
+	Stripped []string `json:",omitempty"`
+}
+
+// Limited returns true if the upload will be deleted on its own terms rather
+// than by the global pruning settings.
+func (m *Meta) Limited() bool {
+	return !m.Expires.IsZero() || m.MaxDownloads > 0
+}
+
+// SetDeleteToken sets the token that lets whoever holds it delete the upload.
+// Only a hash of it is kept.
+func (m *Meta) SetDeleteToken(token string) {
+	sum := sha256.Sum256([]byte(token))
+	m.DeleteHash = hex.EncodeToString(sum[:])
+}
+
+// CheckDeleteToken returns true if token is the upload's deletion token.
+func (m *Meta) CheckDeleteToken(token string) bool {
+	if m.DeleteHash == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(m.DeleteHash)) == 1
+}
+
+// filename returns the base name of the upload's file on disk.
+func (m *Meta) filename() string {
+	return m.ID + "." + m.Name
+}
+
+// indexOp is a single entry in the index journal.
+type indexOp struct {
+	Op   string     `json:"op"` // "put", "del" or "tomb"
+	ID   string     `json:"id,omitempty"`
+	Meta *Meta      `json:"meta,omitempty"`
+	Gone *time.Time `json:"gone,omitempty"` // for "tomb"
+}
+
+// tombstoneAge is how long the IDs of expired uploads are remembered.
+const tombstoneAge = 30 * 24 * time.Hour
+
+// index is an append-only journal of metadata changes. It is compacted every
+// time it is loaded so that it only grows between restarts.
+type index struct {
+	path string
+	f    *os.File
+	enc  *json.Encoder
+}
+
+// openIndex loads the index journal in dir, returning the current set of
+// records and the tombstones of expired uploads. If no journal exists yet, one
+// is built by scanning the directory.
+func openIndex(dir string) (*index, map[string]*Meta, map[string]time.Time, error) {
+	ix := &index{path: filepath.Join(dir, indexName)}
+
+	files, tombs, err := ix.load()
+	if os.IsNotExist(err) {
+		log.Printf("cache: no index found in %s, rebuilding from directory listing", dir)
+		files, err = scanDir(dir)
+		tombs = make(map[string]time.Time)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	cutoff := time.Now().Add(-tombstoneAge)
+	for id, t := range tombs {
+		if t.Before(cutoff) {
+			delete(tombs, id)
+		}
+	}
+
+	if err = ix.compact(files, tombs); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return ix, files, tombs, nil
+}
+
+func (ix *index) load() (map[string]*Meta, map[string]time.Time, error) {
+	f, err := os.Open(ix.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	files := make(map[string]*Meta)
+	tombs := make(map[string]time.Time)
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var op indexOp
+		err := dec.Decode(&op)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// a torn write at the end of the journal only loses the last
+			// operation, so keep what we have
+			log.Printf("cache: index: %v (ignoring the rest)", err)
+			break
+		}
+		switch op.Op {
+		case "put":
+			if op.Meta != nil && op.Meta.ID != "" {
+				files[op.Meta.ID] = op.Meta
+			}
+		case "del":
+			delete(files, op.ID)
+		case "tomb":
+			delete(files, op.ID)
+			if op.Gone != nil {
+				tombs[op.ID] = *op.Gone
+			}
+		}
+	}
+
+	return files, tombs, nil
+}
+
+// compact rewrites the journal to contain only files and tombs and reopens it
+// for appending.
+func (ix *index) compact(files map[string]*Meta, tombs map[string]time.Time) error {
+	tmp := ix.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("writing index: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, m := range files {
+		if err = enc.Encode(&indexOp{Op: "put", Meta: m}); err != nil {
+			break
+		}
+	}
+	for id, t := range tombs {
+		if err != nil {
+			break
+		}
+		t := t
+		err = enc.Encode(&indexOp{Op: "tomb", ID: id, Gone: &t})
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing index: %v", err)
+	}
+	if err = os.Rename(tmp, ix.path); err != nil {
+		return fmt.Errorf("writing index: %v", err)
+	}
+
+	ix.f, err = os.OpenFile(ix.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening index: %v", err)
+	}
+	ix.enc = json.NewEncoder(ix.f)
+	return nil
+}
+
+func (ix *index) put(m *Meta) error {
+	return ix.enc.Encode(&indexOp{Op: "put", Meta: m})
+}
+
+func (ix *index) del(id string) error {
+	return ix.enc.Encode(&indexOp{Op: "del", ID: id})
+}
+
+func (ix *index) tomb(id string, t time.Time) error {
+	return ix.enc.Encode(&indexOp{Op: "tomb", ID: id, Gone: &t})
+}
+
+func (ix *index) Close() error {
+	if ix.f == nil {
+		return nil
+	}
+	return ix.f.Close()
+}
+
+// scanDir builds metadata records for a directory of uploads stored in the
+// old "<id>.<filename>" layout with no index. The upload time is taken from
+// the file's modification time, since that's all there is.
+func scanDir(dir string) (map[string]*Meta, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	fis, err := d.Readdir(0)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*Meta)
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		name := fi.Name()
+		parts := strings.SplitN(name, ".", 2)
+		if parts[0] == "" || len(parts) < 2 {
+			log.Println("cache: skipping", name)
+			continue
+		}
+
+		m := &Meta{
+			ID:       parts[0],
+			Name:     parts[1],
+			Uploaded: fi.ModTime(),
+			Size:     fi.Size(),
+		}
+		if err := m.inspect(filepath.Join(dir, name)); err != nil {
+			log.Printf("cache: reading %s: %v", name, err)
+		}
+		files[m.ID] = m
+	}
+
+	return files, nil
+}
+
+// inspect fills in the digest and content type of m from the file at path.
+func (m *Meta) inspect(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := newSummer()
+	if _, err = io.Copy(s, f); err != nil {
+		return err
+	}
+	m.Digest = s.digest()
+	m.ContentType = s.contentType(m.Name)
+	return nil
+}
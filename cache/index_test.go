@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// reopen loads the cache in dir again, as on a restart.
+func reopen(t *testing.T, dir string) *Cache {
+	c, err := New(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// journal returns the operations in the index journal in dir.
+func journal(t *testing.T, dir string) []indexOp {
+	f, err := os.Open(filepath.Join(dir, indexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ops []indexOp
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var op indexOp
+		if err := json.Unmarshal(s.Bytes(), &op); err != nil {
+			t.Fatalf("journal line %q: %v", s.Text(), err)
+		}
+		ops = append(ops, op)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
+
+func readAll(t *testing.T, c *Cache, id string) string {
+	f, err := c.Open(id)
+	if err != nil {
+		t.Fatalf("opening %s: %v", id, err)
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestIndexReload(t *testing.T) {
+	c := newTestCache(t)
+	a := put(t, c, "counted", &Meta{Name: "a.txt", MaxDownloads: 3})
+	b := put(t, c, "kept", &Meta{Name: "b.txt", Owner: "someone"})
+	removed := put(t, c, "removed", &Meta{Name: "c.txt"})
+	expired := put(t, c, "expired", &Meta{Name: "d.txt"})
+	if _, _, err := c.Hit(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Expire(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	r := reopen(t, c.dir)
+	if r.Len() != 2 {
+		t.Errorf("%d uploads were loaded, want 2", r.Len())
+	}
+	if m := r.Stat(a); m == nil || m.Name != "a.txt" || m.Downloads != 1 || m.MaxDownloads != 3 {
+		t.Errorf("counted upload was loaded as %+v", m)
+	}
+	if m := r.Stat(b); m == nil || m.Owner != "someone" || m.Digest != c.Stat(b).Digest {
+		t.Errorf("kept upload was loaded as %+v", m)
+	}
+	if r.Stat(removed) != nil || r.Stat(expired) != nil {
+		t.Error("an upload that was removed was loaded")
+	}
+	if !r.Gone(expired) || r.Gone(removed) {
+		t.Error("tombstones weren't loaded as they were")
+	}
+	if r.Size() != c.Size() {
+		t.Errorf("size is %d after reloading, was %d", r.Size(), c.Size())
+	}
+	if got := readAll(t, r, b); got != "kept" {
+		t.Errorf("kept upload reads %q", got)
+	}
+
+	// the journal only has what's left
+	puts, tombs := 0, 0
+	for _, op := range journal(t, c.dir) {
+		switch {
+		case op.Op == "put" && (op.Meta.ID == a || op.Meta.ID == b):
+			puts++
+		case op.Op == "tomb" && op.ID == expired:
+			tombs++
+		default:
+			t.Errorf("journal wasn't compacted: it has %+v", op)
+		}
+	}
+	if puts != 2 || tombs != 1 {
+		t.Errorf("compacted journal has %d uploads and %d tombstones, want 2 and 1", puts, tombs)
+	}
+}
+
+func TestIndexTorn(t *testing.T) {
+	c := newTestCache(t)
+	a := put(t, c, "first", &Meta{Name: "a.txt"})
+	b := put(t, c, "second", &Meta{Name: "b.txt"})
+
+	// the server stopped halfway through writing a third
+	f, err := os.OpenFile(filepath.Join(c.dir, indexName), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"op":"put","meta":{"ID":"torn","Na`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	r := reopen(t, c.dir)
+	if r.Len() != 2 || r.Stat(a) == nil || r.Stat(b) == nil {
+		t.Fatalf("loaded %d uploads, want the 2 before the torn line", r.Len())
+	}
+	if ops := journal(t, c.dir); len(ops) != 2 {
+		t.Errorf("journal has %d operations after compacting, want 2", len(ops))
+	}
+
+	// and it carries on from there
+	put(t, r, "third", &Meta{Name: "c.txt"})
+	if n := reopen(t, c.dir).Len(); n != 3 {
+		t.Errorf("loaded %d uploads, want 3", n)
+	}
+}
+
+func TestIndexOldTombstones(t *testing.T) {
+	dir := testDir(t)
+	old := time.Now().Add(-tombstoneAge - time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	f, err := os.Create(filepath.Join(dir, indexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(f)
+	enc.Encode(&indexOp{Op: "tomb", ID: "old", Gone: &old})
+	enc.Encode(&indexOp{Op: "tomb", ID: "recent", Gone: &recent})
+	f.Close()
+
+	c := reopen(t, dir)
+	if c.Gone("old") {
+		t.Error("tombstone older than tombstoneAge was kept")
+	}
+	if !c.Gone("recent") {
+		t.Error("recent tombstone was dropped")
+	}
+}
+
+func TestScanDir(t *testing.T) {
+	dir := testDir(t)
+	files := map[string]string{
+		"abcd.photo.txt": "the same thing",
+		"efgh.copy.txt":  "the same thing",
+		"ijkl.other.txt": "something else",
+		"README":         "not an upload",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uploaded := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "abcd.photo.txt"), uploaded, uploaded); err != nil {
+		t.Fatal(err)
+	}
+
+	c := reopen(t, dir)
+	if c.Len() != 3 {
+		t.Fatalf("found %d uploads, want 3", c.Len())
+	}
+	m := c.Stat("abcd")
+	if m == nil || m.Name != "photo.txt" || m.Size != int64(len("the same thing")) || !m.Uploaded.Equal(uploaded) {
+		t.Errorf("abcd was read as %+v", m)
+	}
+	if m != nil && m.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("content type is %q", m.ContentType)
+	}
+	for id, want := range map[string]string{"abcd": "the same thing", "efgh": "the same thing", "ijkl": "something else"} {
+		if got := readAll(t, c, id); got != want {
+			t.Errorf("%s reads %q, want %q", id, got, want)
+		}
+	}
+
+	// the old files have been moved into the blob store, once each
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if moved := os.IsNotExist(err); moved != (name != "README") {
+			t.Errorf("%s: moved is %v", name, moved)
+		}
+	}
+	if n := blobs(t, c); n != 2 {
+		t.Errorf("%d blobs are stored, want 2", n)
+	}
+	if want := int64(len("the same thing") + len("something else")); c.Size() != want {
+		t.Errorf("size is %d, want %d", c.Size(), want)
+	}
+
+	// starting again finds everything where it was left
+	r := reopen(t, dir)
+	if r.Len() != 3 {
+		t.Fatalf("loaded %d uploads after migrating, want 3", r.Len())
+	}
+	if got := readAll(t, r, "efgh"); got != "the same thing" {
+		t.Errorf("efgh reads %q after migrating", got)
+	}
+}
+
+func TestMigrateIndexed(t *testing.T) {
+	// an index written before contents were kept by digest
+	dir := testDir(t)
+	if err := ioutil.WriteFile(filepath.Join(dir, "abcd.a.txt"), []byte("contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, indexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := json.NewEncoder(f)
+	enc.Encode(&indexOp{Op: "put", Meta: &Meta{ID: "abcd", Name: "a.txt", Size: 8}})
+	enc.Encode(&indexOp{Op: "put", Meta: &Meta{ID: "gone", Name: "b.txt", Size: 8}})
+	f.Close()
+
+	c := reopen(t, dir)
+	if c.Stat("gone") != nil {
+		t.Error("upload whose file is missing was kept")
+	}
+	m := c.Stat("abcd")
+	if m == nil || m.Digest == "" {
+		t.Fatalf("abcd was loaded as %+v", m)
+	}
+	if got := readAll(t, c, "abcd"); got != "contents" {
+		t.Errorf("abcd reads %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "abcd.a.txt")); !os.IsNotExist(err) {
+		t.Errorf("old file is still there: %v", err)
+	}
+	if m := reopen(t, dir).Stat("abcd"); m == nil || m.Digest != c.Stat("abcd").Digest {
+		t.Errorf("digest wasn't written to the index: %+v", m)
+	}
+}
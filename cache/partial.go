@@ -0,0 +1,270 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// partialDir is the directory inside the uploads directory where chunked
+// uploads are staged until they are complete.
+const partialDir = ".partial"
+
+var (
+	ErrPartialNotFound = errors.New("upload not found")
+	ErrPartialBusy     = errors.New("upload is already receiving data")
+	ErrOffsetMismatch  = errors.New("offset does not match upload")
+	ErrPartialTooLarge = errors.New("data exceeds upload length")
+)
+
+// Partial is an upload that is being received in chunks. Its state is
+// persisted next to the staged data so it survives a restart.
+type Partial struct {
+	UID     string
+	Length  int64
+	Offset  int64
+	Info    Meta // only the fields set by the uploader are used
+	Created time.Time
+
+	// ID is the ID of the finished upload, set once all of the data has been
+	// received.
+	ID string `json:",omitempty"`
+}
+
+// Done returns true if all of the data has been received.
+func (p *Partial) Done() bool {
+	return p.ID != ""
+}
+
+func (c *Cache) partialPath(uid string) string {
+	return filepath.Join(c.dir, partialDir, uid)
+}
+
+func (c *Cache) savePartial(p *Partial) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmp := c.partialPath(p.UID) + ".json.tmp"
+	if err = ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.partialPath(p.UID)+".json")
+}
+
+func (c *Cache) loadPartial(uid string) (*Partial, error) {
+	// the UID is used verbatim as a file name
+	if _, err := hex.DecodeString(uid); err != nil || uid == "" {
+		return nil, ErrPartialNotFound
+	}
+	b, err := ioutil.ReadFile(c.partialPath(uid) + ".json")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrPartialNotFound
+		}
+		return nil, err
+	}
+	p := new(Partial)
+	if err = json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CreatePartial begins a new chunked upload of length bytes.
+func (c *Cache) CreatePartial(length int64, info *Meta) (*Partial, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	p := &Partial{
+		UID:    hex.EncodeToString(buf),
+		Length: length,
+		Info: Meta{
+			Name:         info.Name,
+			Addr:         info.Addr,
+			Client:       info.Client,
+			Owner:        info.Owner,
+			DeleteHash:   info.DeleteHash,
+			Expires:      info.Expires,
+			MaxDownloads: info.MaxDownloads,
+		},
+		Created: time.Now(),
+	}
+
+	c.RLock()
+	dir := filepath.Join(c.dir, partialDir)
+	c.RUnlock()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(c.partialPath(p.UID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	if err = c.savePartial(p); err != nil {
+		os.Remove(c.partialPath(p.UID))
+		return nil, err
+	}
+	return p, nil
+}
+
+// Partial returns the current state of the chunked upload with the given UID.
+func (c *Cache) Partial(uid string) (*Partial, error) {
+	c.partialMu.Lock()
+	defer c.partialMu.Unlock()
+	return c.loadPartial(uid)
+}
+
+// AppendPartial writes data from r to the chunked upload with the given UID,
+// starting at offset, which must match the amount of data already received.
+// Whatever data is read before an error occurs is kept. Once the upload is
+// complete it is added to the cache just like Put would, and the returned
+// Partial will contain the new ID.
+func (c *Cache) AppendPartial(uid string, offset int64, r io.Reader, conf Config) (*Partial, error) {
+	c.partialMu.Lock()
+	if c.partialBusy[uid] {
+		c.partialMu.Unlock()
+		return nil, ErrPartialBusy
+	}
+	p, err := c.loadPartial(uid)
+	if err != nil {
+		c.partialMu.Unlock()
+		return nil, err
+	}
+	c.partialBusy[uid] = true
+	c.partialMu.Unlock()
+
+	defer func() {
+		c.partialMu.Lock()
+		delete(c.partialBusy, uid)
+		c.partialMu.Unlock()
+	}()
+
+	if p.Done() || offset != p.Offset {
+		return p, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(c.partialPath(uid), os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(p.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// read one byte past the end to tell if the client is sending too much,
+	// in which case the whole chunk is rejected
+	n, err := io.Copy(f, io.LimitReader(r, p.Length-p.Offset+1))
+	if n > p.Length-p.Offset {
+		n = 0
+		f.Truncate(p.Offset)
+		err = ErrPartialTooLarge
+	}
+	f.Close()
+
+	p.Offset += n
+	if serr := c.savePartial(p); serr != nil {
+		return nil, serr
+	}
+	if err != nil {
+		return p, err
+	}
+
+	if p.Offset == p.Length {
+		if err = c.finishPartial(p, conf); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// finishPartial hashes the staged data and moves it into the cache.
+func (c *Cache) finishPartial(p *Partial, conf Config) error {
+	path := c.partialPath(p.UID)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	sum := newSummer()
+	_, err = io.Copy(sum, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	id, err := c.commit(path, p.Length, sum, &p.Info, conf)
+	if err != nil {
+		// commit has removed the data, so the upload can't be finished
+		// another way
+		c.removePartial(p.UID)
+		return err
+	}
+
+	p.ID = id
+	return c.savePartial(p)
+}
+
+// RemovePartial aborts the chunked upload with the given UID and deletes any
+// data received so far.
+func (c *Cache) RemovePartial(uid string) error {
+	c.partialMu.Lock()
+	defer c.partialMu.Unlock()
+	if c.partialBusy[uid] {
+		return ErrPartialBusy
+	}
+	if _, err := c.loadPartial(uid); err != nil {
+		return err
+	}
+	return c.removePartial(uid)
+}
+
+func (c *Cache) removePartial(uid string) error {
+	path := c.partialPath(uid)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(path + ".json")
+}
+
+// RemoveStalePartials removes every chunked upload that was started before t,
+// whether or not it finished.
+func (c *Cache) RemoveStalePartials(t time.Time) {
+	c.RLock()
+	dir := filepath.Join(c.dir, partialDir)
+	c.RUnlock()
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	c.partialMu.Lock()
+	defer c.partialMu.Unlock()
+	for _, match := range matches {
+		uid := filepath.Base(match)
+		uid = uid[:len(uid)-len(".json")]
+		if c.partialBusy[uid] {
+			continue
+		}
+		p, err := c.loadPartial(uid)
+		if err != nil {
+			log.Printf("cache: %s: %v", match, err)
+			continue
+		}
+		if p.Created.Before(t) {
+			if err = c.removePartial(uid); err != nil {
+				log.Print("cache: ", err)
+			}
+		}
+	}
+}
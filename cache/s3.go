@@ -0,0 +1,392 @@
+package cache
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ktkr.us/pkg/airlift/contentdisposition"
+)
+
+// S3Store keeps contents as objects in a bucket on Amazon S3 or a compatible
+// object store such as MinIO. Requests are signed with AWS Signature Version
+// 4.
+type S3Store struct {
+	Endpoint  string // base URL of the service, e.g. http://localhost:9000
+	Region    string // defaults to us-east-1
+	Bucket    string
+	Prefix    string // prepended to every object key
+	AccessKey string
+	SecretKey string
+	PathStyle bool // address the bucket in the path instead of the host name
+
+	Client *http.Client // defaults to http.DefaultClient
+}
+
+const (
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	s3DateFormat    = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// s3Error is the error document returned by the service.
+type s3Error struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+func readS3Error(resp *http.Response) error {
+	e := &s3Error{Status: resp.StatusCode}
+	if b, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024)); err == nil {
+		xml.Unmarshal(b, e)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w (%v)", os.ErrNotExist, e)
+	}
+	return e
+}
+
+func (s *S3Store) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+func (s *S3Store) region() string {
+	if s.Region == "" {
+		return "us-east-1"
+	}
+	return s.Region
+}
+
+func (s *S3Store) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("s3: endpoint %q needs a scheme and host", s.Endpoint)
+	}
+	p := "/" + s.Prefix + key
+	if s.PathStyle {
+		p = "/" + s.Bucket + p
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + p
+	u.RawPath = s3Escape(u.Path, false)
+	return u, nil
+}
+
+func (s *S3Store) do(method, key string, header http.Header, body io.Reader, length int64) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = length
+	}
+	s.sign(req, time.Now())
+	return s.client().Do(req)
+}
+
+// sign adds the Authorization header to req.
+func (s *S3Store) sign(req *http.Request, t time.Time) {
+	t = t.UTC()
+	req.Header.Set("X-Amz-Date", t.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "range" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	signed, canonical := canonicalHeaders(headers)
+	creq := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonical,
+		signed,
+		unsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.AccessKey, s.scope(t), signed, s.signature(t, creq)))
+}
+
+// SignedURL satisfies the Signer interface.
+func (s *S3Store) SignedURL(key, name, contentType string, ttl time.Duration) (string, error) {
+	return s.signedURL(key, name, contentType, ttl, time.Now())
+}
+
+func (s *S3Store) signedURL(key, name, contentType string, ttl time.Duration, t time.Time) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	t = t.UTC()
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", s3Algorithm)
+	q.Set("X-Amz-Credential", s.AccessKey+"/"+s.scope(t))
+	q.Set("X-Amz-Date", t.Format(s3DateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if name != "" {
+		q.Set("response-content-disposition", contentdisposition.Filename(name))
+	}
+	if contentType != "" {
+		q.Set("response-content-type", contentType)
+	}
+
+	signed, canonical := canonicalHeaders(map[string]string{"host": u.Host})
+	creq := strings.Join([]string{
+		"GET",
+		u.EscapedPath(),
+		canonicalQuery(q),
+		canonical,
+		signed,
+		unsignedPayload,
+	}, "\n")
+	q.Set("X-Amz-Signature", s.signature(t, creq))
+	u.RawQuery = canonicalQuery(q)
+	return u.String(), nil
+}
+
+func (s *S3Store) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region() + "/s3/aws4_request"
+}
+
+func (s *S3Store) signature(t time.Time, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	toSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3DateFormat),
+		s.scope(t),
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.region())
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalHeaders returns the list of signed header names and the canonical
+// header block for the lowercased headers given.
+func canonicalHeaders(headers map[string]string) (signed, canonical string) {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteString(k + ":" + headers[k] + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and slashes
+// too unless escapeSlash is false.
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !escapeSlash {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// Import satisfies the Store interface.
+func (s *S3Store) Import(key, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	resp, err := s.do("PUT", key, nil, f, fi.Size())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readS3Error(resp)
+	}
+	f.Close()
+	return os.Remove(src)
+}
+
+// Exists satisfies the Store interface.
+func (s *S3Store) Exists(key string) (bool, error) {
+	_, err := s.head(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *S3Store) head(key string) (int64, error) {
+	resp, err := s.do("HEAD", key, nil, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, readS3Error(resp)
+	}
+	return resp.ContentLength, nil
+}
+
+// Remove satisfies the Store interface.
+func (s *S3Store) Remove(key string) error {
+	resp, err := s.do("DELETE", key, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return readS3Error(resp)
+	}
+	return nil
+}
+
+// Open satisfies the Store interface. Nothing is downloaded until the first
+// Read, and seeking starts a new ranged request.
+func (s *S3Store) Open(key string) (io.ReadSeekCloser, error) {
+	size, err := s.head(key)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Object{store: s, key: key, size: size}, nil
+}
+
+type s3Object struct {
+	store *S3Store
+	key   string
+	size  int64
+	off   int64
+	body  io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.off >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		h := http.Header{}
+		h.Set("Range", "bytes="+strconv.FormatInt(o.off, 10)+"-")
+		resp, err := o.store.do("GET", o.key, h, nil, 0)
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return 0, readS3Error(resp)
+		}
+		if resp.StatusCode == http.StatusOK && o.off > 0 {
+			// range was ignored
+			if _, err := io.CopyN(ioutil.Discard, resp.Body, o.off); err != nil {
+				resp.Body.Close()
+				return 0, err
+			}
+		}
+		o.body = resp.Body
+	}
+	n, err := o.body.Read(p)
+	o.off += int64(n)
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.off
+	case io.SeekEnd:
+		offset += o.size
+	default:
+		return 0, errors.New("s3: bad whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("s3: negative position")
+	}
+	if offset != o.off && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.off = offset
+	return offset, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
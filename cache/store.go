@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Store is where the contents of uploads are kept. Contents are immutable and
+// keyed by their digest, so a Store never has to deal with partial writes or
+// renames; uploads are always staged on local disk first.
+type Store interface {
+	// Import moves the fully written local file at src into the store under
+	// key. src is only removed if it succeeds.
+	Import(key, src string) error
+	// Open opens the contents stored under key.
+	Open(key string) (io.ReadSeekCloser, error)
+	// Exists reports whether anything is stored under key.
+	Exists(key string) (bool, error)
+	// Remove deletes the contents stored under key.
+	Remove(key string) error
+}
+
+// Signer is implemented by stores that can hand out temporary links to their
+// contents, so that downloads don't have to pass through the server.
+type Signer interface {
+	// SignedURL returns a link to the contents under key that is valid for
+	// ttl. The response will carry the given file name and content type.
+	SignedURL(key, name, contentType string, ttl time.Duration) (string, error)
+}
+
+// ErrNotSigner is returned by Cache.SignedURL if the store can't make links.
+var ErrNotSigner = errors.New("storage backend can't make signed URLs")
+
+// LocalStore keeps contents in a directory on local disk, sharded by the first
+// two characters of the key.
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(s.Dir, key)
+	}
+	return filepath.Join(s.Dir, key[:2], key)
+}
+
+// Import satisfies the Store interface.
+func (s *LocalStore) Import(key, src string) error {
+	dest := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	return os.Rename(src, dest)
+}
+
+// Open satisfies the Store interface.
+func (s *LocalStore) Open(key string) (io.ReadSeekCloser, error) {
+	return os.Open(s.path(key))
+}
+
+// Exists satisfies the Store interface.
+func (s *LocalStore) Exists(key string) (bool, error) {
+	_, err := os.Stat(s.path(key))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Remove satisfies the Store interface.
+func (s *LocalStore) Remove(key string) error {
+	return os.Remove(s.path(key))
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"ktkr.us/pkg/airlift/imgmeta"
+)
+
+// stripMetadata rewrites the image at path without the metadata in it. If
+// there was any, it returns the kinds that were removed and the new size and
+// sum of the file. Otherwise, or if it isn't an image, the file is left alone
+// and removed is nil.
+func stripMetadata(path string) (removed []string, size int64, sum *summer, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer src.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".strip-")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	sum = newSummer()
+	removed, err = imgmeta.Strip(io.MultiWriter(tmp, sum), src)
+	tmp.Close()
+	if err != nil || len(removed) == 0 {
+		os.Remove(tmp.Name())
+		if err == imgmeta.ErrUnsupported {
+			err = nil
+		}
+		return nil, 0, nil, err
+	}
+
+	fi, err := os.Stat(tmp.Name())
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, 0, nil, err
+	}
+	return removed, fi.Size(), sum, nil
+}
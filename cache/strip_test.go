@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testConfig struct{ strip bool }
+
+func (c testConfig) MaxAge() int                   { return 0 }
+func (c testConfig) MaxSize() int64                { return 0 }
+func (c testConfig) MaxCount() int                 { return 0 }
+func (c testConfig) StripMetadata() bool           { return c.strip }
+func (c testConfig) Refresh()                      {}
+func (c testConfig) ProcessHash(buf []byte) string { return hex.EncodeToString(buf[:8]) }
+
+// commentedJPEG is a JPEG image with a comment in it.
+func commentedJPEG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	com := []byte("\xff\xfe\x00\x08secret")
+	return append(append(append([]byte{}, b[:2]...), com...), b[2:]...)
+}
+
+// brokenJPEG starts like a JPEG image, but is cut off in its first segment.
+var brokenJPEG = []byte("\xff\xd8\xff\xe1\x00\x40Exif\x00\x00MM")
+
+// testDir returns an empty directory that is removed after the test.
+func testDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func newTestCache(t *testing.T) *Cache {
+	c, err := New(testDir(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// leftovers returns the names of files in the cache's directory that aren't
+// part of its structure.
+func leftovers(t *testing.T, c *Cache) []string {
+	matches, err := filepath.Glob(filepath.Join(c.dir, ".*-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestPutStripped(t *testing.T) {
+	c := newTestCache(t)
+	id, err := c.Put(bytes.NewReader(commentedJPEG(t)), &Meta{Name: "a.jpg"}, testConfig{strip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := c.Stat(id)
+	if m == nil {
+		t.Fatal("upload wasn't kept")
+	}
+	if len(m.Stripped) != 1 || m.Stripped[0] != "comment" {
+		t.Errorf("Stripped is %q, want the comment", m.Stripped)
+	}
+	if m.Size != int64(len(commentedJPEG(t))-len("\xff\xfe\x00\x08secret")) {
+		t.Errorf("size is %d, which isn't the size without the comment", m.Size)
+	}
+}
+
+func TestPutNotStripped(t *testing.T) {
+	c := newTestCache(t)
+	_, err := c.Put(bytes.NewReader(brokenJPEG), &Meta{Name: "a.jpg"}, testConfig{strip: true})
+	if !errors.Is(err, ErrNotStripped) {
+		t.Fatalf("got %v, want ErrNotStripped", err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("%d uploads were kept", c.Len())
+	}
+	if l := leftovers(t, c); len(l) != 0 {
+		t.Errorf("files left behind: %q", l)
+	}
+
+	// without stripping, it's kept as it is
+	if _, err := c.Put(bytes.NewReader(brokenJPEG), &Meta{Name: "a.jpg"}, testConfig{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPartialNotStripped(t *testing.T) {
+	c := newTestCache(t)
+	p, err := c.CreatePartial(int64(len(brokenJPEG)), &Meta{Name: "a.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.AppendPartial(p.UID, 0, bytes.NewReader(brokenJPEG), testConfig{strip: true})
+	if !errors.Is(err, ErrNotStripped) {
+		t.Fatalf("got %v, want ErrNotStripped", err)
+	}
+	if _, err := c.Partial(p.UID); err != ErrPartialNotFound {
+		t.Errorf("partial upload is still there: %v", err)
+	}
+	if c.Len() != 0 {
+		t.Errorf("%d uploads were kept", c.Len())
+	}
+}
+
+// failingStore is a LocalStore that can't take anything in.
+type failingStore struct{ LocalStore }
+
+var errImport = errors.New("can't import")
+
+func (failingStore) Import(key, src string) error { return errImport }
+
+func TestPartialNotImported(t *testing.T) {
+	dir := testDir(t)
+	c, err := New(dir, &failingStore{LocalStore{Dir: filepath.Join(dir, blobDir)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := commentedJPEG(t)
+	p, err := c.CreatePartial(int64(len(b)), &Meta{Name: "a.jpg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.AppendPartial(p.UID, 0, bytes.NewReader(b), testConfig{}); err != errImport {
+		t.Fatalf("got %v, want the store's error", err)
+	}
+	if _, err := c.Partial(p.UID); err != ErrPartialNotFound {
+		t.Errorf("partial upload is still there: %v", err)
+	}
+	if _, err := os.Stat(c.partialPath(p.UID)); !os.IsNotExist(err) {
+		t.Errorf("partial data is still there: %v", err)
+	}
+}
@@ -0,0 +1,617 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/chroma/styles"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+	"ktkr.us/pkg/airlift/contentdisposition"
+	"ktkr.us/pkg/airlift/thumb"
+	"ktkr.us/pkg/fmtutil"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/out"
+)
+
+// Zip and tar uploads are shown to browsers as a list of their members, each
+// of which can be downloaded on its own from /{id}/-/entry/{path}.
+
+const (
+	// maxArchiveEntries is how many members are listed before giving up.
+	maxArchiveEntries = 10000
+
+	// maxEntryDepth is how many directories deep a member can be linked to.
+	// The router matches one path segment per parameter, so each depth has
+	// its own route.
+	maxEntryDepth = 16
+
+	// the biggest members that are read whole to be formatted or
+	// thumbnailed
+	maxFormattedEntry = 4 << 20
+	maxThumbEntry     = 32 << 20
+
+	// maxMemberIDLen keeps the names of member thumbnails within what file
+	// systems allow.
+	maxMemberIDLen = 200
+)
+
+var (
+	errEntryFound = errors.New("entry found")
+	errNotArchive = errors.New("not an archive")
+	errEntrySize  = errors.New("entry isn't the size it says")
+)
+
+// archiveFormat returns "zip", "tar" or "tgz" for an archive's file name, or
+// an empty string if it isn't one that can be browsed.
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tgz"
+	}
+	return ""
+}
+
+// canBrowse returns true if the members of an upload can be listed and
+// downloaded. Uploads with a download limit are only ever sent whole, since
+// browsing them would not count against it.
+func canBrowse(meta *cache.Meta) bool {
+	return meta.MaxDownloads == 0 && archiveFormat(meta.Name) != ""
+}
+
+// archiveEntry is a member of an archive.
+type archiveEntry struct {
+	Path     string
+	Size     fmtutil.Bytes
+	Modified time.Time
+	Dir      bool
+	Link     string // where it can be downloaded from
+	Thumb    bool   // whether it is an image that can be thumbnailed
+
+	// span finds where the member's data is stored in the archive, if it
+	// can be read from there directly. It must be called before the data is
+	// read.
+	span func() (memberSpan, bool)
+}
+
+// memberSpan is where the data of an archive member is stored in its archive,
+// so that it can be read again without walking the archive.
+type memberSpan struct {
+	off, n int64  // of the data as stored
+	method uint16 // zip.Store or zip.Deflate
+	size   int64  // of the data once uncompressed
+}
+
+// cleanEntryPath makes member paths comparable, whatever form the archive
+// stored them in.
+func cleanEntryPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// walkArchive calls fn with each member of an archive upload in the order
+// they are stored, and the means to read its contents during the call. If fn
+// returns an error, the walk stops and returns it.
+func walkArchive(meta *cache.Meta, fn func(e *archiveEntry, open func() (io.ReadCloser, error)) error) error {
+	format := archiveFormat(meta.Name)
+	if format == "" {
+		return errNotArchive
+	}
+	f, err := fileCache.Open(meta.ID)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == "zip" {
+		ra, ok := f.(io.ReaderAt)
+		if !ok {
+			ra = &seekReaderAt{rs: f}
+		}
+		zr, err := zip.NewReader(ra, meta.Size)
+		if err != nil {
+			return err
+		}
+		for _, zf := range zr.File {
+			e := &archiveEntry{
+				Path:     cleanEntryPath(zf.Name),
+				Size:     fmtutil.Bytes(zf.UncompressedSize64),
+				Modified: zf.Modified,
+				Dir:      zf.FileInfo().IsDir(),
+			}
+			if e.Path == "" {
+				continue
+			}
+			zf := zf
+			e.span = func() (memberSpan, bool) {
+				// encrypted members and other compression methods are left
+				// to the zip package
+				if zf.Flags&0x1 != 0 || zf.Method != zip.Store && zf.Method != zip.Deflate {
+					return memberSpan{}, false
+				}
+				off, err := zf.DataOffset()
+				if err != nil {
+					return memberSpan{}, false
+				}
+				return memberSpan{off, int64(zf.CompressedSize64), zf.Method, int64(zf.UncompressedSize64)}, true
+			}
+			if err := fn(e, zf.Open); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var r io.Reader = f
+	if format == "tgz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	open := func() (io.ReadCloser, error) { return ioutil.NopCloser(tr), nil }
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mode := hdr.FileInfo().Mode()
+		if !mode.IsRegular() && !mode.IsDir() {
+			continue
+		}
+		e := &archiveEntry{
+			Path:     cleanEntryPath(hdr.Name),
+			Size:     fmtutil.Bytes(hdr.Size),
+			Modified: hdr.ModTime,
+			Dir:      mode.IsDir(),
+		}
+		if e.Path == "" {
+			continue
+		}
+		// the tar package reads nothing ahead, so the data of a plain file
+		// in an uncompressed archive starts where the file is at
+		if format == "tar" && hdr.Typeflag == tar.TypeReg {
+			e.span = func() (memberSpan, bool) {
+				off, err := f.Seek(0, io.SeekCurrent)
+				return memberSpan{off, hdr.Size, zip.Store, hdr.Size}, err == nil
+			}
+		}
+		if err := fn(e, open); err != nil {
+			return err
+		}
+	}
+}
+
+// openEntry finds the file at name in an archive upload and calls fn with it.
+// cache.ErrNotFound is returned if there isn't one.
+func openEntry(meta *cache.Meta, name string, fn func(e *archiveEntry, r io.Reader) error) error {
+	name = cleanEntryPath(name)
+	err := walkArchive(meta, func(e *archiveEntry, open func() (io.ReadCloser, error)) error {
+		if e.Dir || e.Path != name {
+			return nil
+		}
+		r, err := open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		if err := fn(e, r); err != nil {
+			return err
+		}
+		return errEntryFound
+	})
+	switch err {
+	case errEntryFound:
+		return nil
+	case nil:
+		return cache.ErrNotFound
+	}
+	return err
+}
+
+// memberIndexTTL is how long the places of an archive's members are kept
+// after it has been walked, for the thumbnails its listing asks for.
+const memberIndexTTL = time.Minute
+
+// memberIndex is where the members of an archive upload that have
+// thumbnails are, by path.
+type memberIndex struct {
+	once    sync.Once
+	spans   map[string]memberSpan
+	err     error
+	expires time.Time
+}
+
+var memberIndexes = struct {
+	sync.Mutex
+	m map[string]*memberIndex
+}{m: make(map[string]*memberIndex)}
+
+// offersThumb returns true if the listing of an archive upload shows a
+// thumbnail of one of its members. Members of compressed tar archives have
+// none, since each would mean decompressing the archive up to it.
+func offersThumb(meta *cache.Meta, e *archiveEntry) bool {
+	return !e.Dir && archiveFormat(meta.Name) != "tgz" && e.Size <= maxThumbEntry &&
+		thumb.FormatSupported(path.Ext(e.Path)) && len(memberID(meta.ID, e.Path)) <= maxMemberIDLen
+}
+
+// memberIndexFor returns the index of an archive upload's members, creating
+// an empty one if there's none yet.
+func memberIndexFor(id string) *memberIndex {
+	memberIndexes.Lock()
+	defer memberIndexes.Unlock()
+	now := time.Now()
+	for k, ix := range memberIndexes.m {
+		if now.After(ix.expires) {
+			delete(memberIndexes.m, k)
+		}
+	}
+	ix := memberIndexes.m[id]
+	if ix == nil {
+		ix = &memberIndex{expires: now.Add(memberIndexTTL)}
+		memberIndexes.m[id] = ix
+	}
+	return ix
+}
+
+// rememberMembers keeps the places of an archive upload's members that were
+// found while listing it.
+func rememberMembers(id string, spans map[string]memberSpan) {
+	ix := &memberIndex{spans: spans, expires: time.Now().Add(memberIndexTTL)}
+	ix.once.Do(func() {})
+	memberIndexes.Lock()
+	memberIndexes.m[id] = ix
+	memberIndexes.Unlock()
+}
+
+// archiveMembers returns the places of an archive upload's members that
+// have thumbnails, walking it only if it hasn't been lately.
+func archiveMembers(meta *cache.Meta) (map[string]memberSpan, error) {
+	ix := memberIndexFor(meta.ID)
+	ix.once.Do(func() {
+		ix.spans = make(map[string]memberSpan)
+		n := 0
+		ix.err = walkArchive(meta, func(e *archiveEntry, open func() (io.ReadCloser, error)) error {
+			if n++; n > maxArchiveEntries {
+				return errEntryFound
+			}
+			addSpan(ix.spans, meta, e)
+			return nil
+		})
+		if ix.err == errEntryFound {
+			ix.err = nil
+		}
+	})
+	return ix.spans, ix.err
+}
+
+// addSpan records where a member is, if it has a thumbnail.
+func addSpan(spans map[string]memberSpan, meta *cache.Meta, e *archiveEntry) {
+	if e.span == nil || !offersThumb(meta, e) {
+		return
+	}
+	// the first member with a path is the one that's downloaded
+	if _, ok := spans[e.Path]; ok {
+		return
+	}
+	if sp, ok := e.span(); ok {
+		spans[e.Path] = sp
+	}
+}
+
+// readMember returns the contents of an archive upload's member from where
+// they are stored.
+func readMember(meta *cache.Meta, sp memberSpan) ([]byte, error) {
+	f, err := fileCache.Open(meta.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		ra = &seekReaderAt{rs: f}
+	}
+	var r io.Reader = io.NewSectionReader(ra, sp.off, sp.n)
+	if sp.method == zip.Deflate {
+		fr := flate.NewReader(r)
+		defer fr.Close()
+		r = fr
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r, sp.size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) != sp.size {
+		return nil, errEntrySize
+	}
+	return b, nil
+}
+
+// seekReaderAt reads from a file in the store at any offset, for stores
+// whose files aren't io.ReaderAts already.
+type seekReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+func (r *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.rs, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// memberID returns the ID that the thumbnail of an archive member is cached
+// under: its archive's ID, a slash, and its encoded path. The thumbnails of
+// an archive's members are then removed along with its own.
+func memberID(id, name string) string {
+	return id + "/" + base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func splitMemberID(s string) (id, name string, ok bool) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return "", "", false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s[i+1:])
+	if err != nil {
+		return "", "", false
+	}
+	return s[:i], string(b), true
+}
+
+// thumbStore gives the thumbnail cache the contents of archive members as
+// well as whole uploads.
+type thumbStore struct{}
+
+func (thumbStore) Open(id string) (io.ReadSeekCloser, error) {
+	archiveID, name, ok := splitMemberID(id)
+	if !ok {
+		f, err := fileCache.Open(id)
+		if err == cache.ErrNotFound {
+			return nil, thumb.ErrSourceMissing
+		}
+		return f, err
+	}
+	meta := fileCache.Stat(archiveID)
+	if meta == nil {
+		return nil, thumb.ErrSourceMissing
+	}
+	spans, err := archiveMembers(meta)
+	if err != nil {
+		return nil, err
+	}
+	sp, ok := spans[name]
+	if !ok {
+		// not there, or not a member that has a thumbnail
+		return nil, thumb.ErrSourceMissing
+	}
+	b, err := readMember(meta, sp)
+	if err != nil {
+		return nil, err
+	}
+	return bytesFile{bytes.NewReader(b)}, nil
+}
+
+func (thumbStore) Name(id string) string {
+	if _, name, ok := splitMemberID(id); ok {
+		return name
+	}
+	return fileCache.Name(id)
+}
+
+func (thumbStore) ContentType(id string) string {
+	if _, name, ok := splitMemberID(id); ok {
+		return mime.TypeByExtension(path.Ext(name))
+	}
+	if meta := fileCache.Stat(id); meta != nil {
+		return meta.ContentType
+	}
+	return ""
+}
+
+// bytesFile is contents read into memory, closed by doing nothing.
+type bytesFile struct{ *bytes.Reader }
+
+func (bytesFile) Close() error { return nil }
+
+// serveArchive shows a browser the members of an archive upload.
+func serveArchive(g *gas.Gas, conf *config.Config, meta *cache.Meta) (int, gas.Outputter) {
+	var (
+		entries   []*archiveEntry
+		truncated bool
+		spans     = make(map[string]memberSpan)
+	)
+	err := walkArchive(meta, func(e *archiveEntry, open func() (io.ReadCloser, error)) error {
+		if len(entries) == maxArchiveEntries {
+			truncated = true
+			return errEntryFound
+		}
+		segments := strings.Split(e.Path, "/")
+		for i := range segments {
+			segments[i] = url.PathEscape(segments[i])
+		}
+		e.Link = "/" + meta.ID + "/-/entry/" + strings.Join(segments, "/")
+		e.Thumb = offersThumb(meta, e)
+		addSpan(spans, meta, e)
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil && err != errEntryFound {
+		// it may just be named like an archive
+		log.Println(g.Request.Method, "serveArchive:", meta.Name, err)
+		contentdisposition.SetFilename(g, meta.Name)
+		setCacheHeaders(g, conf, meta)
+		return serveUpload(g, conf, meta)
+	}
+	// the thumbnails in the listing are read from where the walk found them
+	rememberMembers(meta.ID, spans)
+
+	s := styles.Get(conf.SyntaxTheme)
+	if s == nil {
+		s = styles.Fallback
+	}
+	data := &struct {
+		SyntaxTheme string
+		Filename    string
+		Language    string
+		Entries     []*archiveEntry
+		Truncated   bool
+	}{
+		s.Name,
+		meta.Name,
+		"",
+		entries,
+		truncated,
+	}
+	return 200, out.HTML("archive/layout-syntax", &context{data})
+}
+
+// entryPath returns the path of the member that was asked for, which the
+// router can't hand over whole.
+func entryPath(g *gas.Gas) string {
+	const sep = "/-/entry/"
+	p := g.Request.URL.Path
+	if i := strings.Index(p, sep); i >= 0 {
+		return p[i+len(sep):]
+	}
+	return ""
+}
+
+// getArchiveEntry sends a member of an archive upload. Text is formatted for
+// browsers as the uploads themselves are, and images can be asked for as
+// thumbnails.
+func getArchiveEntry(g *gas.Gas) (int, gas.Outputter) {
+	meta := fileCache.Stat(g.Arg("id"))
+	if meta == nil || !canBrowse(meta) {
+		return 404, out.Error(g, cache.ErrNotFound)
+	}
+	name := cleanEntryPath(entryPath(g))
+
+	form := struct {
+		Raw       bool   `form:"raw"`
+		Formatted bool   `form:"fmt"`
+		Lang      string `form:"lang"`
+		Thumb     bool   `form:"thumb"`
+	}{}
+	if err := g.UnmarshalForm(&form); err != nil {
+		return 400, out.Error(g, err)
+	}
+
+	if form.Thumb {
+		id := memberID(meta.ID, name)
+		if len(id) > maxMemberIDLen || archiveFormat(meta.Name) == "tgz" {
+			return 302, out.Redirect(placeholderThumb)
+		}
+		opt := thumb.Options{Accept: g.Request.Header.Get("Accept")}
+		g.Header().Add("Vary", "Accept")
+		t, err := thumbCache.Transform(g.Request.Context(), id, thumbWidth, thumbHeight, opt)
+		if err != nil {
+			logThumbErr(err)
+			return 302, out.Redirect(placeholderThumb)
+		}
+		http.ServeFile(g, g.Request, t)
+		return g.Stop()
+	}
+
+	conf := config.Get()
+	wantsRaw := form.Raw || clientWantsRaw(g, form.Formatted)
+	var (
+		contents    []byte
+		contentType string
+		sent        bool
+	)
+	err := openEntry(meta, name, func(e *archiveEntry, r io.Reader) error {
+		br := bufio.NewReader(r)
+		contentType = mime.TypeByExtension(path.Ext(e.Path))
+		if contentType == "" {
+			head, _ := br.Peek(512)
+			contentType = http.DetectContentType(head)
+		}
+
+		// text is read whole to be formatted after the archive is closed
+		if strings.HasPrefix(contentType, "text/") && !wantsRaw && e.Size <= maxFormattedEntry {
+			var err error
+			contents, err = ioutil.ReadAll(br)
+			return err
+		}
+
+		h := g.Header()
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Length", strconv.FormatInt(int64(e.Size), 10))
+		contentdisposition.SetFilename(g, path.Base(e.Path))
+		setCacheHeaders(g, conf, meta)
+		sent = true
+		_, err := io.Copy(g, br)
+		return err
+	})
+	if sent {
+		if err != nil {
+			log.Println(g.Request.Method, "getArchiveEntry:", err)
+		}
+		return g.Stop()
+	}
+	switch err {
+	case nil:
+	case cache.ErrNotFound:
+		return 404, out.Error(g, err)
+	default:
+		log.Println(g.Request.Method, "getArchiveEntry:", err)
+		return 500, out.Error(g, err)
+	}
+
+	render := findRenderer(name, "")
+	if form.Lang != "" {
+		render = nil
+	}
+	view := &textView{
+		Name:      name,
+		Langs:     []string{form.Lang},
+		Render:    render,
+		Highlight: conf.SyntaxEnable || form.Lang != "",
+		Contents:  contents,
+	}
+	if code, o, ok := view.serve(g, conf); ok {
+		return code, o
+	}
+
+	g.Header().Set("Content-Type", contentType)
+	contentdisposition.SetFilename(g, path.Base(name))
+	setCacheHeaders(g, conf, meta)
+	http.ServeContent(g, g.Request, "", meta.Uploaded, bytes.NewReader(contents))
+	return g.Stop()
+}
@@ -14,11 +14,14 @@ func init() {
 	bindata.RegisterFile(filepath.Join("static", "airlift_180x180.png"), time.Unix(1442122170, 0), []byte("\x89PNG\x0d\n\x1a\n\x00\x00\x00\x0dIHDR\x00\x00\x00\xb4\x00\x00\x00\xb4\x08\x02\x00\x00\x00\xb2\xaf\x91e\x00\x00\x0d\xdfIDATx\xda\xed\x9d\xf9_\x13\xd7\x16\xc0\xf9\x9f\xde\xd6\xcdZ}m\xad\xad\xa2\xb8\xaf\x88(U\x11\xd9\x17\x15ADE\x14\x84Z\xab\x94\xba\xe0Z\xb5\xb5nh\xb5\xael\xb2\xaf\x81\xec\x1b\x09\x01\x02\x09\x09Y\xc8\xbe\xbc\x8b\xd3\xd7\x17x\x01\x13\xc8\xcc\xdc\x999\xe7\xf3\xfd\xc1\x052w\xce\xfdf\xee\x993\x93I\x94<g-\x00\x04%\nR\x00\x80\x1c\x00\xc8\x01\x80\x1c\x00\xc8\x01\xd0*\x87,{\x0d\x00\x04\x05\xe4\x00@\x0e\x00\xe4\x00@\x0e\x00\xe4\x00@\x0e\x00\xe4\x00\x98&\x874k5\x00\x04\x05\xe4\x00@\x0e\x00\xe4\x00@\x0e\x00\xe4\x00@\x0e\x00S9$\x99\xab\x00 ( \x07\x00r\x00 \xc7\x9c\x91\xe5n\xd2U_\xd5?\xbb%=\xb0\x01\xb2\x01r\xfcW\x8bC\x9b\xf5Ooz&\xcc\xfew\xe16\xea\x86o\x96I\xb2VCf8-\x87<?v\xec\xf9/^\x9b\xc5\xff\x7fa\xef\x17\xab\xbe\xcb\xe2\xba\x1c\xe2\x8c\x18\x0e\";\x1c7\xf6\xfa\x9e\xd7a\xf3\xcf\x12>\xdfx\xcbK\xf9\x91xn\xa6\x08\xc199\xd0d\x1b\xea\xaa}.\x87?\xb4\xf0:\xec\xba\xa77%9\xeb@\x0eVkq4\xc1\xd8\xf0\xbb\xcf\xed\xf2\x87\x1f.\xdd\xd0`U1\xc8\xc1B\x14E{\xc6[^\xf8<n\xff\xfcbB\xd6\xdb_\x96\x0er\xb0\x04eq\x92\xa9\xbd\xc6\xe7\xf5\xf8#\x15>\x9f\xf1\xedSY\xfe6\x90\x83\xc1\xf4\x97\xa6\x9a\xbb\xea\xd1\\\xfaI\x08\xaf\xcd:\xfa\xa8J\x92\xbd\x96\xe5r\x88\xd2W\xb2\x8c\xfe\xb2\x0cKo3IZ\x04\x86S74\xf0\xd3\x11\xf6%\xf0/X%\x87\xeaL\xb6U\xd8\xe1\xa76\xac\xc2N\xc5\xc9} \x07\xbe\xa8\xcf\xe5N\x88\xbb\xfd4\x05\xaai\x0c\xf5O$\xb9\x9bA\x0e\xbc\x18\xa8<b\x93\xf3\xfd\x18\x84\xc7j\xd2\xfeV)\xce\\\x0dr\xd0MF\x8c\xe6\xe21\xbbJ\xe2\xc7,\x9cZ\xf5@e\x01\xc8A'\x86\xda\x87~\x8c\xc3\xc2kR\x14\xeda\xbc\x1c\xc2\xb4\x15\x0ce\xe0R\x91C\xab\xc6\xd6\x0f\x9f\xc7=\xf6\xe6\x81\xe4\xe0&\xe6f\x98Ir\x88\x0flT\x9eN\x0f\xfc\x17Q\xc6*\xed\xdd\x1f\xd1b\x8f\xad\"n\xb3q\xe8\xf6Ya\xfaJ\x90\x83\xcc\xe3\xc4\x85\xa3n\xa3\xde\xef\xf3\x99:\xead\x85;\x03\xff\x0b\xbd;\xd1{t\xfe\xddq\xf2\xc2\xae\x91\xab\xce\xe5\x82\x1c\x91Grh\xab\xa9\xa3v\xca\x11\xdb\xe5\xd4\xbf\xf8E\xbc\x7f}\xe0\x8f\xc9\x8f\xef6\xf3\x9ap.D\xcc\xdd\x0d\xd3\xb4\x069\xe6\xc5\xe0\xb5R\x8fe<\xf8\x11\xdbd@Gl\xb4\xb2\x04\xfe<z\x83\xda\xfaE\xf8\x16\"\xc1\xb4\xc6W\x0eAj4\x9eH\x0e\xc7\x99{[\xde\x9bn\xc7\xb0Z\xfdc\xc1\x94\xdfM[\xa1\xb9V\xea2\x8c\xe2[\x88\x98\x0c\x837\xca\xd18\xb1M>\x01\x96r\xa4\xad@\x87\x04\x8f\xcd\x1a\xc6\xa9\xa3\xb0C^\xbc7\xf0E\x84YkF\x9f\\\xf7:\xec\xd8*\x82\x8ep\xca\xf2,\x90#\x0c\xa4G\x13\xacsj\x84O\xf6\xb0\x1b\x9e\x88s\xb7L9\xfc\xe4o36\xbf\xa0\xe0\"\xdc\x9co\x00@\xe5\x94\xb4`;\xc8\xf1\xfe\x03\x86\xf6\xfeE\xafs^\xefut\xbc\xd1>\xb8,\xccX\x15\xf8\xca\x8a\x92T\xab\xa8\x13\xdbC\x08:\xbc\xa1\x83\x1c:\xd4\x81\x1c\xc1\x91\x9fH\xb4)\x85\x91\xbc\x98~\xa9h\xda&Pi\x82\n\x14l\x15AE\x12*\x95\xb0*D\xa2\xf8)\xcb\xe9E\x90\xber\xe4\xc9\x8d\xb9\xdd\xda\xf9\x9e\x8b\xe9R\x9e\xa2,c\xca\xb6\xd2V\x0e\xfdZ\x81s\xd3lB\xda+/I\xa1}R\x08h\x96C^\x92j\xd7(H]\xd4\xc7;j%\x05\xf1\x81\x1b\x15\xed\xdf\xa0\x7f}\x1f\xdf\xa6\x99\xcf\x87\xea$q^,w\xe5\x10d\xae\xd6\xbd\xbc\x1b\xc9\xbb;g\xed.\xe8\x9e\xdf\x11f\xaf\x0d\x1c\x80\xf4\xe8\xb7\xa6\xcezlkUT\x88h\x1f^\x16\xa4\xc7pN\x0e\xe5\x99\x1c\xe7\x88\x86\x86\xee\xc2\xcfg\xf8\xa9\xd1\x81#Q\x94g\xda\x94\xf86\xcdP\xf1\xa4\xbeT\xc4\x159\x84\xd9\xeb\xc6\xea\x1f\xd3\xf8~\xb5\x0f\xc8\xfb\xcf\x1e\x9c2\xaa\xd4h\xcd\xd5\x12\x9c\x9bf\xa8x\x92\x9dH\xa4A\x8e\xbe\xe4e\x94\xd1\x7f>\xdf56\x82C\xbaM=\x8d\x92\xc2\x84\xc0\xb1\x092V\x8d<\xbe\x86m\xd3\x0c\xad\xbfc\xf5O\x84\x076Q9_\x14\xc9!\xdc\xbf\xc1\xd8\xf2\x12\xb7\xfb-\xf45\x8f\xa6\xa5[t(\xd6\xd0\xf8\x8c\x9aJhn]\x9c\xe1\xfb\x17\xf9\xa9+\xd8#\x07Z5\xd1z\x8fq\xba/\xf1\xd3V\x06\x0eXZ\xb4\xc7\"h\xc7v\x95q\x0c\xab\xfb+\x0e\xb3D\x0e?\xf61Y\xf7]<>}\x11\xac8\x8ci\xd3\xec]\xc5F\x85\x1c\xbd\xfb\xbe!\x1b?C\xc2*\xe1IO&\x07\x8e\xbc/%z\xf0\x97\xf3\xf84\xcd<\x13\x16T\xb4\x11\x1d\x1a\n&\x0e\xe4\x98\xfe\xa644=\x17\xe6n\x0d\x1c\xbf {\x9d\xee\xd5o^\x97\x93\xce\xab\xfcf#*\xa2\x1d\x01\xe7\xff \x07}\x0d\xa8\xeak\xfc\xf4\x98\xc0\xbd\x10\xe5\xc7\x8d\xb7\xd7P\x7f\x12\x8e\xca5\xa4\xa6\xa9\xaba\xda\xa6A\x0eZ\xaf\x84\x8d\xeb57\xbf\xebM^\x16\xb8/\xf2\xd3\x19\x13\x91\xbb@\xf8\xde\x01\xa0s\x13\xed\xe3\xebA\x9f@\x04r\xd0\x1f\xf6\x01\x99\xe2\xfb\xfdS\xf6\x08\x9d\x7f]9Ej\xd3\x0c\xbd8\xaauT?\x1duh\x07f\xfa\x19*\xe4\xe0%}M6~\xe6\x87\xa9\xbbQtdg\xe0N\xf5\xa5\xad\xd4V_\x0d\xebv\xb5\x90\xce\x9b\xf4Z\xcd\xcf\xdf\x8b\x8f\xedB[\x9c\xfd')\x988\x90#\x8c\xa6\x19Z\xfb\xf9Yk\x03wM\xb0\x7f\x83\xbe\xeeqD\x9af\xce\xd1\xc1\x81\x1b\xe5}\x19\xabG\x9e\xdd\x0e\xa5\xf8\x059\xb0\x0b\xb7\xc54x\xe7\\o\xf2\xf2\xc0\x1d\x14\x1f\xdbm\xe6\xcf\xbdi\xe6\x18\x19P_-A\xaf\xa9\xba|\"\xf4\xd5\n\xe4\xc0\xb6G\xa9RV\x1c\x9e\xb6\x9b\xca\xf3y\xe8\xdf\xc3+h\x86\xfa\xd5U\xc5\xbc}\xdfH\x8e\xef\xb1Jxa\xfd.\x15r\xf4\xec]J6~\x96\x86E\xd2#9\x91\x14\xb8\xa7\xbc}\xcb4w\xce\xb9\xcd\xc6\xf7k\xa1\x91\xf7_<\xde\x83j\x97\xcc5\xba\x9a\x87sX\x98(\x988\x90c\xbeM\xb3\xb1\xc6?\x04\x077\x07\xeeoo\xfa\xaa\x91g\xb7f\xaa\x1bl*\xa9\xb2\xb2\x10i\x81\x18\xb8\xf1\x1dZ\xa7\xe6\xb6e\x90\x83)M3\xdb\xf0\xa3\xab\xbd\xa9+\x02\xf7Zp(\xd6\xd8\xf6&\xb0s5\xa1\x14\xa1\xc5hR\x8b\xbdK\xa5\xa5i\xb6\xf9=\\\x04\xe4`T\xd3\xcc0\xaa\xaa:I\xcc\xfd_ \x09&\x14\xc2\x09\xb9@\xf1\xc3\xa1?\xa59\xb8\xd9\xd0\xf4|\xfe\x9dV\x90\x83y\x81T@B\x04\xcd\x03/y\xf9\xd0\xbd\x0b\x91j\x8dP!Gw\xe2Wd\xe3\xe7Z\xf8|hA\xe9\xcb\xd9\x10\x98\x04\xf9\xd9\x83\xf6!U\x047B\xc1\xc4\x81\x1c$\xb8\xe1r\x0e\xde\xad\xec\xde\xbb\x94\xd8}A^\xdcx\xf7\xdb\x88o\x05\xe4`^\xd8\xd42\xd1\xd1]\xc4\x8e\xf3R\xa2\xb5\xbf\xdf$\xe9Z?\xc8\xc1\xa8\x03\x86\xd7;\xf2\xc7\x9d\x9e\xe4e\xc4^+/\x1cs\x92y75\x15rt\xedYB6\\0\xc3\xa9\x1b\x92\x94\xa6\x13\xfb+,L\xb0\x90\xff\xc4\\\n&\x0e\xe4\x88@\xe8\x1b\x9e\xf6\xa4\xadD{\xcaK_=\xfa\xfa>57\xaf\x83\x1c\xd8_\x873\x1b\x15\x15\x05\x93\xbb\x99\xf8\x95\xeaz\x99{\x86'T\x81\x1c\x9c\x93\xc3\xc4k\xea\xcd^\x8fvPr*e\xa2_L\xf1\xd6\xa9\x90\xa3s\xf7\x97d\xc3\xc2~\xb9\xdd\xa6\xba^\x8ev\xad7g\x83\xfe\xed3Z>\xddI\xc1\xc4\x81\x1ca\x87U\xd6\xc7\xcf\xdb\xd6\x95\xb8Ts\xb7\xd2\x13\xeckGA\x0e.\xca\xe1s\xbb\x07\x1f\\F\xe5\x85\xf4\xcc\xfe\xc8\xb6;A\x0ef\x07\xb2Ax<\xb1\xef\xe0\x16cW\x03\x0e\xe3\xa1B\x8e\x8e]_\x90\x0d\x0b\xae\x95\x8c\xbc\xfc\xad;-f\xe8\xf1uo\xc8_HKvP0q \xc7\xfb\xba[\x86QIY\x96\xfc\xc7#N<\x1e\x1e\x01r\xe0\x12c-\xafD\xa7R\xcc\xa2.\x0c\xc7\x06r\xd0\xd7\xdd\xb2\x9aU7\xca\xd1j\x82\xed\xb3:\xa8\x90\xa3}\xd7\x17d\xc3\xbc\xee\x96\xa0c\xf0\xd1\x95P\xee\x13\xa61(\x98\xb8\xa8\xf6o?'\x1b&u\xb7\x9c\x8e\xb1\xe6\x97V\xaa>\x0d;/9\xc8\x9f8\x90cJ\xedi\x91\xf5\xe1\xfb\xa0t\x90\x83\xb6c\x86\xc3\x1e\xf4\xc3\xec \x07\xc8\xc1\xbc\xa0B\x8e\xb6\x84\x7f\x93\x0dL$\x19A\xc1\xc4\x81\x1c \x07\xc8\x01r\x80\x1c G$\xe5h\xdd\xb9\x98l`\"\xc9\x08\n&\x0e\xe4\x009@\x0e\x90\x03\xe4\x009\")G\xcb\x8eEdCK\xeeL\xc2N\xc5\xd5R\x16\xcbA\xc1\xc4\xb1P\x0e\x9f\xd73p\xefR\xcb\xce\xc5h\xd3\xe60\x1f\xb4\x05r\xb0Y\x0e\xe7\xd8\x08\xbf(\xe9\xafM\x0b\x8a\x93A\x0e\x90c2\xc6\xdak\xdb\xf7-\x9f\xb6ucO#\xc8\xc1i9\xbcN\x87\xf2ZY\xd0\xad\xf3\xf2\xe3\x99r\x15\x1e;9\x9a\xe3?#\x1b\xb2\xd3d\xd3(z\xf2\xb6\xcf2\x00]\xe3\x1f\xec\x93\x83\x82\x89c\xbc\x1c#5\x8fZw/\x99}\x00]\xd9\x1b}n7\xc8\xc1!9<\x13\x16\xc9\xb9\xfc\x10\xc7\xa0}u\x0f\xe4\xe0\x8a\x1cfi_W\xd6\xfa\xd0\xc7\xd0\x91\x1a\x83\xed\xd7\x82\xe2+G\xd3\xf6\x85d\x13\xe9>\x86OS}\xady\xc7\xe2p\x87\x81~\x8bMrP0q\x0c\x94\xc3\xef7\xf2Z\xbas\xb7\x85;\x8c\xd6\xc4\xaf\xdd\xd8|\x95\x1f\xc8A\x96\x1cD\x1bT\xfb\xea~\xdb\xbe\xe8\xb0F\xa2\xbaS\x01r\xb0_\x0e\"\xdc\x96q\xc5\xf5\xf2\xd0\x97\x98\x96]_:\x0d:\x90#T9\x1a\xe3>%\x1b\xd2\xfb\x1c\x83J\xc1\xe9\xcc\x10\x07#\xbfR\xc2\x0e9(\x9886\xc8\x11V\xb2\x9a\xe2\x17\xd9g\xfeV=\x90\x83\xd3r \xc4\xe7\x0b@\x0e\x90c\x06\xb6/\xb4(E \x07\xc8\x11\x1c~I:\xc8\xf1~9\xden[@6\xd4$+\xdcQ\x8d\x0b:\x18-\x07\x05\x13\xc7]9x\x85\xbb\x18})\x1f\xe4 7Yc\xed\xb5 \x07\xc8\xf1?\xf4\xadoZ\xf7E\x13\x7f\xee:\xb0\xd5\xe7\xf5\x82\x1c3\xca\xd1\x10\xfb\x09\xd9\xf0K2l\xe4\xb7\x16B\x1c\x0c\xd1W\x15W\x14\x12\x7f\x1d\xa9\xfb\x9dqZ\xd8\xb5\x03\xfc\xd2L\n&\x8e\n9\x10o\xe3\x17)o\xfd\xe0\x9e\xb0\xe0 \x07\x11\x86\xee\xc6\xd6\x94\x98\xb6\xf45^\xb7\x8b)ZxlV\x94F\x94Ljf\x8d\"9\x08Z\x92\x96kk\x1f\xfb}^\x1c\xe4 r-\xbdT<\xf4\xfcW\x06x\xe1\xf3\xa2\xd4\xb5&\xaf\xa0r\xbe(\x95\x83\xa0+o\xbb\x89\x84\x8f\x93\xccA\x0e\"\xacj\x19\xe6b\x98e}\xdd\x87wR?S4\xc81\xc9\xb6\x05\xe2\xf3\x05\x0e\xbd\x16\x079p\x0e\xa7Q/\xae8\x82\xd2E\xcb4E\xd5o\xfd\x98.\xde\xeeX\xac~x\xc5\xeb\x8c\xcc\xd3\xc4C\xdc(S\xb4\xf0\xba\x9c(9\x8d\x09\x9f\xd38At\xcaA\xd0\x9a\xbaJ\xd7\xfc\n\xe4\x08\x0c}{m[\xe6z\xda\xa7\x86~9\x08xEI\x96\xf9}\x13\x16;\xe4\x98\xd0({\x8bS0\x99\x14\\\xe4\x98$v\x81\xb4\xaa\xc4e\x1e\xe7\xa6\x1c\xe8<_v\xad\xac!\xeeS|f\x04'9\xde\xd1\xb4k\x89\xe6\xe9m\x9f\xc7\xc3\x1d9|^\xef\xd0\xcb{\xcd\x89_\xe36\x17Qu[>\xc2\x90\xf6\x9cM\x86\x9e\xa6\xb0R\x1c\xe2+\xe3f\xc6\xb8\xa0\xb337\x0e\xcfY\xc0T\x0e\x82\xbe\xd3Y\xa1\xf7\xdd\x19'\x07:\x93\x17|\x7f\x08\xe7\xfcc-\x07\xa2>n\xa1\xe2\xe7\xb3\x1e\xfb\x04\x9b\xe4@g\xef\xfdw/4\xc4/\xc2<\xf9\xb8\xcbA\xd0\x9c\xb4\\[S={\xdf\x9d\x19r\xf8|\xa3\x8d\xcf[Rb\x18\x91vf\xc8A\xd0\x99\x17o\x96\xf6\xcd\xd8\x1b\xe8\xa8\x0f%\xe94\x8a\x81\xce\xd5{\x8e\xefeP\xc2\xa3j7\x7f\xc8$\xb6|$\xfc!\xdfa\x18\x0d~\xd1\xd2nS\xdc:W\x17\xfb\xc9,\xaf@\x8b\x16.\xb3Qr\xe9d\xed\x96\x8f\x99\x95m\xa6\xc9\xf1\x8e\x86\x1d\x8bU\x0f\xaaf\xea\xbb[\xd5\xb2\xee\xc2\xdd\x98\xc8\x81\xce\xc9\xd1\x99\xf9\xdb\x84/\x98\x98gF\xcaA\x80\x16\x11][\xcdLK\xfb\xf0\x9bG\x8d\xbb\x97\xd2+\xc7XOS[\xf6&\xe6f\x98\xc1r\x10\xf4\x1c\xdbkUIg:\x98\x8b+\x8f\xa1\x95\x88z9\xd0\x198\xbf|?\xd3s\xcbx9\x10\xa8\xc8x\xd7w7\x06\xef2\x89\xba\xdbs6S&\xc7d\xdds\xfb|\xdd\xb6OY\x90\xd8\xa8\x9aM\x1f\xb0\x83\x86\x9d\x9f\x0f>\xff5h\xdf\xdd\xe7q\xab\xab\xaf\xd7o_\x84~\x8c\xd4\xd3Tm\xdd\x93\xc6\xc4e\xacI){\xe4 h\xcd\xdah\xe05\x07\xbf/W7\xdc[\x9aE\x92\x18&iog\xfeN\x96%\x93mr\x10\xf4\x95\xe5\xd8\xa8\xfa(\xbd\xd3\xa8\x13U\x1e\xab\xd9\xfc!\xfb\xd2\xc8N9\x10\xb5\xb1\x0b\xd0\xda\x8f*\x00\x12\x97\x11\xb7K]}\xa3>~\x11[s\xc8Z9\x08P\x050<\xd9w\x8f\xfc\xc7\x1e'\x1b\xb2ik\xd8\x9d\xbd\xa87\x1b\xff\xc5z:\xf2v\xa0\x9a RZX5\x8a\x9e\xe2\x14.\xe4\x8d\x13rL\xb2\xe9\x03\xc1\xf9\x02T\x1f\xcc\xeff-\xb3\xec\xc6\x99\x9a-\x1fq$i\x9c\x91\xe3\x1du\xdb?\xeb\xbf_\xe5u9\xc3?K\xf5\x0e\xbd~\xd8\xf0\xed\x12N\xa5\x8b[r\x104\xa7\xae\xd6\xb5\xbe\x09\xe3f-aW\xdb\x81\xad\x1cL\x14\x17\xe5 \xe8:\x9a\x88\xaa\x87\xd9\xb5\xb0\xeb\x86\xf9g\xf3\xd0\x92\xc4\xcd\x14qW\x0e\x04\xaa\x1e$\x97O\xb9,\xe3\xc1o\xd6\xba_U\x17\xb7\x90\xcb\xf9\x89z\xbd\xe1\x9f\x1c\xa7>\xe1K\xcd\xd4\xbe\xfbh\xcb\xeb\xc6\xa4h\xc8\x0c\xc8\xf1'-\x99\xeb\x8d\xfc\x0e\x8bJ\xdaY\xb8\x1b\xb2\x01r\x04\x01\x95\x17\x90\x04\x90\x03\x009\x00\x90\x03 E\x8eW\xeb\xff\x01\x00A\x019\x00\x90\x03\x009\x00\x90\x03\x009\x00\x90\x03\xc0T\x8e\x97\xeb\xfe\x0e\x00A\x019\x00\x90\x03\x009\x00\x90\x03\x009\x00\x90\x03\x009\x00\x90\x03`\x8d\x1c/\xd6\xfe\x0d\x00\x82\x02r\x00 \x07\x00r\x00\x11\xe4?\x84\x84\xb4\x84]\x83\xf9\xd8\x00\x00\x00\x00IEND\xaeB`\x82"))
 	bindata.RegisterFile(filepath.Join("static", "airlift_76x76.png"), time.Unix(1442122170, 0), []byte("\x89PNG\x0d\n\x1a\n\x00\x00\x00\x0dIHDR\x00\x00\x00L\x00\x00\x00L\x08\x02\x00\x00\x00H\xf5\xc6|\x00\x00\x06uIDATx\xda\xed\x9a\x89S\x13W\x1c\xc7\xf7\x7f\xeai\xbd\xad\xb4ZD+\x87'\x08\x02\x8aU\x9a\x02\xe1\x10\x14\x15\xb5\xa88b\x05<\xa9Hu,*xTk\x1d\x07L\x91p\x86\x84\x089\xc8AHHb\x08\x90\x106!\xf7&\xe9\xb3k\x19\x1a\x82\xfb\xb2G`\"\xbf\xf9Nf\xb3\xec\xef\xfd\xbe\x1f~\xbb\xfb\xde\xee\x04Q\x15%E\xbd\x90\x8f\x02r\xa8()\xea\x85\x0c\x15&F\xbd\x96 \xa3\x06RY\x90\x10\xf5Z\x82d@\xaa#\xbbU\xc7\xf6D\x1aR\x91\x1f\x1f\x19)\x8bw\x8c\xffQ\x87\xd9\xa60\xab\xc5x\xbfFQ\x90\x10\xb1\xd2\x91\x80T\x16%\x8d5]\xf3N\x99\x02\xb3\xc2\xa9\x91k\xab\x0fG\x05dA\x82\xb1\xa1\xcac2\x06B\x86\xdf\x8f\xf2^\xa9\xca\xd2\x19\x87\x94\xb3\xb72\xa2\xfcx\xc3\xed\xf3n\xa3.@\x14>\xc7\xf4\xf8\xd3[\x8a\xc2D\xa6\x9c\xb0\xb72\x02\xa9\xff\xf5\xb4K?\x1c\x08'\xdcF\xad\xeez\x19S\x90\xb2\xbc\xefi\x94\xf6J\xa9C=\x18 \x1b\xb6\x81.\xd5\xe9,z-\x01\xd1\x0c\xa9\xbbQ\x06z\x12\xa0\x10~\x8f\xdb\xd4\xdc(/\xdaF'\xe4`\xee\x16\x8aR\x94\xa6X\x85\xed\xd3R\x81\xea\xe7\x03\xe0\xab\x8c\x1d?\xdax\xd5\x8bNRA\xf5L\x8e\xeb\x7f\xab\xa0\xee\x0d\x17UH}\xfd90\xf5\xbdo\x02\xe65s\x1e\xcb\x0fo\x07\xfb\xc1\xa7\xa9\xf9\x01h\x0b\x15T\xbbR4\\\xc1ZHH\xbc\x81s\x9d\x81\x1e\x1a\x1a\xaa\x06\xf3\xbe\x07\xc7\x0c\x9d\xccD\x05m`\xaa \x7f\xf6\xfa\xb0I\xeesE\xc9.J\x90\xd2\x9c\xcd$\xa4\xbfu\xd6\xfb_\x03C\x86C#S_,\xc0\x0fVW\xb2AO\xa8\xb4\x14\x9bFG\x1f\\\x96\xe6n!\xe76lH\xf9\xd1\x14T\xc8\x85\xeb\x82\xdf\xd2\xdd\xac8\x96\x8a'\xean\x96\xbb'\x0cTP\x9dz\x95\xfaR!\x19H\xc9Oq\xf0\xd2\xd5\x1140\xc4\\\xefr\x18\x9f\xdc\x94\xe6m\x05\xe9\xe0\x13lc\x8ei\n7_\xff\x14\xbfU^\xba',\xdb\xb0\x90\xb2#\xc9h\x1f\x97\xb47\xf7\xf8\xdb\x91k\xc7\xdf\x0fU\xbc\xcb\xc4y\x0c\xeeR\xa4G\xf3\xb9\x9ccO\xeb\xf1\x7f\x1c\x14\xa4\x98\xb5\x89P\xda\xba3^\xebT\x80r\xd8$|\xc5\xc9}\xf8\x98`\x03\xed\xef\xa42\x1a8\xf95\xd7\x8e\xc3\xf8\x87\x82\x0c\xd0\x17\xa0\x81&\xce#iA\">\xb2\xba\xaa\xd8\xa9\x1b\"\xb1`p\x8c(0\xbb\x15lCA\x8a~\x8c%T\x80\xee\xf0\xa2f\xfd\xdd\x8b\"\xd6\xa6w\xe3\x833\xa5\xbe\x02\xcc\xfeP\xdd3\x19\xc7_\xde\xb7\xf08`j\xc1\xf7\xc0\xf8_\x18\xc8\xf7s\xbdzPu\x81\x8d\x97\x90\x80{\xd2\xb3\xdb\xe0b\x9b\xef~c\x95\xf4\x8e\xdc8eh\xbc\n\xa6\x93\xd9\x7f\x81\x82\x1c\xc8\xfe\x8eP\x01\xe6\xc2\xef\x9f\xecz)-I\xc6\x0b\x81\x0ds\xc7\x8b\x99.\xfd;CZ'Z\x1e\xcaN\xa4\xab\xaf\x94\xbaFG\xe6\x0e\x00\xe3\x1f\xe9?\xb4\x91P\x01\x86\xc3\xe7t\x18\x1e\xd5\x0e\xb0\xe2\xf0r\xf2SYV\x09\xdf\xae\x91k\xefT\x8ar\xb6\xc8Nd\x80\xaf\xf3\xe5\xc2\xf8_\x14\x903\xd3\xcc\xf0\xe5\xa3\xb3\xeb\x8a\xf3\x13\xc7[\x1e~x\xb2\x81\x82|sp\x03\xa1\"\x03\x09`t\x0d\xd5x\xc5\xfe\xecX\xfd\xbd\x1a\xaf\x0d%\xcc\x82\xf1\xbfX \xbdV\x8b\xb22\x1f/\xa7\xaa9\xea4\x8c@&BA\n\x7f\xf8\x96PL\x13:\xb4JqI2($=\xb6wJ\xd8\x11V.\x8c\xff\x85\x87\x9c\xe4q\xfaYq\x03y\x09c\xcdM~o\xd8k=(\xc8\xbe\x03\xdf\x10\x8a\xa9\x8b\xd0\xe7\xd3?\xac\x15\x1e\xdc\xa8m\xa8\x0ew\xdd?\x130\xfe\x91\xbe\xac\x18B1A\x88\xd9mC\x97\x8a\x15\x17\xf2\x1da\xbe\xd7\x0b\x86\x84\xf0\x8f\x08\xb2b\x08E;\xa1\xd3\xa0QV\x95XB\xbdX\x087`\xfc#\xfc\xac\x18B\xd1Kh\x95\xf7O\xb4='q\xf9\x85\x0c\x18\xff\x08\x7f\xffzB\xd1H\xe8A\xcd\xdei\x94\xc6\x01a\xfc#\xbd\xfb\xbe&T`\x11\x07\x8c\xff\x8f\x03\x92\x97\xb9\x8eP\x8b\x19\x12\xc6?\x93\x90\x14^\xb7\xd2\x0c\xd9\x93\xb1\x96P$j\xa3\x83B\xf1\xc9\xfd\xf3>\x04\xd3\x170\xfe\xe9\x87\x04\x8f\xbc\xbaG7{2\xd7\x81\xc4\xb7\x7f\xdeY\x14\x90\xdd\xe9k\x08\x05_\xd2m\x1e\x93\x94\x1f\x9aI\xec=\x14Kz\xbd\x06\x190\xfe\x91\xae\xbd\xab\x09\x05Y\xcf\xcc\x7f\x0d\xa8\x82r5\xf7.3\n\x09\xe3\x9f6H\x0f:)9\xc3\x9a\x9b\xdb\xb3?\x06\xb4w\x81!;\xd3V\x11\n\xbe\xe4\x94\xb4O|\x86\x15\x94>Tw\x8e9H\x18\xff4C\x86,\xdc\x95\xbe\xd6\x01\xfd\xa4\xcf\x08dG\xeaJB\x85[x\xee\x08\xb2\x9aR\x86 a\xfcG\x08\xb2#m\x95mxp\xc1 \xdb\xf7\xac \x94\xb4\xb2\xd0\x09\xf1\x8b\x9c\x99\x08J7\x0b\xb8\xbd9\xf1\xe2\x8a\\z\xf1\x80%`\x0c\xc6?\x14$Pg\xfaZM\xd3\x0d\xcc\xe9 \x01\x09\xf6\x80\xc4\xe1\xbbU\xa8\xec\x0d-x>\xb7\x0b\x98\xe9\xccX\x07i\x1e\xe1\xa6,\x87\x17/'~\xa2\xbb\x85\xd0DP\xd6\xcc~\x8c\x8eU\xde\x04\x8f\x03l\x84e;<H\\\x03\xe5\xd9\xf6\x0f\xbe\x98\x99\x0f\x92b\xd8\xf5j\xd1Y\x16\x09\xc3H[\xf2W$\xc4M]\xa9\xba\xf3\x8b\xd7n\x0b\xe9&\xe8`\xeax\x98cz\xf8\xf7jn\xea*rnIB\xe2\xea:\x18kl}\x06\x96\xe4\x0cB\xfa\xfdc\xed/\xba\xb3\xe3\xa8\xf8D^\xef^FQ\xc2\xe3\x99V\x95t\xb6\xb1\xa0\x03H\x03\xda\xd4raY\x16u\x874@\xbeS\xf2rym\xb9\xc7j\xc1\xcd\x99\x04\xdc\x9e\xdc\x04*\x90^\x1b\xaa\xbcu\x1e\x0cK\x8b=\xa4u\xd7\x97t\xa9=s\xbd\xfee\xa3\x1f\xc3\xf0\xbb\xbc\xba\xa9\xb6-m5\xd8\x1f\xe6\xf9\xe93\xbcz\xdc\x91\xb5\x81FctB\xe2\xea=\x9cl\x91\np\xc3\x0e\xa3^t\x9e\x0d\x0f\x88*E\xfc\x92T\xda-\xd1\x0f\xf9N\xbb\x97IkJ]\xe1<a\xb9-&\xd9\xf5\xd3 \x91\x09?\xc8\xdf;\xbf`Hm{\xd7\x8c<\xa9\xf7y=\x04\xa7'\x86\xe9\xfej\xe0f\xacg\xce\x09\x83\x90\xb8\xbas\x12\xcc\xc2y\x7f\x94d\x91\x08x\x05;\x99\xf6\x80pv|\x1e\x01\x0dT\xb0\x1d\xa3\xff[\xe2\xbbLF\xf1\xa5#\x91\xa9\x1e!H\xa0\xd6\x94\x15\xaa\xfbW\xc1\xf2\x15\x9c\xc0\x9a'\xf5\xaf\xd3VG\xact\xe4 qufo\xee\xca\x89\x8fpQ\xe4\xd5\xf6\xcf\xa2^K\x90Q\x03\xd9\xb2\xed\xd3\xa8\xd7\x12d\xd4@6'}\x12\xf5\xfa( \xff\x01\xea\x89b\xd8\x91D\xe7\xc5\x00\x00\x00\x00IEND\xaeB`\x82"))
 	bindata.RegisterFile(filepath.Join("static", "common.js"), time.Unix(1449817206, 0), []byte("var messageTimeout;\n\nfunction $(sel, root) {\n\x09return (root || document).querySelector(sel);\n}\n\nfunction $$(sel, root) {\n\x09return (root || document).querySelectorAll(sel);\n}\n\nNode.prototype.sacrificeChildren = function() {\n\x09while (this.hasChildNodes()) this.removeChild(this.firstChild);\n};\n\nfunction chain(f) {\n    return {\n        i: 0,\n        funcs: f != null ? [f] : [],\n        err: null,\n        catcher: null,\n        then: function(g) {\n            this.funcs = this.funcs || [];\n            this.funcs.push(g);\n            return this;\n        },\n        pass: function() {\n            if (this.i < this.funcs.length) {\n                var args = [this.pass.bind(this), this.fail.bind(this)];\n                if (arguments != null) {\n                    args = args.concat(Array.prototype.slice.call(arguments));\n                }\n                this.funcs[this.i++].apply(this, args);\n            }\n            return this;\n        },\n        fail: function(err) {\n            if (this.catcher != null) {\n                this.catcher(err);\n            }\n            return this;\n        },\n        catch: function(g) {\n            this.catcher = g;\n            return this;\n        }\n    };\n}\n\nfunction makesvg(elem) {\n\x09return document.createElementNS(\"http://www.w3.org/2000/svg\", elem);\n}\n\nfunction showMessage(msg, classname) {\n\x09if (messageTimeout != null) {\n\x09\x09window.clearTimeout(messageTimeout);\n\x09}\n\n\x09var box = $('#message-box');\n\x09box.innerText = msg;\n\x09box.classList.add(classname);\n\x09box.classList.add('active')\n\x09messageTimeout = window.setTimeout(hideMessage, 5000);\n}\n\nfunction hideMessage() {\n\x09$('#message-box').classList.remove('active');\n}\n\nfunction errorMessage(resp) {\n\x09var err = resp.Err || resp;\n\x09if (err != null) {\n\x09\x09showMessage('Error: ' + err, 'bad');\n\x09} else {\n\x09\x09console.error('errorMessage: malformed error object');\n\x09\x09console.log(resp);\n\x09\x09showMessage('An unknown error occurred (status ' + code + ')', 'bad');\n\x09}\n}\n\n// method string\n// url    string\n// data   Object - post form data or null\n// cb     function(code int, resp Object) - callback\n// mutate function(x XMLHttpRequest, afteropen Boolean) [opt] -\n//  callback to mutate xhr before request\nfunction json(method, url, data, cb, mutate) {\n\x09var x = new XMLHttpRequest();\n\x09var h = function(x) {\n\x09\x09var resp = {};\n\x09\x09if (x.response != '') {\n\x09\x09\x09try {\n\x09\x09\x09\x09resp = JSON.parse(x.response);\n\x09\x09\x09} catch (err) {\n\x09\x09\x09\x09console.error(err);\n\x09\x09\x09\x09showMessage('Something\\'s wrong with the server (status ' + code + ')', 'bad');\n\x09\x09\x09\x09return false;\n\x09\x09\x09}\n\x09\x09}\n\x09\x09return cb(x.status, resp);\n\x09};\n\n\x09x.addEventListener('load', function(e) { h(e.target); }, false);\n\n\x09if (mutate != null) {\n\x09\x09mutate(x, false);\n\x09}\n\n\x09x.open(method, url, true);\n\n\x09if (mutate != null) {\n\x09\x09mutate(x, true);\n\x09}\n\n\x09x.send(data);\n}\n\nfunction reloadSection(endpoint, target, cb) {\n\x09var x = new XMLHttpRequest();\n\x09x.addEventListener('load', function(e) {\n\x09\x09var section    = $(target);\n\x09\x09var newSection = $(target, e.target.response);\n\x09\x09section.parentNode.replaceChild(newSection, section);\n\x09\x09if (cb != null) {\n\x09\x09\x09cb();\n\x09\x09}\n\x09}, false);\n\x09x.open('GET', endpoint, true);\n\x09x.responseType = 'document';\n\x09x.setRequestHeader('X-Ajax-Partial', 1);\n\x09x.send();\n}\n\nfunction redirectLogin(returnPath) {\n\x09returnPath = returnPath || window.location.pathname;\n\x09window.location = '/-/login?return=' + encodeURIComponent(returnPath);\n}\n"))
-	bindata.RegisterFile(filepath.Join("static", "config.js"), time.Unix(1528666514, 0), []byte("(function() {\n\x09'use strict';\n\n\x09var oldMaxSize, oldMaxAge, sampleID, sampleExt, idSize, addExt;\n\n\x09function reloadConfigValues() {\n\x09\x09reloadSection('/-/config', '#section-config', setupConfig);\n\x09}\n\n\x09function reloadOverview() {\n\x09\x09reloadSection('/-/config/overview', '#section-overview', setupOverview);\n\x09}\n\n\x09function updateSample() {\n\x09\x09var a = new Array(parseInt(idSize.value));\n\x09\x09for (var i = 0; i < a.length; i++) {\n\x09\x09\x09a[i] = 'X';\n\x09\x09}\n\x09\x09sampleID.textContent = sampleID.innerText = a.join('');\n\n\x09\x09if (addExt.checked) {\n\x09\x09\x09sampleExt.classList.add('show');\n\x09\x09} else {\n\x09\x09\x09sampleExt.classList.remove('show');\n\x09\x09}\n\x09}\n\n\x09function purgeDone(code, resp) {\n\x09\x09if (code == 204) {\n\x09\x09\x09reloadOverview();\n\x09\x09} else {\n\x09\x09\x09errorMessage(resp);\n\x09\x09}\n\x09}\n\n\x09function purgeAll() {\n\x09\x09var str = 'Really delete all of your uploads?\\n\\n' +\n\x09\x09\x09'Once they\\'re gone, the\\'re really gone.';\n\x09\x09if (!window.confirm(str)) {\n\x09\x09\x09return;\n\x09\x09}\n\n\x09\x09json('POST', '/purge/all', null, purgeDone);\n\x09}\n\n\x09function purgeThumbs() {\n\x09\x09json('POST', '/purge/thumbs', null, purgeDone);\n\x09}\n\n\x09function setupOverview() {\n\x09\x09$('#purge-all-link').addEventListener('click', purgeAll, false);\n\x09\x09$('#purge-thumbs-link').addEventListener('click', purgeThumbs, false);\n\x09}\n\n\x09function setupConfig() {\n\x09\x09var buttons = $$('button'), host = $('#host');\n\x09\x09oldMaxSize  = parseInt($('#max-size').value);\n\x09\x09oldMaxAge   = parseInt($('#max-age').value);\n\x09\x09sampleID    = $('#sample-id');\n\x09\x09sampleExt   = $('#sample-ext');\n\x09\x09idSize      = $('#id-size');\n\x09\x09addExt      = $('#append-ext');\n\n\x09\x09if (host.value === '') {\n\x09\x09\x09host.value = window.location.host;\n\x09\x09}\n\n\x09\x09updateSample();\n\x09\x09// IE and webkit seem to have different change and input impls\n\x09\x09idSize.addEventListener('change', updateSample, false);\n\x09\x09idSize.addEventListener('input', updateSample, false);\n\x09\x09addExt.addEventListener('change', updateSample, false);\n\n\x09\x09var boxes = $$('.check-enable');\n\x09\x09for (var i = 0, b; b = boxes[i]; i++) {\n\x09\x09\x09var hider = b.querySelector('.hider');\n\x09\x09\x09hider.hidee = b.querySelector('.hidee input, .hidee select');\n\x09\x09\x09hider.addEventListener('click', function() {\n\x09\x09\x09\x09if (this.checked) {\n\x09\x09\x09\x09\x09this.hidee.removeAttribute('disabled');\n\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09this.hidee.setAttribute('disabled', 'disabled');\n\x09\x09\x09\x09}\n\x09\x09\x09}, false);\n\x09\x09}\n\n\x09\x09$('#submit').addEventListener('click', function(e) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09e.stopPropagation();\n\n\x09\x09\x09for (var i = 0, button; button = buttons[i]; i++) {\n\x09\x09\x09\x09button.setAttribute('disabled', true);\n\x09\x09\x09}\n\x09\x09\x09var maxSize = parseInt($('#max-size').value);\n\x09\x09\x09var maxAge  = parseInt($('#max-age').value);\n\x09\x09\x09var delta   = 0;\n\x09\x09\x09var f = function(url, val, pass, fail) {\n\x09\x09\x09\x09var fd = new FormData();\n\x09\x09\x09\x09fd.append('N', val);\n\n\x09\x09\x09\x09json('POST', url, fd, function(code, resp) {\n\x09\x09\x09\x09\x09if (code === 200) {\n\x09\x09\x09\x09\x09\x09if (resp.N > delta) delta = resp.N;\n\x09\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09\x09fail(resp);\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09});\n\x09\x09\x09};\n\n\x09\x09\x09chain(function(pass, fail) {\n\x09\x09\x09\x09if (maxSize > 0 && (oldMaxSize == 0 || maxSize < oldMaxSize)) {\n\x09\x09\x09\x09\x09f('/-/config/size', maxSize, pass, fail);\n\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09}\n\x09\x09\x09}).then(function(pass, fail) {\n\x09\x09\x09\x09if (maxAge > 0 && (oldMaxAge == 0 || maxAge < oldMaxAge)) {\n\x09\x09\x09\x09\x09f('/-/config/age', maxAge, pass, fail);\n\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09}\n\x09\x09\x09}).then(function(pass, fail) {\n\x09\x09\x09\x09if (delta > 0) {\n\x09\x09\x09\x09\x09if (!confirm('Changes made to age or size limits mean that ' + delta + ' old file(s) will be pruned. Continue?')) {\n\x09\x09\x09\x09\x09\x09return false;\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09}\n\n\x09\x09\x09\x09oldMaxAge  = maxAge;\n\x09\x09\x09\x09oldMaxSize = maxSize;\n\n\x09\x09\x09\x09var host   = $('#host');\n\x09\x09\x09\x09host.value = host.value.replace(/\\w+:\\/\\//, '');\n\x09\x09\x09\x09var fd     = new FormData($('#config'));\n\n\x09\x09\x09\x09json('POST', '/-/config', fd, function(code, resp) {\n\x09\x09\x09\x09\x09$('#newpass-confirm').value = '';\n\n\x09\x09\x09\x09\x09for (var i = 0, button; button = buttons[i]; i++) {\n\x09\x09\x09\x09\x09\x09button.removeAttribute('disabled');\n\x09\x09\x09\x09\x09}\n\n\x09\x09\x09\x09\x09switch (code) {\n\x09\x09\x09\x09\x09case 204:\n\x09\x09\x09\x09\x09\x09$('#newpass').value = '';\n\x09\x09\x09\x09\x09\x09reloadConfigValues();\n\x09\x09\x09\x09\x09\x09reloadOverview();\n\x09\x09\x09\x09\x09\x09showMessage('Configuration updated.', 'good');\n\x09\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09case 403:\n\x09\x09\x09\x09\x09\x09redirectLogin();\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09\x09fail(resp);\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09});\n\x09\x09\x09}).catch(errorMessage).pass();\n\n\x09\x09\x09return false;\n\x09\x09}, false);\n\x09}\n\n\x09window.addEventListener('DOMContentLoaded', setupOverview, false);\n\x09window.addEventListener('DOMContentLoaded', setupConfig, false);\n})();\n"))
+	bindata.RegisterFile(filepath.Join("static", "config.js"), time.Unix(1792200112, 0), []byte("(function() {\n\x09'use strict';\n\n\x09var oldMaxSize, oldMaxAge, sampleID, sampleExt, idSize, addExt;\n\n\x09function reloadConfigValues() {\n\x09\x09reloadSection('/-/config', '#section-config', setupConfig);\n\x09}\n\n\x09function reloadOverview() {\n\x09\x09reloadSection('/-/config/overview', '#section-overview', setupOverview);\n\x09}\n\n\x09function reloadAccount() {\n\x09\x09reloadSection('/-/config', '#section-account', setupAccount);\n\x09}\n\n\x09function reloadUsers() {\n\x09\x09reloadSection('/-/config', '#section-users', setupUsers);\n\x09}\n\n\x09function updateSample() {\n\x09\x09var a = new Array(parseInt(idSize.value));\n\x09\x09for (var i = 0; i < a.length; i++) {\n\x09\x09\x09a[i] = 'X';\n\x09\x09}\n\x09\x09sampleID.textContent = sampleID.innerText = a.join('');\n\n\x09\x09if (addExt.checked) {\n\x09\x09\x09sampleExt.classList.add('show');\n\x09\x09} else {\n\x09\x09\x09sampleExt.classList.remove('show');\n\x09\x09}\n\x09}\n\n\x09function purgeDone(code, resp) {\n\x09\x09if (code == 204) {\n\x09\x09\x09reloadOverview();\n\x09\x09} else {\n\x09\x09\x09errorMessage(resp);\n\x09\x09}\n\x09}\n\n\x09function purgeAll() {\n\x09\x09var str = 'Really delete all of your uploads?\\n\\n' +\n\x09\x09\x09'Once they\\'re gone, the\\'re really gone.';\n\x09\x09if (!window.confirm(str)) {\n\x09\x09\x09return;\n\x09\x09}\n\n\x09\x09json('POST', '/purge/all', null, purgeDone);\n\x09}\n\n\x09function purgeThumbs() {\n\x09\x09json('POST', '/purge/thumbs', null, purgeDone);\n\x09}\n\n\x09function setupOverview() {\n\x09\x09// only admins can purge\n\x09\x09if ($('#purge-all-link') == null) {\n\x09\x09\x09return;\n\x09\x09}\n\x09\x09$('#purge-all-link').addEventListener('click', purgeAll, false);\n\x09\x09$('#purge-thumbs-link').addEventListener('click', purgeThumbs, false);\n\x09}\n\n\x09// done returns a json callback that runs next on a 2xx response.\n\x09function done(next) {\n\x09\x09return function(code, resp) {\n\x09\x09\x09if (code >= 200 && code < 300) {\n\x09\x09\x09\x09next(resp);\n\x09\x09\x09} else if (code == 403 && resp.Err == null) {\n\x09\x09\x09\x09redirectLogin();\n\x09\x09\x09} else {\n\x09\x09\x09\x09errorMessage(resp);\n\x09\x09\x09}\n\x09\x09};\n\x09}\n\n\x09function setupAccount() {\n\x09\x09if ($('#section-account') == null) {\n\x09\x09\x09return;\n\x09\x09}\n\n\x09\x09$('#account-submit').addEventListener('click', function(e) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09json('POST', '/-/account', new FormData($('#account')), done(function() {\n\x09\x09\x09\x09$('#account').reset();\n\x09\x09\x09\x09showMessage('Password changed.', 'good');\n\x09\x09\x09}));\n\x09\x09}, false);\n\n\x09\x09$('#token-submit').addEventListener('click', function(e) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09json('POST', '/-/tokens', new FormData($('#token')), done(function(resp) {\n\x09\x09\x09\x09reloadSection('/-/config', '#section-account', function() {\n\x09\x09\x09\x09\x09setupAccount();\n\x09\x09\x09\x09\x09$('#new-token').textContent = 'New API key (it won\\'t be shown again): ' + resp.Token;\n\x09\x09\x09\x09});\n\x09\x09\x09}));\n\x09\x09}, false);\n\n\x09\x09$('#ssh-keys-submit').addEventListener('click', function(e) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09json('POST', '/-/account/ssh-keys', new FormData($('#ssh-keys')), done(function() {\n\x09\x09\x09\x09showMessage('SSH keys saved.', 'good');\n\x09\x09\x09}));\n\x09\x09}, false);\n\n\x09\x09var links = $$('.revoke-token');\n\x09\x09for (var i = 0, a; a = links[i]; i++) {\n\x09\x09\x09a.addEventListener('click', function() {\n\x09\x09\x09\x09var id = this.parentNode.getAttribute('data-id');\n\x09\x09\x09\x09if (!window.confirm('Revoke API key ' + id + '? Clients using it will stop working.')) {\n\x09\x09\x09\x09\x09return;\n\x09\x09\x09\x09}\n\x09\x09\x09\x09json('POST', '/-/tokens/' + id + '/revoke', null, done(reloadAccount));\n\x09\x09\x09}, false);\n\x09\x09}\n\x09}\n\n\x09function setupUsers() {\n\x09\x09if ($('#section-users') == null) {\n\x09\x09\x09return;\n\x09\x09}\n\n\x09\x09$('#user-submit').addEventListener('click', function(e) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09json('POST', '/-/users', new FormData($('#user')), done(function() {\n\x09\x09\x09\x09showMessage('User saved.', 'good');\n\x09\x09\x09\x09reloadUsers();\n\x09\x09\x09}));\n\x09\x09}, false);\n\n\x09\x09var links = $$('.delete-user');\n\x09\x09for (var i = 0, a; a = links[i]; i++) {\n\x09\x09\x09a.addEventListener('click', function() {\n\x09\x09\x09\x09var name = this.parentNode.getAttribute('data-name');\n\x09\x09\x09\x09if (!window.confirm('Delete user ' + name + '? Their uploads will be kept.')) {\n\x09\x09\x09\x09\x09return;\n\x09\x09\x09\x09}\n\x09\x09\x09\x09json('POST', '/-/users/' + encodeURIComponent(name) + '/delete', null, done(reloadUsers));\n\x09\x09\x09}, false);\n\x09\x09}\n\x09}\n\n\x09function setupConfig() {\n\x09\x09// only admins can change the configuration\n\x09\x09if ($('#section-config') == null) {\n\x09\x09\x09return;\n\x09\x09}\n\n\x09\x09var buttons = $$('#config button'), host = $('#host');\n\x09\x09oldMaxSize  = parseInt($('#max-size').value);\n\x09\x09oldMaxAge   = parseInt($('#max-age').value);\n\x09\x09sampleID    = $('#sample-id');\n\x09\x09sampleExt   = $('#sample-ext');\n\x09\x09idSize      = $('#id-size');\n\x09\x09addExt      = $('#append-ext');\n\n\x09\x09if (host.value === '') {\n\x09\x09\x09host.value = window.location.host;\n\x09\x09}\n\n\x09\x09updateSample();\n\x09\x09// IE and webkit seem to have different change and input impls\n\x09\x09idSize.addEventListener('change', updateSample, false);\n\x09\x09idSize.addEventListener('input', updateSample, false);\n\x09\x09addExt.addEventListener('change', updateSample, false);\n\n\x09\x09var boxes = $$('.check-enable');\n\x09\x09for (var i = 0, b; b = boxes[i]; i++) {\n\x09\x09\x09var hider = b.querySelector('.hider');\n\x09\x09\x09hider.hidee = b.querySelector('.hidee input, .hidee select');\n\x09\x09\x09hider.addEventListener('click', function() {\n\x09\x09\x09\x09if (this.checked) {\n\x09\x09\x09\x09\x09this.hidee.removeAttribute('disabled');\n\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09this.hidee.setAttribute('disabled', 'disabled');\n\x09\x09\x09\x09}\n\x09\x09\x09}, false);\n\x09\x09}\n\n\x09\x09$('#submit').addEventListener('click', function(e) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09e.stopPropagation();\n\n\x09\x09\x09for (var i = 0, button; button = buttons[i]; i++) {\n\x09\x09\x09\x09button.setAttribute('disabled', true);\n\x09\x09\x09}\n\x09\x09\x09var maxSize = parseInt($('#max-size').value);\n\x09\x09\x09var maxAge  = parseInt($('#max-age').value);\n\x09\x09\x09var delta   = 0;\n\x09\x09\x09var f = function(url, val, pass, fail) {\n\x09\x09\x09\x09var fd = new FormData();\n\x09\x09\x09\x09fd.append('N', val);\n\n\x09\x09\x09\x09json('POST', url, fd, function(code, resp) {\n\x09\x09\x09\x09\x09if (code === 200) {\n\x09\x09\x09\x09\x09\x09if (resp.N > delta) delta = resp.N;\n\x09\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09\x09fail(resp);\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09});\n\x09\x09\x09};\n\n\x09\x09\x09chain(function(pass, fail) {\n\x09\x09\x09\x09if (maxSize > 0 && (oldMaxSize == 0 || maxSize < oldMaxSize)) {\n\x09\x09\x09\x09\x09f('/-/config/size', maxSize, pass, fail);\n\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09}\n\x09\x09\x09}).then(function(pass, fail) {\n\x09\x09\x09\x09if (maxAge > 0 && (oldMaxAge == 0 || maxAge < oldMaxAge)) {\n\x09\x09\x09\x09\x09f('/-/config/age', maxAge, pass, fail);\n\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09}\n\x09\x09\x09}).then(function(pass, fail) {\n\x09\x09\x09\x09if (delta > 0) {\n\x09\x09\x09\x09\x09if (!confirm('Changes made to age or size limits mean that ' + delta + ' old file(s) will be pruned. Continue?')) {\n\x09\x09\x09\x09\x09\x09return false;\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09}\n\n\x09\x09\x09\x09oldMaxAge  = maxAge;\n\x09\x09\x09\x09oldMaxSize = maxSize;\n\n\x09\x09\x09\x09var host   = $('#host');\n\x09\x09\x09\x09host.value = host.value.replace(/\\w+:\\/\\//, '');\n\x09\x09\x09\x09var fd     = new FormData($('#config'));\n\n\x09\x09\x09\x09var setup = $('#newpass') != null;\n\n\x09\x09\x09\x09json('POST', '/-/config', fd, function(code, resp) {\n\x09\x09\x09\x09\x09if (setup) {\n\x09\x09\x09\x09\x09\x09$('#newpass-confirm').value = '';\n\x09\x09\x09\x09\x09}\n\n\x09\x09\x09\x09\x09for (var i = 0, button; button = buttons[i]; i++) {\n\x09\x09\x09\x09\x09\x09button.removeAttribute('disabled');\n\x09\x09\x09\x09\x09}\n\n\x09\x09\x09\x09\x09switch (code) {\n\x09\x09\x09\x09\x09case 204:\n\x09\x09\x09\x09\x09\x09if (setup) {\n\x09\x09\x09\x09\x09\x09\x09// reload everything now that there is an account\n\x09\x09\x09\x09\x09\x09\x09window.location.reload();\n\x09\x09\x09\x09\x09\x09\x09return;\n\x09\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09\x09reloadConfigValues();\n\x09\x09\x09\x09\x09\x09reloadOverview();\n\x09\x09\x09\x09\x09\x09showMessage('Configuration updated.', 'good');\n\x09\x09\x09\x09\x09\x09pass();\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09case 403:\n\x09\x09\x09\x09\x09\x09redirectLogin();\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09\x09fail(resp);\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09});\n\x09\x09\x09}).catch(errorMessage).pass();\n\n\x09\x09\x09return false;\n\x09\x09}, false);\n\x09}\n\n\x09window.addEventListener('DOMContentLoaded', setupOverview, false);\n\x09window.addEventListener('DOMContentLoaded', setupConfig, false);\n\x09window.addEventListener('DOMContentLoaded', setupAccount, false);\n\x09window.addEventListener('DOMContentLoaded', setupUsers, false);\n})();\n"))
 	bindata.RegisterFile(filepath.Join("static", "favicon.png"), time.Unix(1442122170, 0), []byte("\x89PNG\x0d\n\x1a\n\x00\x00\x00\x0dIHDR\x00\x00\x00\x10\x00\x00\x00\x10\x08\x06\x00\x00\x00\x1f\xf3\xffa\x00\x00\x01(IDATx\xda\x94\xd3\xbdJCA\x10\x86\xe1\xe7\x84\x14j*\x0b-\xecL#\x08\x16*\x01;S\xc7R\x12\xb0\xd2J\x05AH\xa5\xe0\x1dX\x09b\xa3\x8d\x9db@+s\x15\x89\x9d\x85W \xf8\x83\x08\xfe`\xa5\xcd\x1c8\x84\x1cI>Xfv\xf8v\xf6\xdd]6\xb9i\x96\xe5h\x02'\x91\xef\xe0\xb9\x9f\xa9\xd83\x1f\xc1$\xd6\xb1\x87R\xd4Wp\x8b\x16\xda\xf8L\x17\x14\xc2T\xc7\x15^\xf1\x80%\x1c\x07\xc1\x0b\xc62\x9e\xa7\x88u\x94\x8a\x91@\x92\xa1\xa8\xc5x\xc65\xde1\x87j\xc6[B=\xb9i\x96\x7f\xf1\x15\x88\xed\x0cr-v\x16\xc8\x87\xb8\xc4<V\xc33\x96\xdeA\x8aX\xcf4\xdb\xe9\xb9\x9f\x1a\xee\xb0\x81\xe9\xb4yJ\x90\xa7\x8b\x88k\x11[h`\x14\xe7h\x14\x0d\xae\x1f\xecG\xfe\x8d\x83\xec\x11\x06\xd1\x09\xde\"\xdf\xc5\x11\x92\xc2\x10\x0d\x16\xf0\x18\xf9x\xfaj\xc3\x10T\xfb\x15\x87!H5\x8bJ\xb6A\x05\xa7\xf8\x18\xb0\xc1=\x96q\x86J\x01\x1dla*b\xf7\x9f\xc5w\xd8\x0e\xef&:I\xceo\\\x0cC\x92\xa9\x9d\xc6f\xff\xfe\xc6T\xdd\xa0\x99\x89\xf9C\x1e\xd2\xdf\x00\x9f\x1c;nP\xff`~\x00\x00\x00\x00IEND\xaeB`\x82"))
 	bindata.RegisterFile(filepath.Join("static", "file.svg"), time.Unix(1440218376, 0), []byte("<?xml version=\"1.0\" encoding=\"utf-8\"?>\x0d\n<!DOCTYPE svg PUBLIC \"-//W3C//DTD SVG 1.1//EN\" \"http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd\" [\x0d\n\x09<!ENTITY st0 \"fill:url(#SVGID_1_);\">\x0d\n\x09<!ENTITY st1 \"fill:#ABABAB;\">\x0d\n\x09<!ENTITY st2 \"fill:url(#SVGID_2_);\">\x0d\n]>\x0d\n<svg version=\"1.1\" id=\"Layer_1\" xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" x=\"0px\" y=\"0px\"\x0d\n\x09 width=\"100px\" height=\"100px\" viewBox=\"0 0 100 100\" style=\"enable-background:new 0 0 100 100;\" xml:space=\"preserve\">\x0d\n<g>\x0d\n\x09<linearGradient id=\"SVGID_1_\" gradientUnits=\"userSpaceOnUse\" x1=\"50\" y1=\"98.5\" x2=\"50\" y2=\"1.5\">\x0d\n\x09\x09<stop  offset=\"0\" style=\"stop-color:#E8E8E8\"/>\x0d\n\x09\x09<stop  offset=\"0.1339\" style=\"stop-color:#EDEDED\"/>\x0d\n\x09\x09<stop  offset=\"0.5859\" style=\"stop-color:#FBFBFB\"/>\x0d\n\x09\x09<stop  offset=\"1\" style=\"stop-color:#FFFFFF\"/>\x0d\n\x09</linearGradient>\x0d\n\x09<polygon style=\"&st0;\" points=\"15.5,98.5 15.5,1.5 64.207,1.5 84.5,21.793 84.5,98.5 \x09\"/>\x0d\n\x09<path style=\"&st1;\" d=\"M64,2l20,20v76H16V2H64 M64.414,1H64H16h-1v1v96v1h1h68h1v-1V22v-0.414l-0.293-0.293l-20-20L64.414,1\x0d\n\x09\x09L64.414,1z\"/>\x0d\n</g>\x0d\n<g>\x0d\n\x09\x0d\n\x09\x09<linearGradient id=\"SVGID_2_\" gradientUnits=\"userSpaceOnUse\" x1=\"74.0732\" y1=\"22.3535\" x2=\"74.0732\" y2=\"1.5\" gradientTransform=\"matrix(-1 0 0 -1 148 24)\">\x0d\n\x09\x09<stop  offset=\"0\" style=\"stop-color:#DEDEDE\"/>\x0d\n\x09\x09<stop  offset=\"0.2894\" style=\"stop-color:#EDEDED\"/>\x0d\n\x09\x09<stop  offset=\"0.6602\" style=\"stop-color:#FBFBFB\"/>\x0d\n\x09\x09<stop  offset=\"1\" style=\"stop-color:#FFFFFF\"/>\x0d\n\x09</linearGradient>\x0d\n\x09<polygon style=\"&st2;\" points=\"63.5,22.5 63.5,2 64.354,1.646 84.354,21.646 84,22.5 \x09\"/>\x0d\n\x09<path style=\"&st1;\" d=\"M64,2l20,20H64V2 M64.707,1.293L63,2v20v1h1h20l0.707-1.707L64.707,1.293L64.707,1.293z\"/>\x0d\n</g>\x0d\n</svg>\x0d\n"))
 	bindata.RegisterFile(filepath.Join("static", "history.js"), time.Unix(1449817215, 0), []byte("(function() {\n\x09'use strict';\n\n\x09function bindHistoryItem(item) {\n\x09\x09var a = item.querySelector('a.delete-upload');\n\x09\x09a.addEventListener('click', function() {\n\x09\x09\x09item.style.opacity = '0.5';\n\x09\x09\x09var path = '/-/delete/' + item.dataset.id;\n\n\x09\x09\x09json('POST', path, null, function(code, resp) {\n\x09\x09\x09\x09switch (code) {\n\x09\x09\x09\x09case 204:\n\x09\x09\x09\x09\x09item.style.opacity = '0.0';\n\x09\x09\x09\x09\x09item.addEventListener('transitionend', function(e) {\n\x09\x09\x09\x09\x09\x09reloadSection(window.location.pathname, '#history', setupHistory);\n\x09\x09\x09\x09\x09}, false);\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09case 403:\n\x09\x09\x09\x09\x09redirectLogin();\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09item.style.opacity = '';\n\x09\x09\x09\x09\x09errorMessage(resp);\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09}\n\x09\x09\x09});\n\x09\x09}, false);\n\x09}\n\n\x09function setupHistory() {\n\x09\x09var items = $$('.history-item');\n\x09\x09Array.prototype.forEach.call(items, bindHistoryItem);\n\x09}\n\n\x09window.addEventListener('DOMContentLoaded', setupHistory, true);\n})();\n"))
-	bindata.RegisterFile(filepath.Join("static", "style.css"), time.Unix(1528666514, 0), []byte("* {\n\x09margin: 0;\n\x09padding: 0;\n\x09box-sizing: border-box;\n\x09-moz-box-sizing: border-box;\n}\n\n*::selection {\n\x09background: #c64;\n\x09color: #fff;\n}\n*::-moz-selection {\n\x09background: #c64;\n\x09color: #fff;\n}\n\nhtml {\n\x09width: 100%;\n\x09height: 100%;\n\x09background: #fafafa;\n}\nbody {\n\x09padding: 64px;\n\x09font-family: clear sans,sans-serif;\n}\n#nav {\n\x09text-align: center;\n\x09width: 100%;\n\x09margin-bottom: 16px;\n\x09font-size: 16px;\n}\n.floating-section {\n\x09margin: 0 auto 16px;\n\x09width: 512px;\n\x09padding: 32px;\n\x09background: #fff;\n\x09position: relative;\n\x09border: 3px solid #eee;\n}\nsection h1 {\n\x09text-transform: uppercase;\n\x09font-size: 20px;\n\x09color: #888;\n\x09margin-bottom: 16px;\n}\na, a:visited {\n\x09color: #a42;\n}\na:hover {\n\x09color: #c64;\n}\nhr {\n\x09border: 0;\n\x09border-top: 1px solid #ccc;\n\x09margin: 16px 0;\n}\nbutton {\n\x09-webkit-appearance: none;\n\x09-moz-appearance: none;\n\x09-ms-appearance: none;\n\x09padding: 8px;\n\x09font-size: 14px;\n\x09font-weight: 700;\n\x09border: none;\n\x09background: #eaeaea;\n\x09color: #444;\n\x09font-family: clear sans,sans-serif;\n\x09margin-right: 8px;\n\x09outline: 0;\n}\nbutton:hover {\n\x09background: #c64;\n\x09color: #fff;\n}\nbutton:active {\n\x09background: #a42;\n\x09color: #fff;\n}\nlabel {\n\x09display: block;\n\x09font-weight: 700;\n\x09font-size: 14px;\n\x09color: #666;\n}\ninput[type=text], input[type=password], input[type=number], select {\n\x09width: 100%;\n\x09padding: 8px;\n\x09margin: 8px 0;\n\x09-webkit-appearance: none;\n\x09-moz-appearance: none;\n\x09-ms-appearance: none;\n\x09background: #fafafa;\n\x09font-family: clear sans,sans-serif;\n\x09font-size: 18px;\n\x09color: #444;\n\x09border: 1px solid #ccc;\n}\n.checkbox {\n\x09margin-bottom: 8px;\n}\ninput[type=\"checkbox\"] + label {\n\x09display: inline-block;\n\x09line-height: 20px;\n}\ninput[type=checkbox] {\n\x09-moz-appearance: none;\n\x09-webkit-appearance: none;\n\x09-ms-appearance: none;\n\x09appearance: none;\n\x09width: 20px;\n\x09height: 20px;\n\x09position: relative;\n\x09margin-right: 4px;\n\x09background: #fafafa;\n\x09border: 1px solid #ccc;\n\x09border-radius: 2px;\n\x09vertical-align: bottom;\n}\ninput[type=checkbox]:checked {\n\x09background: #888;\n\x09border-color: #000;\n}\ninput[type=checkbox]:checked:after {\n\x09position: absolute;\n\x09top: 0;\n\x09left: 0;\n\x09content: \"\xe2\x9c\x93\";\n\x09font-weight: 700;\n\x09font-size: 18px;\n\x09line-height: 18px;\n\x09width: 18px;\n\x09text-align: center;\n\x09color: #f0f0f0;\n}\ninput:focus, select:focus {\n\x09outline: none;\n\x09border: 2px solid #888;\n\x09margin: 7px -1px;\n\x09padding-right: 7px;\n}\ninput[type=checkbox]:focus {\n\x09margin: -1px 3px -1px -1px;\n\x09width: 22px;\n\x09height: 22px;\n\x09padding-right: 0;\n}\n\n/*** Range input ***/\n\ninput[type=range] {\n\x09-webkit-appearance: none;\n\x09width: 100%;\n\x09margin: 8px 0;\n}\n\ninput[type=range]::-webkit-slider-thumb { -webkit-appearance: none; }\n\ninput[type=range]:focus {\n\x09outline: none !important;\n\x09border: none !important;\n\x09margin: 8px 0;\n\x09padding: 0;\n}\n\ninput[type=range]::-ms-track {\n\x09width: 100%;\n\x09cursor: pointer;\n\x09background: transparent;\n\x09border-color: transparent;\n\x09color: transparent;\n}\n\ninput[type=range]::-webkit-slider-thumb {\n\x09-webkit-appearance: none;\n\x09margin-top: -1px;\n}\n\ninput[type=range]::-webkit-slider-thumb {\n\x09width: 16px;\n\x09height: 16px;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 -1px 2px #eee inset;\n\x09background: #fff;\n}\ninput[type=range]::-ms-thumb {\n\x09width: 16px;\n\x09height: 16px;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 -1px 2px #eee inset;\n\x09background: #fff;\n}\ninput[type=range]::-moz-range-thumb {\n\x09width: 16px;\n\x09height: 16px;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 -1px 2px #eee inset;\n\x09background: #fff;\n}\n\ninput[type=range]::-webkit-slider-runnable-track {\n\x09width: 100%;\n\x09height: 16px;\n\x09cursor: pointer;\n\x09background: #fafafa;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n}\ninput[type=range]::-moz-range-track {\n\x09width: 100%;\n\x09height: 16px;\n\x09cursor: pointer;\n\x09background: #fafafa;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n}\ninput[type=range]::-ms-track {\n\x09width: 100%;\n\x09height: 16px;\n\x09cursor: pointer;\n\x09background: #fafafa;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n}\n\ninput[type=range]:focus::-webkit-slider-runnable-track { background: #eee; }\ninput[type=range]:focus::-ms-track { background: #eee; }\n\ninput[type=range]:focus::-ms-fill-upper,\ninput[type=range]::-ms-fill-lower,\ninput[type=range]:focus::-ms-fill-lower,\ninput[type=range]::-ms-fill-upper {\n\x09background: transparent;\n}\n\n.box {\n\x09display: inline-block;\n\x09position: relative;\n\x09width: 100%;\n\x09margin-bottom: 8px;\n}\n.box[data-tooltip]::before {\n\x09z-index: 9;\n\x09content: attr(data-tooltip);\n\x09display: none;\n\x09position: absolute;\n\x09font-size: 12px;\n\x09background: #fff;\n\x09color: #444;\n\x09border-radius: 2px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 3px 10px rgba(0, 0, 0, .2);\n\x09padding: 8px;\n\x09width: 256px;\n}\n.box[data-tooltip]:hover::before {\n\x09display: block;\n}\n.box[data-tt-pos=left]::before {\n\x09right: 100%;\n\x09margin-right: 16px;\n}\n.box[data-tt-pos=right]::before {\n\x09left: 100%;\n\x09margin-left: 16px;\n}\n.box[data-tt-pos=top]::before {\n\x09left: 50%;\n\x09margin-left: -128px;\n\x09bottom: 100%;\n\x09margin-bottom: 16px;\n}\n.box.check-enable small {\n\x09display: block;\n\x09position: absolute;\n\x09padding-top: 15px;\n\x09padding-left: 30px;\n}\n.hidee {\n\x09float: right;\n\x09width: 200px;\n}\n.hider:not(:checked) ~ .hidee * {\n\x09-webkit-user-select: none;\n\x09-moz-user-select: none;\n\x09-ms-user-select: none;\n\x09user-select: none;\n\x09opacity: 0.2;\n}\n#host-box {\n\x09width: 280px;\n}\n#id-box {\n\x09width: 125px;\n\x09font-size: 18px;\n\x09color: #888;\n}\n.col3 {\n\x09width: 123px;\n\x09margin-right: 32px;\n}\n* > .col3:nth-of-type(3n) {\n\x09margin-right: 0;\n}\n\n#sample-ext {\n\x09display: none;\n}\n#sample-ext.show {\n\x09display: inline;\n}\n\n#message-box {\n\x09padding: 16px;\n\x09position: fixed;\n\x09top: -64px;\n\x09width: 512px;\n\x09text-align: center;\n\x09left: 50%;\n\x09margin-left: -256px;\n\x09transition: top 0.5s cubic-bezier(0, 0.8, 0.2, 1);\n\x09z-index: 9999;\n}\n#message-box.active {\n\x09top: 16px;\n}\n#message-box.bad {\n\x09background: #fee;\n\x09color: #800;\n}\n#message-box.good {\n\x09background: #eef4ee;\n\x09color: #444;\n}\n#message-box:before {\n\x09border-width: 1px;\n\x09border-style: solid;\n\x09border-radius: 3px;\n\x09display: inline-block;\n\x09height: 20px;\n\x09width: 20px;\n\x09line-height: 18px;\n\x09font-size: 18px;\n\x09text-align: center;\n\x09margin-right: 8px;\n\x09font-weight: 900;\n}\n#message-box.good:before {\n\x09content: \"\xe2\x9c\x93\";\n\x09color: #080;\n\x09border-color: #4c4;\n}\n#message-box.bad:before {\n\x09content: \"!\";\n\x09font-family: georgia, serif;\n\x09font-style: italic;\n\x09color: #800;\n\x09border-color: #c44;\n}\n#twitter-card--hidden {\n\x09margin-top: 8px;\n\x09display: none;\n}\ninput#twitter-card:checked ~ #twitter-card--hidden {\n\x09display: block;\n}\n\n#history {\n\x09padding: 64px;\n}\n\n#history ul {\n\x09list-style-type: none;\n\x09display: flex;\n\x09flex-flow: row wrap;\n\x09justify-content: center;\n\x09align-content: flex-start;\n\x09align-items: flex-start;\n\x09-webkit-display: flex;\n\x09-webkit-flex-flow: row wrap;\n\x09-webkit-justify-content: center;\n\x09-webkit-align-content: flex-start;\n\x09-webkit-align-items: flex-start;\n}\n\n.history-item {\n\x09display: inline-block;\n\x09padding: 16px;\n\x09transition: opacity;\n\x09transition-duration: 0.5s;\n}\n.upload-link {\n\x09display: block;\n\x09width: 100px;\n\x09height: 100px;\n\x09text-align: center;\n}\n.upload-link img {\n\x09display: block;\n\x09margin: 0 auto;\n}\n.upload-link .file-ext-overlay {\n\x09position: relative;\n\x09display: inline-block;\n\x09background: #c64;\n\x09padding: 0 6px;\n\x09font-size: 16px;\n\x09text-transform: uppercase;\n\x09color: #fff;\n\x09bottom: 36px;\n\x09font-weight: 700;\n}\n.history-item-name {\n\x09width: 100px;\n\x09white-space: nowrap;\n\x09overflow: hidden;\n\x09text-overflow: ellipsis;\n\x09font-size: 14px;\n}\n\n.history-item-data {\n\x09color: #888;\n\x09font-size: 12px;\n}\n.delete-upload {\n\x09color: #888;\n}\n\n#upload-form.active {\n\x09border: 4px solid #c64;\n\x09margin: -4px;\n}\n#picker {\n\x09visibility: hidden;\n\x09position: absolute;\n\x09width: 0;\n\x09height: 0;\n}\n#drop-zone {\n\x09height: 128px;\n\x09position: relative;\n\x09border: 4px dashed #aaa;\n\x09color: #888;\n\x09cursor: pointer;\n}\n#drop-zone-text {\n\x09position: absolute;\n\x09height: 120px;\n\x09width: 100%;\n\x09line-height: 120px;\n\x09font-size: 20px;\n\x09text-align: center;\n\x09z-index: 9;\n}\n#drop-zone.active {\n\x09border: 4px solid #c64;\n\x09background: #fa8;\n\x09color: #fff;\n}\n#drop-zone svg {\n\x09width: 100%;\n\x09height: 128px;\n}\n#drop-zone svg line {\n\x09stroke: #c64;\n\x09stroke-width: 2;\n}\n.progress-bar {\n\x09position: absolute;\n\x09left: 0;\n\x09top: 0;\n\x09height: 100%;\n\x09width: 0%;\n\x09background: #c64;\n\x09z-index: 1;\n}\n#uploaded-urls {\n\x09display: none;\n\x09margin-top: 32px;\n\x09text-align: center;\n}\n#uploaded-urls.active {\n\x09display: block;\n}\n#uploaded-urls ul {\n\x09list-style-type: none;\n}\n#uploaded-urls ul a {\n\x09font-size: 20px;\n\x09line-height: 32px;\n}\n.pagination {\n\x09text-align: center;\n\x09margin: 32px;\n}\n.prevnext {\n\x09visibility: hidden;\n}\n.prevnext.active {\n\x09visibility: visible;\n}\n\n#front {\n\x09text-align: center;\n}\n#big-logo {\n\x09display: flex;\n\x09justify-content: center;\n\x09align-items: center;\n\x09height: 512px;\n\x09color: #aaa;\n\x09font-size: 32px;\n\x09font-weight: 300;\n\x09background: url('/-/static/airlift.svg') center no-repeat;\n}\n\n.login-link a {\n\x09color: #ddd;\n}\n\n#version {\n\x09font-size: 12px;\n\x09color: #888;\n\x09text-align: center;\n}\n\n@media screen and (max-width: 768px) {\n\x09body {\n\x09\x09padding: 32px 0;\n\x09}\n\x09.floating-section {\n\x09\x09width: 100%;\n\x09\x09border-left: none;\n\x09\x09border-right: none;\n\x09\x09padding: 16px;\n\x09}\n\x09.box {\n\x09\x09width: 100% !important;\n\x09\x09margin-right: 0 !important;\n\x09\x09margin-bottom: 16px;\n\x09}\n\x09#history {\n\x09\x09padding: 8px;\n\x09}\n\x09.history-item {\n\x09\x09padding: 16px 8px;\n\x09}\n\x09.box[data-tooltip]:hover::before {\n\x09\x09display: none !important;\n\x09}\n\x09#message-box {\n\x09\x09width: 100%;\n\x09\x09margin: 0;\n\x09\x09left: 0;\n\x09}\n\x09#message-box.active {\n\x09\x09top: 0;\n\x09}\n\x09input[type=checkbox] {\n\x09\x09float: right;\n\x09}\n\x09.box.check-enable small {\n\x09\x09display: inline;\n\x09\x09position: relative;\n\x09}\n\x09.hider, .hider + label {\n\x09\x09margin-bottom: 16px;\n\x09}\n\x09.hidee {\n\x09\x09float: none;\n\x09\x09width: 100%;\n\x09}\n\x09#big-logo {\n\x09\x09font-size: 22px;\n\x09\x09height: 256px;\n\x09\x09background-size: contain;\n\x09}\n}\n@media screen and (max-width: 320px) {\n\x09#history {\n\x09\x09padding: 0 0 0 40px;\n\x09}\n\x09.history-item {\n\x09\x09width: 136px;\n\x09\x09padding: 0 40px 16px 0;\n\x09}\n}\n\n@media\nonly screen and (-webkit-min-device-pixel-ratio: 2), /* safari */\nonly screen and (min-device-pixel-ratio: 2), /* old version */\nonly screen and (min-resolution: 192dpi), /* IE 9..11 and opera mini */\nonly screen and (min-resolution: 2dppx) {  /* compliant */\n\x09input[type=range]::-webkit-slider-thumb {\n\x09\x09width: 24px;\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-ms-thumb {\n\x09\x09width: 24px;\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-moz-range-thumb {\n\x09\x09width: 24px;\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\n\x09input[type=range]::-webkit-slider-runnable-track {\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-moz-range-track {\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-ms-track {\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=\"checkbox\"] + label {\n\x09\x09line-height: 32px;\n\x09}\n\x09input[type=checkbox] {\n\x09\x09width: 32px;\n\x09\x09height: 32px;\n\x09\x09border-radius: 3px;\n\x09}\n\x09input[type=checkbox]:checked:after {\n\x09\x09font-size: 28px;\n\x09\x09line-height: 30px;\n\x09\x09width: 30px;\n\x09}\n}\n"))
-	bindata.RegisterFile(filepath.Join("static", "syntax.css"), time.Unix(1528666514, 0), []byte(".syntax .raw {\n  display: block;\n  position: fixed;\n  top: 20px;\n  right: 20px;\n  padding: 10px;\n  border-radius: 5px;\n  background: white;\n  color: black;\n  font-family: sans-serif;\n  text-decoration: none;\n  user-select: none;\n  -ms-user-select: none;\n  -moz-user-select: none;\n  -webkit-user-select: none;\n}\n\n.syntax .raw:hover { background: #d1d1d1; }\n\n.syntax .raw svg {\n  display: inline-block;\n  padding-left: 5px;\n  vertical-align: middle;\n  width: 18px;\n  height: 18px;\n}\n\n.chroma {\n  -moz-tab-size: 4;\n  -o-tab-size: 4;\n  tab-size: 4;\n}\n"))
-	bindata.RegisterFile(filepath.Join("static", "uploader.js"), time.Unix(1451090931, 0), []byte("(function() {\n\x09'use strict';\n\n\x09var dropZone, dropZoneText, picker, urlList, bar;\n\n\x09function paste(e) {\n\x09\x09var item;\n\x09\x09var c = chain();\n\n\x09\x09for (var i = 0; i < e.clipboardData.items.length; i++) {\n\x09\x09\x09(function(item) {\n\x09\x09\x09\x09c.then(function(pass, fail, items) {\n\x09\x09\x09\x09\x09switch (item.kind) {\n\x09\x09\x09\x09\x09case 'file':\n\x09\x09\x09\x09\x09\x09var blob = item.getAsFile();\n\x09\x09\x09\x09\x09\x09blob.name = 'Paste ' + new Date().toISOString() + '.png';\n\x09\x09\x09\x09\x09\x09items.push(blob);\n\x09\x09\x09\x09\x09\x09pass(items);\n\x09\x09\x09\x09\x09\x09break;\n\n\x09\x09\x09\x09\x09case 'string':\n\x09\x09\x09\x09\x09\x09item.getAsString(function(s) {\n\x09\x09\x09\x09\x09\x09\x09var blob = new Blob([s]);\n\x09\x09\x09\x09\x09\x09\x09blob.name = 'Paste ' + new Date().toISOString() + '.txt';\n\x09\x09\x09\x09\x09\x09\x09items.push(blob);\n\x09\x09\x09\x09\x09\x09\x09pass(items);\n\x09\x09\x09\x09\x09\x09});\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09});\n\x09\x09\x09})(e.clipboardData.items[i]);\n\x09\x09}\n\n\x09\x09c.then(function(pass, fail, items) {\n\x09\x09\x09uploadFiles(items);\n\x09\x09}).pass([]);\n\x09}\n\n\x09function setURLList(urls) {\n\x09\x09var ul = urlList.querySelector('ul');\n\x09\x09ul.sacrificeChildren();\n\x09\x09for (var i = 0, url, li, a; url = urls[i]; i++) {\n\x09\x09\x09li = document.createElement('li');\n\x09\x09\x09a = document.createElement('a');\n\x09\x09\x09a.href = a.innerText = a.textContent = url;\n\x09\x09\x09li.appendChild(a);\n\x09\x09\x09ul.appendChild(li);\n\x09\x09}\n\x09\x09urlList.classList.add('active');\n\x09}\n\n\x09function dropZoneEnter(e) {\n\x09\x09var dt = e.dataTransfer;\n\x09\x09if (dt != null && Array.prototype.indexOf.call(dt.types, 'Files') >= 0) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09e.stopPropagation();\n\x09\x09\x09dropZone.classList.add('active');\n\x09\x09}\n\x09}\n\n\x09function dropZoneLeave(e) {\n\x09\x09e.preventDefault();\n\x09\x09e.stopPropagation();\n\x09\x09dropZone.classList.remove('active');\n\x09}\n\n\x09function dropped(e) {\n\x09\x09e.stopPropagation();\n\x09\x09e.preventDefault();\n\x09\x09uploadFiles(e.dataTransfer.files);\n\x09}\n\n\x09function uploadFiles(fileList) {\n\x09\x09if (fileList == null || fileList.length == 0) {\n\x09\x09\x09finish();\n\x09\x09\x09return;\n\x09\x09}\n\n\x09\x09var totalSize = 0;\n\x09\x09var svg, err, x;\n\n\x09\x09for (var i = 0; i < fileList.length; i++) {\n\x09\x09\x09totalSize += fileList[i].size;\n\x09\x09}\n\n\x09\x09if (fileList.length > 1) {\n\x09\x09\x09svg = dropZone.querySelector('svg');\n\x09\x09\x09if (svg == null) {\n\x09\x09\x09\x09svg = makesvg('svg');\n\x09\x09\x09\x09dropZone.appendChild(svg);\n\x09\x09\x09}\n\x09\x09\x09svg.sacrificeChildren();\n\n\x09\x09\x09var i, acc, pos;\n\n\x09\x09\x09for (i = acc = 0; i < fileList.length; i++) {\n\x09\x09\x09\x09acc += fileList[i].size;\n\x09\x09\x09\x09pos = acc/totalSize * svg.offsetWidth;\n\x09\x09\x09\x09var line = makesvg('line');\n\x09\x09\x09\x09line.setAttribute('x1', pos);\n\x09\x09\x09\x09line.setAttribute('x2', pos);\n\x09\x09\x09\x09line.setAttribute('y1', 0);\n\x09\x09\x09\x09line.setAttribute('y2', dropZone.offsetHeight - 8);\n\x09\x09\x09\x09svg.appendChild(line);\n\x09\x09\x09}\n\x09\x09}\n\n\x09\x09bar.style.width = '0%';\n\x09\x09urlList.classList.remove('active');\n\x09\x09dropZone.classList.add('active');\n\n\x09\x09var cancel = function() {\n\x09\x09\x09if (x != null) {\n\x09\x09\x09\x09x.abort();\n\x09\x09\x09\x09dropZone.removeEventListener(cancel);\n\x09\x09\x09\x09finish();\n\x09\x09\x09}\n\x09\x09\x09if (svg != null) {\n\x09\x09\x09\x09svg.sacrificeChildren();\n\x09\x09\x09}\n\x09\x09};\n\x09\x09dropZone.removeEventListener('click', clickPicker);\n\x09\x09dropZone.addEventListener('click', cancel, false);\n\n\x09\x09dropZoneText.dataset.oldText = dropZoneText.innerText;\n\x09\x09dropZoneText.innerText = 'Cancel';\n\n\x09\x09var c = chain();\n\n\x09\x09for (var i = 0; i < fileList.length; i++) {\n\x09\x09\x09(function(file) {\n\x09\x09\x09\x09c.then(function(pass, fail, result, totalLoaded) {\n\x09\x09\x09\x09\x09json('POST', '/upload/web', file, function(code, resp) {\n\x09\x09\x09\x09\x09\x09switch (code) {\n\x09\x09\x09\x09\x09\x09case 201:\n\x09\x09\x09\x09\x09\x09\x09result.push(window.location.protocol + '//' + resp.URL);\n\x09\x09\x09\x09\x09\x09\x09pass(result, totalLoaded);\n\x09\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09\x09case 403:\n\x09\x09\x09\x09\x09\x09\x09redirectLogin();\n\x09\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09\x09\x09fail(resp);\n\x09\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09}, function(x, afteropen) {\n\x09\x09\x09\x09\x09\x09if (!afteropen) {\n\x09\x09\x09\x09\x09\x09\x09x.upload.addEventListener('progress', function(e) {\n\x09\x09\x09\x09\x09\x09\x09\x09if (e.lengthComputable) {\n\x09\x09\x09\x09\x09\x09\x09\x09\x09bar.style.width = ((totalLoaded + e.loaded)*100 / totalSize) + '%';\n\x09\x09\x09\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09\x09\x09}, false);\n\n\x09\x09\x09\x09\x09\x09\x09x.upload.addEventListener('load', function() {\n\x09\x09\x09\x09\x09\x09\x09\x09totalLoaded += file.size;\n\x09\x09\x09\x09\x09\x09\x09\x09bar.style.width = totalLoaded*100 / totalSize + '%';\n\x09\x09\x09\x09\x09\x09\x09}, false);\n\x09\x09\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09\x09\x09x.setRequestHeader('X-Airlift-Filename', encodeURIComponent(file.name));\n\x09\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09});\n\x09\x09\x09\x09});\n\x09\x09\x09})(fileList[i]);\n\x09\x09}\n\n\x09\x09c.then(function(pass, fail, result) {\n\x09\x09\x09finish();\n\x09\x09\x09setURLList(result);\n\x09\x09\x09dropZone.removeEventListener('click', cancel);\n\x09\x09\x09dropZone.addEventListener('click', clickPicker);\n\x09\x09\x09if (svg != null) {\n\x09\x09\x09\x09svg.sacrificeChildren();\n\x09\x09\x09}\n\x09\x09}).catch(errorMessage).pass([], 0);\n\x09}\n\n\x09function finish() {\n\x09\x09dropZone.classList.remove('active');\n\x09\x09dropZoneText.innerText = dropZoneText.dataset.oldText;\n\x09\x09bar.style.width = '0%';\n\x09\x09enable();\n\x09}\n\n\x09function enable() {\n\x09\x09dropZone.addEventListener('click', clickPicker, false);\n\x09\x09dropZoneText.addEventListener('dragenter', dropZoneEnter, false);\n\x09\x09dropZoneText.addEventListener('dragover', dropZoneEnter, false);\n\x09\x09dropZoneText.addEventListener('dragleave', dropZoneLeave, false);\n\x09\x09dropZoneText.addEventListener('drop', dropped, false);\n\x09}\n\n\x09function disable() {\n\x09\x09dropZoneText.removeEventListener('dragenter');\n\x09\x09dropZoneText.removeEventListener('dragover');\n\x09\x09dropZoneText.removeEventListener('dragleave');\n\x09\x09dropZoneText.removeEventListener('drop');\n\x09}\n\n\x09function clickPicker() {\n\x09\x09picker.click();\n\x09}\n\n\x09window.addEventListener('DOMContentLoaded', function() {\n\x09\x09dropZone     = $('#drop-zone');\n\x09\x09dropZoneText = $('#drop-zone-text');\n\x09\x09picker       = $('#picker');\n\x09\x09urlList      = $('#uploaded-urls');\n\x09\x09bar          = dropZone.querySelector('.progress-bar');\n\n\x09\x09picker.addEventListener('change', function(e) {\n\x09\x09\x09uploadFiles(this.files);\n\x09\x09}, false);\n\n\x09\x09window.addEventListener('paste', paste, false);\n\n\x09\x09enable();\n\x09}, false);\n})();\n"))
+	bindata.RegisterFile(filepath.Join("static", "paste.js"), time.Unix(1792200618, 0), []byte("(function() {\n\x09'use strict';\n\n\x09function setupPaste() {\n\x09\x09var button = $('#paste-submit');\n\x09\x09button.addEventListener('click', function(e) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09if ($('#paste-text').value === '') {\n\x09\x09\x09\x09showMessage('There is nothing to paste.', 'bad');\n\x09\x09\x09\x09return;\n\x09\x09\x09}\n\n\x09\x09\x09button.setAttribute('disabled', true);\n\x09\x09\x09json('POST', '/paste/web', new FormData($('#paste')), function(code, resp) {\n\x09\x09\x09\x09button.removeAttribute('disabled');\n\x09\x09\x09\x09switch (code) {\n\x09\x09\x09\x09case 201:\n\x09\x09\x09\x09\x09window.location = window.location.protocol + '//' + resp.URL;\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09case 403:\n\x09\x09\x09\x09\x09redirectLogin();\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09errorMessage(resp);\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09}\n\x09\x09\x09});\n\x09\x09}, false);\n\x09}\n\n\x09window.addEventListener('DOMContentLoaded', setupPaste, true);\n})();\n"))
+	bindata.RegisterFile(filepath.Join("static", "render.js"), time.Unix(1792200973, 0), []byte("(function() {\n\x09'use strict';\n\n\x09// cellValue returns something to sort a table cell by: its number if it\n\x09// is one, or else its text. A data-sort attribute stands in for the text.\n\x09function cellValue(row, col) {\n\x09\x09var cell = row.cells[col];\n\x09\x09var text = '';\n\x09\x09if (cell != null) {\n\x09\x09\x09text = cell.hasAttribute('data-sort') ? cell.getAttribute('data-sort') : cell.textContent.trim();\n\x09\x09}\n\x09\x09var n = Number(text.replace(/,/g, ''));\n\x09\x09return text !== '' && !isNaN(n) ? n : text.toLowerCase();\n\x09}\n\n\x09function compare(a, b) {\n\x09\x09if (typeof a === typeof b) {\n\x09\x09\x09return a < b ? -1 : a > b ? 1 : 0;\n\x09\x09}\n\x09\x09// numbers before text\n\x09\x09return typeof a === 'number' ? -1 : 1;\n\x09}\n\n\x09function sortBy(table, th) {\n\x09\x09var col = th.cellIndex;\n\x09\x09var desc = th.classList.contains('asc');\n\x09\x09var headings = table.tHead.rows[0].cells;\n\x09\x09for (var i = 0; i < headings.length; i++) {\n\x09\x09\x09headings[i].classList.remove('asc', 'desc');\n\x09\x09}\n\x09\x09th.classList.add(desc ? 'desc' : 'asc');\n\n\x09\x09var body = table.tBodies[0];\n\x09\x09var rows = Array.prototype.slice.call(body.rows);\n\x09\x09rows.sort(function(a, b) {\n\x09\x09\x09var c = compare(cellValue(a, col), cellValue(b, col));\n\x09\x09\x09return desc ? -c : c;\n\x09\x09});\n\x09\x09rows.forEach(function(row) { body.appendChild(row); });\n\x09}\n\n\x09function setupTables() {\n\x09\x09var tables = document.querySelectorAll('table.sortable');\n\x09\x09Array.prototype.forEach.call(tables, function(table) {\n\x09\x09\x09table.tHead.addEventListener('click', function(e) {\n\x09\x09\x09\x09var th = e.target.closest('th');\n\x09\x09\x09\x09if (th != null) {\n\x09\x09\x09\x09\x09sortBy(table, th);\n\x09\x09\x09\x09}\n\x09\x09\x09}, false);\n\x09\x09});\n\x09}\n\n\x09window.addEventListener('DOMContentLoaded', setupTables, true);\n})();\n"))
+	bindata.RegisterFile(filepath.Join("static", "style.css"), time.Unix(1792201049, 0), []byte("* {\n\x09margin: 0;\n\x09padding: 0;\n\x09box-sizing: border-box;\n\x09-moz-box-sizing: border-box;\n}\n\n*::selection {\n\x09background: #c64;\n\x09color: #fff;\n}\n*::-moz-selection {\n\x09background: #c64;\n\x09color: #fff;\n}\n\nhtml {\n\x09width: 100%;\n\x09height: 100%;\n\x09background: #fafafa;\n}\nbody {\n\x09padding: 64px;\n\x09font-family: clear sans,sans-serif;\n}\n#nav {\n\x09text-align: center;\n\x09width: 100%;\n\x09margin-bottom: 16px;\n\x09font-size: 16px;\n}\n.floating-section {\n\x09margin: 0 auto 16px;\n\x09width: 512px;\n\x09padding: 32px;\n\x09background: #fff;\n\x09position: relative;\n\x09border: 3px solid #eee;\n}\nsection h1 {\n\x09text-transform: uppercase;\n\x09font-size: 20px;\n\x09color: #888;\n\x09margin-bottom: 16px;\n}\na, a:visited {\n\x09color: #a42;\n}\na:hover {\n\x09color: #c64;\n}\nhr {\n\x09border: 0;\n\x09border-top: 1px solid #ccc;\n\x09margin: 16px 0;\n}\nbutton {\n\x09-webkit-appearance: none;\n\x09-moz-appearance: none;\n\x09-ms-appearance: none;\n\x09padding: 8px;\n\x09font-size: 14px;\n\x09font-weight: 700;\n\x09border: none;\n\x09background: #eaeaea;\n\x09color: #444;\n\x09font-family: clear sans,sans-serif;\n\x09margin-right: 8px;\n\x09outline: 0;\n}\nbutton:hover {\n\x09background: #c64;\n\x09color: #fff;\n}\nbutton:active {\n\x09background: #a42;\n\x09color: #fff;\n}\nlabel {\n\x09display: block;\n\x09font-weight: 700;\n\x09font-size: 14px;\n\x09color: #666;\n}\ninput[type=text], input[type=password], input[type=number], select, textarea {\n\x09width: 100%;\n\x09padding: 8px;\n\x09margin: 8px 0;\n\x09-webkit-appearance: none;\n\x09-moz-appearance: none;\n\x09-ms-appearance: none;\n\x09background: #fafafa;\n\x09font-family: clear sans,sans-serif;\n\x09font-size: 18px;\n\x09color: #444;\n\x09border: 1px solid #ccc;\n}\n.checkbox {\n\x09margin-bottom: 8px;\n}\ninput[type=\"checkbox\"] + label {\n\x09display: inline-block;\n\x09line-height: 20px;\n}\ninput[type=checkbox] {\n\x09-moz-appearance: none;\n\x09-webkit-appearance: none;\n\x09-ms-appearance: none;\n\x09appearance: none;\n\x09width: 20px;\n\x09height: 20px;\n\x09position: relative;\n\x09margin-right: 4px;\n\x09background: #fafafa;\n\x09border: 1px solid #ccc;\n\x09border-radius: 2px;\n\x09vertical-align: bottom;\n}\ninput[type=checkbox]:checked {\n\x09background: #888;\n\x09border-color: #000;\n}\ninput[type=checkbox]:checked:after {\n\x09position: absolute;\n\x09top: 0;\n\x09left: 0;\n\x09content: \"\xe2\x9c\x93\";\n\x09font-weight: 700;\n\x09font-size: 18px;\n\x09line-height: 18px;\n\x09width: 18px;\n\x09text-align: center;\n\x09color: #f0f0f0;\n}\ntextarea {\n\x09font-family: monospace;\n\x09font-size: 14px;\n\x09resize: vertical;\n}\ninput:focus, select:focus, textarea:focus {\n\x09outline: none;\n\x09border: 2px solid #888;\n\x09margin: 7px -1px;\n\x09padding-right: 7px;\n}\ninput[type=checkbox]:focus {\n\x09margin: -1px 3px -1px -1px;\n\x09width: 22px;\n\x09height: 22px;\n\x09padding-right: 0;\n}\n\n/*** Range input ***/\n\ninput[type=range] {\n\x09-webkit-appearance: none;\n\x09width: 100%;\n\x09margin: 8px 0;\n}\n\ninput[type=range]::-webkit-slider-thumb { -webkit-appearance: none; }\n\ninput[type=range]:focus {\n\x09outline: none !important;\n\x09border: none !important;\n\x09margin: 8px 0;\n\x09padding: 0;\n}\n\ninput[type=range]::-ms-track {\n\x09width: 100%;\n\x09cursor: pointer;\n\x09background: transparent;\n\x09border-color: transparent;\n\x09color: transparent;\n}\n\ninput[type=range]::-webkit-slider-thumb {\n\x09-webkit-appearance: none;\n\x09margin-top: -1px;\n}\n\ninput[type=range]::-webkit-slider-thumb {\n\x09width: 16px;\n\x09height: 16px;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 -1px 2px #eee inset;\n\x09background: #fff;\n}\ninput[type=range]::-ms-thumb {\n\x09width: 16px;\n\x09height: 16px;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 -1px 2px #eee inset;\n\x09background: #fff;\n}\ninput[type=range]::-moz-range-thumb {\n\x09width: 16px;\n\x09height: 16px;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 -1px 2px #eee inset;\n\x09background: #fff;\n}\n\ninput[type=range]::-webkit-slider-runnable-track {\n\x09width: 100%;\n\x09height: 16px;\n\x09cursor: pointer;\n\x09background: #fafafa;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n}\ninput[type=range]::-moz-range-track {\n\x09width: 100%;\n\x09height: 16px;\n\x09cursor: pointer;\n\x09background: #fafafa;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n}\ninput[type=range]::-ms-track {\n\x09width: 100%;\n\x09height: 16px;\n\x09cursor: pointer;\n\x09background: #fafafa;\n\x09border-radius: 8px;\n\x09border: 1px solid #ccc;\n}\n\ninput[type=range]:focus::-webkit-slider-runnable-track { background: #eee; }\ninput[type=range]:focus::-ms-track { background: #eee; }\n\ninput[type=range]:focus::-ms-fill-upper,\ninput[type=range]::-ms-fill-lower,\ninput[type=range]:focus::-ms-fill-lower,\ninput[type=range]::-ms-fill-upper {\n\x09background: transparent;\n}\n\n.box {\n\x09display: inline-block;\n\x09position: relative;\n\x09width: 100%;\n\x09margin-bottom: 8px;\n}\n.box[data-tooltip]::before {\n\x09z-index: 9;\n\x09content: attr(data-tooltip);\n\x09display: none;\n\x09position: absolute;\n\x09font-size: 12px;\n\x09background: #fff;\n\x09color: #444;\n\x09border-radius: 2px;\n\x09border: 1px solid #ccc;\n\x09box-shadow: 0 3px 10px rgba(0, 0, 0, .2);\n\x09padding: 8px;\n\x09width: 256px;\n}\n.box[data-tooltip]:hover::before {\n\x09display: block;\n}\n.box[data-tt-pos=left]::before {\n\x09right: 100%;\n\x09margin-right: 16px;\n}\n.box[data-tt-pos=right]::before {\n\x09left: 100%;\n\x09margin-left: 16px;\n}\n.box[data-tt-pos=top]::before {\n\x09left: 50%;\n\x09margin-left: -128px;\n\x09bottom: 100%;\n\x09margin-bottom: 16px;\n}\n.box.check-enable small {\n\x09display: block;\n\x09position: absolute;\n\x09padding-top: 15px;\n\x09padding-left: 30px;\n}\n.hidee {\n\x09float: right;\n\x09width: 200px;\n}\n.hider:not(:checked) ~ .hidee * {\n\x09-webkit-user-select: none;\n\x09-moz-user-select: none;\n\x09-ms-user-select: none;\n\x09user-select: none;\n\x09opacity: 0.2;\n}\n#host-box {\n\x09width: 280px;\n}\n#id-box {\n\x09width: 125px;\n\x09font-size: 18px;\n\x09color: #888;\n}\n.col3 {\n\x09width: 123px;\n\x09margin-right: 32px;\n}\n* > .col3:nth-of-type(3n) {\n\x09margin-right: 0;\n}\n\n#sample-ext {\n\x09display: none;\n}\n#sample-ext.show {\n\x09display: inline;\n}\n\n#message-box {\n\x09padding: 16px;\n\x09position: fixed;\n\x09top: -64px;\n\x09width: 512px;\n\x09text-align: center;\n\x09left: 50%;\n\x09margin-left: -256px;\n\x09transition: top 0.5s cubic-bezier(0, 0.8, 0.2, 1);\n\x09z-index: 9999;\n}\n#message-box.active {\n\x09top: 16px;\n}\n#message-box.bad {\n\x09background: #fee;\n\x09color: #800;\n}\n#message-box.good {\n\x09background: #eef4ee;\n\x09color: #444;\n}\n#message-box:before {\n\x09border-width: 1px;\n\x09border-style: solid;\n\x09border-radius: 3px;\n\x09display: inline-block;\n\x09height: 20px;\n\x09width: 20px;\n\x09line-height: 18px;\n\x09font-size: 18px;\n\x09text-align: center;\n\x09margin-right: 8px;\n\x09font-weight: 900;\n}\n#message-box.good:before {\n\x09content: \"\xe2\x9c\x93\";\n\x09color: #080;\n\x09border-color: #4c4;\n}\n#message-box.bad:before {\n\x09content: \"!\";\n\x09font-family: georgia, serif;\n\x09font-style: italic;\n\x09color: #800;\n\x09border-color: #c44;\n}\n#twitter-card--hidden {\n\x09margin-top: 8px;\n\x09display: none;\n}\ninput#twitter-card:checked ~ #twitter-card--hidden {\n\x09display: block;\n}\n\n#history {\n\x09padding: 64px;\n}\n\n#history ul {\n\x09list-style-type: none;\n\x09display: flex;\n\x09flex-flow: row wrap;\n\x09justify-content: center;\n\x09align-content: flex-start;\n\x09align-items: flex-start;\n\x09-webkit-display: flex;\n\x09-webkit-flex-flow: row wrap;\n\x09-webkit-justify-content: center;\n\x09-webkit-align-content: flex-start;\n\x09-webkit-align-items: flex-start;\n}\n\n.history-item {\n\x09display: inline-block;\n\x09padding: 16px;\n\x09transition: opacity;\n\x09transition-duration: 0.5s;\n}\n.upload-link {\n\x09display: block;\n\x09width: 100px;\n\x09height: 100px;\n\x09text-align: center;\n}\n.upload-link img {\n\x09display: block;\n\x09margin: 0 auto;\n}\n.upload-link .file-ext-overlay {\n\x09position: relative;\n\x09display: inline-block;\n\x09background: #c64;\n\x09padding: 0 6px;\n\x09font-size: 16px;\n\x09text-transform: uppercase;\n\x09color: #fff;\n\x09bottom: 36px;\n\x09font-weight: 700;\n}\n.history-item-name {\n\x09width: 100px;\n\x09white-space: nowrap;\n\x09overflow: hidden;\n\x09text-overflow: ellipsis;\n\x09font-size: 14px;\n}\n\n.history-item-data {\n\x09color: #888;\n\x09font-size: 12px;\n}\n.delete-upload {\n\x09color: #888;\n}\n\n#viewer {\n\x09margin: 0 auto;\n\x09max-width: 1024px;\n\x09text-align: center;\n}\n#viewer-media img, #viewer-media video {\n\x09max-width: 100%;\n\x09max-height: 80vh;\n\x09background: #fff;\n\x09border: 3px solid #eee;\n}\n#viewer-media audio {\n\x09width: 100%;\n}\n#viewer-info {\n\x09margin-top: 16px;\n\x09color: #888;\n\x09font-size: 14px;\n}\n#viewer-info h1 {\n\x09color: #444;\n\x09font-size: 20px;\n\x09overflow: hidden;\n\x09text-overflow: ellipsis;\n\x09white-space: nowrap;\n}\n#viewer-info p {\n\x09margin-top: 8px;\n}\n\n#upload-form.active {\n\x09border: 4px solid #c64;\n\x09margin: -4px;\n}\n#picker {\n\x09visibility: hidden;\n\x09position: absolute;\n\x09width: 0;\n\x09height: 0;\n}\n#drop-zone {\n\x09height: 128px;\n\x09position: relative;\n\x09border: 4px dashed #aaa;\n\x09color: #888;\n\x09cursor: pointer;\n}\n#drop-zone-text {\n\x09position: absolute;\n\x09height: 120px;\n\x09width: 100%;\n\x09line-height: 120px;\n\x09font-size: 20px;\n\x09text-align: center;\n\x09z-index: 9;\n}\n#drop-zone.active {\n\x09border: 4px solid #c64;\n\x09background: #fa8;\n\x09color: #fff;\n}\n#drop-zone svg {\n\x09width: 100%;\n\x09height: 128px;\n}\n#drop-zone svg line {\n\x09stroke: #c64;\n\x09stroke-width: 2;\n}\n.progress-bar {\n\x09position: absolute;\n\x09left: 0;\n\x09top: 0;\n\x09height: 100%;\n\x09width: 0%;\n\x09background: #c64;\n\x09z-index: 1;\n}\n#uploaded-urls {\n\x09display: none;\n\x09margin-top: 32px;\n\x09text-align: center;\n}\n#uploaded-urls.active {\n\x09display: block;\n}\n#uploaded-urls ul {\n\x09list-style-type: none;\n}\n#uploaded-urls ul a {\n\x09font-size: 20px;\n\x09line-height: 32px;\n}\n.pagination {\n\x09text-align: center;\n\x09margin: 32px;\n}\n.prevnext {\n\x09visibility: hidden;\n}\n.prevnext.active {\n\x09visibility: visible;\n}\n\n#front {\n\x09text-align: center;\n}\n#big-logo {\n\x09display: flex;\n\x09justify-content: center;\n\x09align-items: center;\n\x09height: 512px;\n\x09color: #aaa;\n\x09font-size: 32px;\n\x09font-weight: 300;\n\x09background: url('/-/static/airlift.svg') center no-repeat;\n}\n\n.login-link a {\n\x09color: #ddd;\n}\n\n#version {\n\x09font-size: 12px;\n\x09color: #888;\n\x09text-align: center;\n}\n\n@media screen and (max-width: 768px) {\n\x09body {\n\x09\x09padding: 32px 0;\n\x09}\n\x09.floating-section {\n\x09\x09width: 100%;\n\x09\x09border-left: none;\n\x09\x09border-right: none;\n\x09\x09padding: 16px;\n\x09}\n\x09.box {\n\x09\x09width: 100% !important;\n\x09\x09margin-right: 0 !important;\n\x09\x09margin-bottom: 16px;\n\x09}\n\x09#history {\n\x09\x09padding: 8px;\n\x09}\n\x09.history-item {\n\x09\x09padding: 16px 8px;\n\x09}\n\x09.box[data-tooltip]:hover::before {\n\x09\x09display: none !important;\n\x09}\n\x09#message-box {\n\x09\x09width: 100%;\n\x09\x09margin: 0;\n\x09\x09left: 0;\n\x09}\n\x09#message-box.active {\n\x09\x09top: 0;\n\x09}\n\x09input[type=checkbox] {\n\x09\x09float: right;\n\x09}\n\x09.box.check-enable small {\n\x09\x09display: inline;\n\x09\x09position: relative;\n\x09}\n\x09.hider, .hider + label {\n\x09\x09margin-bottom: 16px;\n\x09}\n\x09.hidee {\n\x09\x09float: none;\n\x09\x09width: 100%;\n\x09}\n\x09#big-logo {\n\x09\x09font-size: 22px;\n\x09\x09height: 256px;\n\x09\x09background-size: contain;\n\x09}\n}\n@media screen and (max-width: 320px) {\n\x09#history {\n\x09\x09padding: 0 0 0 40px;\n\x09}\n\x09.history-item {\n\x09\x09width: 136px;\n\x09\x09padding: 0 40px 16px 0;\n\x09}\n}\n\n@media\nonly screen and (-webkit-min-device-pixel-ratio: 2), /* safari */\nonly screen and (min-device-pixel-ratio: 2), /* old version */\nonly screen and (min-resolution: 192dpi), /* IE 9..11 and opera mini */\nonly screen and (min-resolution: 2dppx) {  /* compliant */\n\x09input[type=range]::-webkit-slider-thumb {\n\x09\x09width: 24px;\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-ms-thumb {\n\x09\x09width: 24px;\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-moz-range-thumb {\n\x09\x09width: 24px;\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\n\x09input[type=range]::-webkit-slider-runnable-track {\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-moz-range-track {\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=range]::-ms-track {\n\x09\x09height: 24px;\n\x09\x09border-radius: 12px;\n\x09}\n\x09input[type=\"checkbox\"] + label {\n\x09\x09line-height: 32px;\n\x09}\n\x09input[type=checkbox] {\n\x09\x09width: 32px;\n\x09\x09height: 32px;\n\x09\x09border-radius: 3px;\n\x09}\n\x09input[type=checkbox]:checked:after {\n\x09\x09font-size: 28px;\n\x09\x09line-height: 30px;\n\x09\x09width: 30px;\n\x09}\n}\n"))
+	bindata.RegisterFile(filepath.Join("static", "syntax.css"), time.Unix(1792200973, 0), []byte(".syntax .raw {\n  display: block;\n  position: fixed;\n  top: 20px;\n  right: 20px;\n  padding: 10px;\n  border-radius: 5px;\n  background: white;\n  color: black;\n  font-family: sans-serif;\n  text-decoration: none;\n  user-select: none;\n  -ms-user-select: none;\n  -moz-user-select: none;\n  -webkit-user-select: none;\n}\n\n.syntax .raw:hover { background: #d1d1d1; }\n\n.syntax .raw svg {\n  display: inline-block;\n  padding-left: 5px;\n  vertical-align: middle;\n  width: 18px;\n  height: 18px;\n}\n\n.chroma {\n  -moz-tab-size: 4;\n  -o-tab-size: 4;\n  tab-size: 4;\n}\n\n.chroma .line { display: block; }\n\n.chroma .ln {\n  text-decoration: none;\n  user-select: none;\n  -ms-user-select: none;\n  -moz-user-select: none;\n  -webkit-user-select: none;\n}\n\n.rendered {\n  max-width: 60em;\n  margin: 0 auto;\n  padding: 20px;\n  font-family: sans-serif;\n  line-height: 1.5;\n}\n\n.rendered img { max-width: 100%; }\n\n.rendered pre {\n  overflow-x: auto;\n  padding: 8px;\n}\n\n.rendered table {\n  border-collapse: collapse;\n  margin: 1em 0;\n}\n\n.rendered th, .rendered td {\n  padding: 4px 8px;\n  border: 1px solid rgba(128, 128, 128, 0.4);\n  text-align: left;\n  vertical-align: top;\n}\n\n.rendered table.sortable th {\n  cursor: pointer;\n  user-select: none;\n  -ms-user-select: none;\n  -moz-user-select: none;\n  -webkit-user-select: none;\n}\n\n.rendered th.asc::after { content: \" \xe2\x96\xb2\"; }\n.rendered th.desc::after { content: \" \xe2\x96\xbc\"; }\n\n.rendered .truncated { font-style: italic; }\n\n.rendered table.archive { width: 100%; }\n.rendered table.archive td:nth-child(2) { text-align: right; white-space: nowrap; }\n.rendered table.archive td:nth-child(3) { white-space: nowrap; }\n\n.rendered img.thumb {\n  width: 24px;\n  height: 24px;\n  margin-right: 6px;\n  object-fit: cover;\n  vertical-align: middle;\n}\n\n.notebook .cell { margin: 1em 0; }\n\n.notebook .prompt {\n  font-family: monospace;\n  opacity: 0.6;\n}\n\n.notebook .output {\n  border-left: 3px solid rgba(128, 128, 128, 0.4);\n  padding-left: 8px;\n}\n\n.notebook .output pre { margin: 4px 0; }\n.notebook .error { color: #c00; }\n"))
+	bindata.RegisterFile(filepath.Join("static", "syntax.js"), time.Unix(1792200618, 0), []byte("(function() {\n\x09'use strict';\n\n\x09// range returns the first and last line in a fragment like #L10 or\n\x09// #L10-L20, or null.\n\x09function range() {\n\x09\x09var m = /^#L(\\d+)(?:-L?(\\d+))?$/.exec(window.location.hash);\n\x09\x09if (m == null) {\n\x09\x09\x09return null;\n\x09\x09}\n\x09\x09var a = parseInt(m[1], 10);\n\x09\x09var b = m[2] != null ? parseInt(m[2], 10) : a;\n\x09\x09return a <= b ? [a, b] : [b, a];\n\x09}\n\n\x09function highlight(scroll) {\n\x09\x09var lit = document.querySelectorAll('.line.hl');\n\x09\x09for (var i = 0; i < lit.length; i++) {\n\x09\x09\x09lit[i].classList.remove('hl');\n\x09\x09}\n\n\x09\x09var r = range();\n\x09\x09if (r == null) {\n\x09\x09\x09return;\n\x09\x09}\n\x09\x09for (var n = r[0]; n <= r[1]; n++) {\n\x09\x09\x09var line = document.getElementById('L' + n);\n\x09\x09\x09if (line == null) {\n\x09\x09\x09\x09break;\n\x09\x09\x09}\n\x09\x09\x09line.classList.add('hl');\n\x09\x09}\n\x09\x09var first = document.getElementById('L' + r[0]);\n\x09\x09if (scroll && first != null) {\n\x09\x09\x09first.scrollIntoView({block: 'center'});\n\x09\x09}\n\x09}\n\n\x09// clicking a line number selects it, and shift-clicking another one\n\x09// selects the lines in between\n\x09document.addEventListener('click', function(e) {\n\x09\x09var a = e.target.closest('.ln');\n\x09\x09if (a == null) {\n\x09\x09\x09return;\n\x09\x09}\n\x09\x09e.preventDefault();\n\n\x09\x09var hash = '#' + a.parentNode.id;\n\x09\x09var r = range();\n\x09\x09if (e.shiftKey && r != null) {\n\x09\x09\x09var n = parseInt(a.parentNode.id.slice(1), 10);\n\x09\x09\x09hash = '#L' + Math.min(r[0], n) + '-L' + Math.max(r[0], n);\n\x09\x09}\n\x09\x09history.replaceState(null, '', hash);\n\x09\x09highlight(false);\n\x09}, false);\n\n\x09window.addEventListener('hashchange', function() { highlight(true); }, false);\n\x09highlight(true);\n})();\n"))
+	bindata.RegisterFile(filepath.Join("static", "uploader.js"), time.Unix(1792198935, 0), []byte("(function() {\n\x09'use strict';\n\n\x09var dropZone, dropZoneText, picker, urlList, bar;\n\n\x09var chunkSize  = 16 * 1024 * 1024;\n\x09var maxRetries = 5;\n\n\x09function b64(s) {\n\x09\x09return window.btoa(unescape(encodeURIComponent(s)));\n\x09}\n\n\x09function parseResp(x) {\n\x09\x09try {\n\x09\x09\x09return JSON.parse(x.response);\n\x09\x09} catch (err) {\n\x09\x09\x09return { Err: x.statusText || 'network error' };\n\x09\x09}\n\x09}\n\n\x09// tusUpload sends a file through the resumable upload endpoint. If the\n\x09// connection drops, the upload picks up from wherever the server left off,\n\x09// including after a page reload.\n\x09//\n\x09// progress func(loaded Number)\n\x09// done     func(url String)\n\x09// fail     func(code Number, resp Object)\n\x09function tusUpload(file, progress, done, fail) {\n\x09\x09var key = 'tus:' + [file.name, file.size, file.lastModified].join(':');\n\x09\x09var location = null, offset = 0, retries = 0, x = null, aborted = false;\n\n\x09\x09function req(method, url, cb) {\n\x09\x09\x09x = new XMLHttpRequest();\n\x09\x09\x09x.open(method, url, true);\n\x09\x09\x09x.setRequestHeader('Tus-Resumable', '1.0.0');\n\x09\x09\x09x.addEventListener('load', function(e) { cb(e.target); }, false);\n\x09\x09\x09x.addEventListener('error', retry, false);\n\x09\x09\x09return x;\n\x09\x09}\n\n\x09\x09function retry() {\n\x09\x09\x09if (aborted) {\n\x09\x09\x09\x09return;\n\x09\x09\x09}\n\x09\x09\x09if (retries++ < maxRetries && location != null) {\n\x09\x09\x09\x09window.setTimeout(head, 1000 * retries);\n\x09\x09\x09} else {\n\x09\x09\x09\x09fail(0, { Err: 'network error' });\n\x09\x09\x09}\n\x09\x09}\n\n\x09\x09function finish(url) {\n\x09\x09\x09window.localStorage.removeItem(key);\n\x09\x09\x09done(url);\n\x09\x09}\n\n\x09\x09function create() {\n\x09\x09\x09var x = req('POST', '/upload/tus', function(x) {\n\x09\x09\x09\x09switch (x.status) {\n\x09\x09\x09\x09case 201:\n\x09\x09\x09\x09\x09location = x.getResponseHeader('Location');\n\x09\x09\x09\x09\x09window.localStorage.setItem(key, location);\n\x09\x09\x09\x09\x09var url = x.getResponseHeader('X-Airlift-URL');\n\x09\x09\x09\x09\x09if (url) {\n\x09\x09\x09\x09\x09\x09finish(url);\n\x09\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09\x09patch();\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09fail(x.status, parseResp(x));\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09}\n\x09\x09\x09});\n\x09\x09\x09x.setRequestHeader('Upload-Length', file.size);\n\x09\x09\x09x.setRequestHeader('Upload-Metadata', 'filename ' + b64(file.name));\n\x09\x09\x09x.send(null);\n\x09\x09}\n\n\x09\x09function head() {\n\x09\x09\x09req('HEAD', location, function(x) {\n\x09\x09\x09\x09switch (x.status) {\n\x09\x09\x09\x09case 200:\n\x09\x09\x09\x09\x09offset = parseInt(x.getResponseHeader('Upload-Offset'));\n\x09\x09\x09\x09\x09var url = x.getResponseHeader('X-Airlift-URL');\n\x09\x09\x09\x09\x09if (url) {\n\x09\x09\x09\x09\x09\x09finish(url);\n\x09\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09\x09patch();\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09case 403:\n\x09\x09\x09\x09\x09fail(x.status, {});\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09// gone or never existed; start over\n\x09\x09\x09\x09\x09window.localStorage.removeItem(key);\n\x09\x09\x09\x09\x09location = null;\n\x09\x09\x09\x09\x09offset = 0;\n\x09\x09\x09\x09\x09create();\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09}\n\x09\x09\x09}).send(null);\n\x09\x09}\n\n\x09\x09function patch() {\n\x09\x09\x09var chunk = file.slice(offset, offset + chunkSize);\n\x09\x09\x09var x = req('PATCH', location, function(x) {\n\x09\x09\x09\x09switch (x.status) {\n\x09\x09\x09\x09case 204:\n\x09\x09\x09\x09\x09retries = 0;\n\x09\x09\x09\x09\x09offset = parseInt(x.getResponseHeader('Upload-Offset'));\n\x09\x09\x09\x09\x09var url = x.getResponseHeader('X-Airlift-URL');\n\x09\x09\x09\x09\x09if (url) {\n\x09\x09\x09\x09\x09\x09finish(url);\n\x09\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09\x09patch();\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09case 409:\n\x09\x09\x09\x09\x09head();\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09default:\n\x09\x09\x09\x09\x09fail(x.status, parseResp(x));\n\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09}\n\x09\x09\x09});\n\x09\x09\x09x.setRequestHeader('Content-Type', 'application/offset+octet-stream');\n\x09\x09\x09x.setRequestHeader('Upload-Offset', offset);\n\x09\x09\x09x.upload.addEventListener('progress', function(e) {\n\x09\x09\x09\x09if (e.lengthComputable) {\n\x09\x09\x09\x09\x09progress(offset + e.loaded);\n\x09\x09\x09\x09}\n\x09\x09\x09}, false);\n\x09\x09\x09x.send(chunk);\n\x09\x09}\n\n\x09\x09location = window.localStorage.getItem(key);\n\x09\x09if (location != null) {\n\x09\x09\x09head();\n\x09\x09} else {\n\x09\x09\x09create();\n\x09\x09}\n\n\x09\x09return {\n\x09\x09\x09abort: function() {\n\x09\x09\x09\x09aborted = true;\n\x09\x09\x09\x09if (x != null) {\n\x09\x09\x09\x09\x09x.abort();\n\x09\x09\x09\x09}\n\x09\x09\x09\x09if (location != null) {\n\x09\x09\x09\x09\x09window.localStorage.removeItem(key);\n\x09\x09\x09\x09\x09req('DELETE', location, function() {}).send(null);\n\x09\x09\x09\x09}\n\x09\x09\x09}\n\x09\x09};\n\x09}\n\n\x09function paste(e) {\n\x09\x09var item;\n\x09\x09var c = chain();\n\n\x09\x09for (var i = 0; i < e.clipboardData.items.length; i++) {\n\x09\x09\x09(function(item) {\n\x09\x09\x09\x09c.then(function(pass, fail, items) {\n\x09\x09\x09\x09\x09switch (item.kind) {\n\x09\x09\x09\x09\x09case 'file':\n\x09\x09\x09\x09\x09\x09var blob = item.getAsFile();\n\x09\x09\x09\x09\x09\x09blob.name = 'Paste ' + new Date().toISOString() + '.png';\n\x09\x09\x09\x09\x09\x09items.push(blob);\n\x09\x09\x09\x09\x09\x09pass(items);\n\x09\x09\x09\x09\x09\x09break;\n\n\x09\x09\x09\x09\x09case 'string':\n\x09\x09\x09\x09\x09\x09item.getAsString(function(s) {\n\x09\x09\x09\x09\x09\x09\x09var blob = new Blob([s]);\n\x09\x09\x09\x09\x09\x09\x09blob.name = 'Paste ' + new Date().toISOString() + '.txt';\n\x09\x09\x09\x09\x09\x09\x09items.push(blob);\n\x09\x09\x09\x09\x09\x09\x09pass(items);\n\x09\x09\x09\x09\x09\x09});\n\x09\x09\x09\x09\x09\x09break;\n\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09});\n\x09\x09\x09})(e.clipboardData.items[i]);\n\x09\x09}\n\n\x09\x09c.then(function(pass, fail, items) {\n\x09\x09\x09uploadFiles(items);\n\x09\x09}).pass([]);\n\x09}\n\n\x09function setURLList(urls) {\n\x09\x09var ul = urlList.querySelector('ul');\n\x09\x09ul.sacrificeChildren();\n\x09\x09for (var i = 0, url, li, a; url = urls[i]; i++) {\n\x09\x09\x09li = document.createElement('li');\n\x09\x09\x09a = document.createElement('a');\n\x09\x09\x09a.href = a.innerText = a.textContent = url;\n\x09\x09\x09li.appendChild(a);\n\x09\x09\x09ul.appendChild(li);\n\x09\x09}\n\x09\x09urlList.classList.add('active');\n\x09}\n\n\x09function dropZoneEnter(e) {\n\x09\x09var dt = e.dataTransfer;\n\x09\x09if (dt != null && Array.prototype.indexOf.call(dt.types, 'Files') >= 0) {\n\x09\x09\x09e.preventDefault();\n\x09\x09\x09e.stopPropagation();\n\x09\x09\x09dropZone.classList.add('active');\n\x09\x09}\n\x09}\n\n\x09function dropZoneLeave(e) {\n\x09\x09e.preventDefault();\n\x09\x09e.stopPropagation();\n\x09\x09dropZone.classList.remove('active');\n\x09}\n\n\x09function dropped(e) {\n\x09\x09e.stopPropagation();\n\x09\x09e.preventDefault();\n\x09\x09uploadFiles(e.dataTransfer.files);\n\x09}\n\n\x09function uploadFiles(fileList) {\n\x09\x09if (fileList == null || fileList.length == 0) {\n\x09\x09\x09finish();\n\x09\x09\x09return;\n\x09\x09}\n\n\x09\x09var totalSize = 0;\n\x09\x09var svg, err, x;\n\n\x09\x09for (var i = 0; i < fileList.length; i++) {\n\x09\x09\x09totalSize += fileList[i].size;\n\x09\x09}\n\n\x09\x09if (fileList.length > 1) {\n\x09\x09\x09svg = dropZone.querySelector('svg');\n\x09\x09\x09if (svg == null) {\n\x09\x09\x09\x09svg = makesvg('svg');\n\x09\x09\x09\x09dropZone.appendChild(svg);\n\x09\x09\x09}\n\x09\x09\x09svg.sacrificeChildren();\n\n\x09\x09\x09var i, acc, pos;\n\n\x09\x09\x09for (i = acc = 0; i < fileList.length; i++) {\n\x09\x09\x09\x09acc += fileList[i].size;\n\x09\x09\x09\x09pos = acc/totalSize * svg.offsetWidth;\n\x09\x09\x09\x09var line = makesvg('line');\n\x09\x09\x09\x09line.setAttribute('x1', pos);\n\x09\x09\x09\x09line.setAttribute('x2', pos);\n\x09\x09\x09\x09line.setAttribute('y1', 0);\n\x09\x09\x09\x09line.setAttribute('y2', dropZone.offsetHeight - 8);\n\x09\x09\x09\x09svg.appendChild(line);\n\x09\x09\x09}\n\x09\x09}\n\n\x09\x09bar.style.width = '0%';\n\x09\x09urlList.classList.remove('active');\n\x09\x09dropZone.classList.add('active');\n\n\x09\x09var cancel = function() {\n\x09\x09\x09if (x != null) {\n\x09\x09\x09\x09x.abort();\n\x09\x09\x09\x09dropZone.removeEventListener(cancel);\n\x09\x09\x09\x09finish();\n\x09\x09\x09}\n\x09\x09\x09if (svg != null) {\n\x09\x09\x09\x09svg.sacrificeChildren();\n\x09\x09\x09}\n\x09\x09};\n\x09\x09dropZone.removeEventListener('click', clickPicker);\n\x09\x09dropZone.addEventListener('click', cancel, false);\n\n\x09\x09dropZoneText.dataset.oldText = dropZoneText.innerText;\n\x09\x09dropZoneText.innerText = 'Cancel';\n\n\x09\x09var c = chain();\n\n\x09\x09for (var i = 0; i < fileList.length; i++) {\n\x09\x09\x09(function(file) {\n\x09\x09\x09\x09c.then(function(pass, fail, result, totalLoaded) {\n\x09\x09\x09\x09\x09x = tusUpload(file, function(loaded) {\n\x09\x09\x09\x09\x09\x09bar.style.width = ((totalLoaded + loaded)*100 / totalSize) + '%';\n\x09\x09\x09\x09\x09}, function(url) {\n\x09\x09\x09\x09\x09\x09totalLoaded += file.size;\n\x09\x09\x09\x09\x09\x09bar.style.width = totalLoaded*100 / totalSize + '%';\n\x09\x09\x09\x09\x09\x09result.push(window.location.protocol + '//' + url);\n\x09\x09\x09\x09\x09\x09pass(result, totalLoaded);\n\x09\x09\x09\x09\x09}, function(code, resp) {\n\x09\x09\x09\x09\x09\x09if (code == 403) {\n\x09\x09\x09\x09\x09\x09\x09redirectLogin();\n\x09\x09\x09\x09\x09\x09} else {\n\x09\x09\x09\x09\x09\x09\x09fail(resp);\n\x09\x09\x09\x09\x09\x09}\n\x09\x09\x09\x09\x09});\n\x09\x09\x09\x09});\n\x09\x09\x09})(fileList[i]);\n\x09\x09}\n\n\x09\x09c.then(function(pass, fail, result) {\n\x09\x09\x09finish();\n\x09\x09\x09setURLList(result);\n\x09\x09\x09dropZone.removeEventListener('click', cancel);\n\x09\x09\x09dropZone.addEventListener('click', clickPicker);\n\x09\x09\x09if (svg != null) {\n\x09\x09\x09\x09svg.sacrificeChildren();\n\x09\x09\x09}\n\x09\x09}).catch(errorMessage).pass([], 0);\n\x09}\n\n\x09function finish() {\n\x09\x09dropZone.classList.remove('active');\n\x09\x09dropZoneText.innerText = dropZoneText.dataset.oldText;\n\x09\x09bar.style.width = '0%';\n\x09\x09enable();\n\x09}\n\n\x09function enable() {\n\x09\x09dropZone.addEventListener('click', clickPicker, false);\n\x09\x09dropZoneText.addEventListener('dragenter', dropZoneEnter, false);\n\x09\x09dropZoneText.addEventListener('dragover', dropZoneEnter, false);\n\x09\x09dropZoneText.addEventListener('dragleave', dropZoneLeave, false);\n\x09\x09dropZoneText.addEventListener('drop', dropped, false);\n\x09}\n\n\x09function disable() {\n\x09\x09dropZoneText.removeEventListener('dragenter');\n\x09\x09dropZoneText.removeEventListener('dragover');\n\x09\x09dropZoneText.removeEventListener('dragleave');\n\x09\x09dropZoneText.removeEventListener('drop');\n\x09}\n\n\x09function clickPicker() {\n\x09\x09picker.click();\n\x09}\n\n\x09window.addEventListener('DOMContentLoaded', function() {\n\x09\x09dropZone     = $('#drop-zone');\n\x09\x09dropZoneText = $('#drop-zone-text');\n\x09\x09picker       = $('#picker');\n\x09\x09urlList      = $('#uploaded-urls');\n\x09\x09bar          = dropZone.querySelector('.progress-bar');\n\n\x09\x09picker.addEventListener('change', function(e) {\n\x09\x09\x09uploadFiles(this.files);\n\x09\x09}, false);\n\n\x09\x09window.addEventListener('paste', paste, false);\n\n\x09\x09enable();\n\x09}, false);\n})();\n"))
 }
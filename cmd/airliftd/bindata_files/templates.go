@@ -8,13 +8,17 @@ import (
 )
 
 func init() {
-	bindata.RegisterFile(filepath.Join("templates", "content", "config.tmpl"), time.Unix(1528666514, 0), []byte("{{ define \"title\" }} \xe2\x80\xa2 Configure{{ end }}\n\n{{ define \"content\" }}\n  {{ template \"%overview\" . }}\n  {{ template \"%config\" . }}\n  <script src=\"/-/static/common.js\"></script>\n  <script src=\"/-/static/config.js\"></script>\n{{ end }}\n\n{{ define \"%config\" }}\n{{ with $.Data.Data }}\n  <section id=\"section-config\" class=\"floating-section\">\n    <h1>Configuration</h1>\n    <form id=\"config\" autocomplete=\"off\">\n      <div class=\"box\" id=\"host-box\" data-tooltip=\"Returned file links will begin with this domain and path.\" data-tt-pos=\"top\">\n        <label for=\"host\">Base URL</label>\n        <input type=\"text\" id=\"host\" name=\"host\" value=\"{{ .Conf.Host }}\" placeholder=\"i.example.com\">\n      </div>\n      <div class=\"box\" id=\"id-box\">\n        /<span id=\"sample-id\"></span><span id=\"sample-ext\">.ext</span>\n      </div>\n      <div class=\"box\">\n        <label for=\"id-size\">Length of File ID</label>\n        <input type=\"range\" id=\"id-size\" name=\"id-size\" min=\"2\" max=\"12\" value=\"{{ .Conf.HashLen }}\">\n      </div>\n      <div class=\"box checkbox\" data-tooltip=\"Enable to append the original file extension to returned links.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" id=\"append-ext\" name=\"append-ext\"{{ if .Conf.AppendExt }} checked{{ end }}>\n        <label for=\"append-ext\">Append File Extensions</label>\n      </div>\n      <div class=\"box check-enable\">\n        <input type=\"checkbox\" class=\"hider\" id=\"enable-age-prune\" name=\"enable-age-prune\"{{ if .Conf.MaxAgeEnable }} checked{{ end }}>\n        <label for=\"enable-age-prune\">Limit Upload Age</label>\n        <div class=\"hidee\">\n          <label for=\"max-age\">Maximum Age (Days)</label>\n          <input type=\"number\" id=\"max-age\" name=\"max-age\" value=\"{{ .Conf.Age }}\" min=\"0\"{{ if not .Conf.MaxAgeEnable }} disabled{{ end }}>\n        </div>\n      </div>\n      <div class=\"box check-enable\">\n        <input type=\"checkbox\" class=\"hider\" id=\"enable-size-prune\" name=\"enable-size-prune\"{{ if .Conf.MaxSizeEnable }} checked{{ end }}>\n        <label for=\"enable-size-prune\">Limit Total Uploads Size</label>\n        <div class=\"hidee\">\n          <label for=\"max-size\">Maximum Size (MB)</label>\n          <input type=\"number\" id=\"max-size\" name=\"max-size\" value=\"{{ .Conf.Size }}\" min=\"0\"{{ if not .Conf.MaxSizeEnable }} disabled{{ end }}>\n        </div>\n      </div>\n      <div class=\"box check-enable\" data-tooltip=\"Enable to allow uploads to show Twitter Cards with file previews if applicable.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" class=\"hider\" id=\"twitter-card\" name=\"twitter-card\"{{ if .Conf.TwitterCardEnable }} checked{{ end }}>\n        <label for=\"twitter-card\">Enable Twitter Cards</label>\n        <div class=\"hidee\">\n          <label for=\"twitter-handle\">Twitter Handle</label>\n          <input type=\"text\" id=\"twitter-handle\" name=\"twitter-handle\" value=\"{{ .Conf.TwitterHandle }}\" required placeholder=\"@handle\"{{ if not .Conf.TwitterCardEnable }} disabled{{ end }}>\n        </div>\n      </div>\n      <div class=\"box check-enable\" data-tooltip=\"Enable to format code text files with syntax highlighting.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" class=\"hider\" id=\"syntax-enable\" name=\"syntax-enable\"{{ if .Conf.SyntaxEnable }} checked{{ end }}>\n        <label for=\"syntax-enable\">Syntax Highlighting</label>\n        <small>\n          <a href=\"https://xyproto.github.io/splash/docs/\" target=\"_blank\">View theme examples</a>\n        </small>\n        <div class=\"hidee\">\n          <label for=\"syntax-theme\">Syntax Theme</label>\n          <select id=\"syntax-theme\" name=\"syntax-theme\">\n            {{ range .SyntaxThemes }}\n              <option value=\"{{ . }}\" {{ if eq . $.Data.Data.Conf.SyntaxTheme }} selected {{ end }} >{{ . }}</option>\n            {{ end }}\n          </select>\n        </div>\n      </div>\n      <div class=\"box\" id=\"directory-box\">\n        <label for=\"directory\">Upload Directory</label>\n        <input type=\"text\" id=\"directory\" name=\"directory\" value=\"{{ .Conf.Directory }}\" placeholder=\"/home/user/uploads\">\n      </div>\n      <div class=\"box\" id=\"newpass-box\" data-tooltip=\"Enter a new password here to change your password.\" data-tt-pos=\"right\">\n        <label for=\"newpass\">New Password</label>\n        <input type=\"password\" id=\"newpass\" name=\"newpass\" placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      <div class=\"box\" id=\"newpass-confirm-box\" data-tooltip=\"Confirm new password\" data-tt-pos=\"left\">\n        <label for=\"newpass-confirm\">Confirm New Password</label>\n        <input type=\"password\" id=\"newpass-confirm\" name=\"newpass-confirm\" required placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      <button id=\"submit\" type=\"button\">Update configuration</button>\n    </form>\n  </section>\n{{ end }}\n{{ end }}\n\n{{ define \"%overview\" }}\n{{ with $.Data.Data }}\n  <section id=\"section-overview\" class=\"floating-section\">\n    <h1>Overview</h1>\n    <p><strong><a href=\"/-/history/0\">{{ .NumUploads }} upload{{ if ne .NumUploads 1 }}s{{ end }}</a></strong> totalling <strong>{{ .UploadsSize }}</strong>. (<a id=\"purge-all-link\" href=\"javascript:void(0)\">purge</a>)</p>\n    <p>Thumbnail cache is <strong>{{ .ThumbsSize }}</strong>. (<a id=\"purge-thumbs-link\" href=\"javascript:void(0)\">purge</a>)</p>\n  </section>\n{{ end }}\n{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "archive.tmpl"), time.Unix(1792200973, 0), []byte("{{ define \"title\" }}{{ $.Data.Data.Filename }}{{ end }}\n\n{{ define \"content\" }}{{ with $.Data.Data }}\n  <main class=\"rendered\">\n    <table class=\"sortable archive\">\n      <thead><tr><th title=\"Sort\">Name</th><th title=\"Sort\">Size</th><th title=\"Sort\">Modified</th></tr></thead>\n      <tbody>\n        {{ range .Entries }}\n        <tr>\n          {{ if .Dir }}\n          <td data-sort=\"{{ .Path }}\">{{ .Path }}/</td>\n          <td data-sort=\"0\"></td>\n          {{ else }}\n          <td data-sort=\"{{ .Path }}\"><a href=\"{{ .Link }}\">{{ if .Thumb }}<img class=\"thumb\" src=\"{{ .Link }}?thumb=1\" loading=\"lazy\" alt=\"\">{{ end }}{{ .Path }}</a></td>\n          <td data-sort=\"{{ printf \"%d\" .Size }}\">{{ .Size }}</td>\n          {{ end }}\n          <td data-sort=\"{{ .Modified.Unix }}\">{{ if not .Modified.IsZero }}{{ .Modified.Format \"2006-01-02 15:04\" }}{{ end }}</td>\n        </tr>\n        {{ end }}\n      </tbody>\n    </table>\n    {{ if .Truncated }}<p class=\"truncated\">Only the first {{ len .Entries }} entries are shown. <a href=\"?raw=1\">Download the whole archive.</a></p>{{ end }}\n  </main>\n  <script src=\"/-/static/render.js\"></script>\n{{ end }}{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "config.tmpl"), time.Unix(1792203579, 0), []byte("{{ define \"title\" }} \xe2\x80\xa2 Configure{{ end }}\n\n{{ define \"content\" }}\n  {{ template \"%overview\" . }}\n  {{ template \"%config\" . }}\n  {{ template \"%account\" . }}\n  {{ template \"%users\" . }}\n  <script src=\"/-/static/common.js\"></script>\n  <script src=\"/-/static/config.js\"></script>\n{{ end }}\n\n{{ define \"%config\" }}\n{{ with $.Data.Data }}{{ if .IsAdmin }}\n  <section id=\"section-config\" class=\"floating-section\">\n    <h1>Configuration</h1>\n    <form id=\"config\" autocomplete=\"off\">\n      <div class=\"box\" id=\"host-box\" data-tooltip=\"Returned file links will begin with this domain and path.\" data-tt-pos=\"top\">\n        <label for=\"host\">Base URL</label>\n        <input type=\"text\" id=\"host\" name=\"host\" value=\"{{ .Conf.Host }}\" placeholder=\"i.example.com\">\n      </div>\n      <div class=\"box\" id=\"id-box\">\n        /<span id=\"sample-id\"></span><span id=\"sample-ext\">.ext</span>\n      </div>\n      <div class=\"box\">\n        <label for=\"id-size\">Length of File ID</label>\n        <input type=\"range\" id=\"id-size\" name=\"id-size\" min=\"2\" max=\"12\" value=\"{{ .Conf.HashLen }}\">\n      </div>\n      <div class=\"box checkbox\" data-tooltip=\"Enable to append the original file extension to returned links.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" id=\"append-ext\" name=\"append-ext\"{{ if .Conf.AppendExt }} checked{{ end }}>\n        <label for=\"append-ext\">Append File Extensions</label>\n      </div>\n      <div class=\"box checkbox\" data-tooltip=\"Enable to remove EXIF, GPS, XMP and other metadata from uploaded JPEG, PNG and WebP images.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" id=\"strip-meta\" name=\"strip-meta\"{{ if .Conf.StripEnable }} checked{{ end }}>\n        <label for=\"strip-meta\">Strip Image Metadata</label>\n      </div>\n      <div class=\"box check-enable\">\n        <input type=\"checkbox\" class=\"hider\" id=\"enable-age-prune\" name=\"enable-age-prune\"{{ if .Conf.MaxAgeEnable }} checked{{ end }}>\n        <label for=\"enable-age-prune\">Limit Upload Age</label>\n        <div class=\"hidee\">\n          <label for=\"max-age\">Maximum Age (Days)</label>\n          <input type=\"number\" id=\"max-age\" name=\"max-age\" value=\"{{ .Conf.Age }}\" min=\"0\"{{ if not .Conf.MaxAgeEnable }} disabled{{ end }}>\n        </div>\n      </div>\n      <div class=\"box check-enable\">\n        <input type=\"checkbox\" class=\"hider\" id=\"enable-size-prune\" name=\"enable-size-prune\"{{ if .Conf.MaxSizeEnable }} checked{{ end }}>\n        <label for=\"enable-size-prune\">Limit Total Uploads Size</label>\n        <div class=\"hidee\">\n          <label for=\"max-size\">Maximum Size (MB)</label>\n          <input type=\"number\" id=\"max-size\" name=\"max-size\" value=\"{{ .Conf.Size }}\" min=\"0\"{{ if not .Conf.MaxSizeEnable }} disabled{{ end }}>\n        </div>\n      </div>\n      <div class=\"box check-enable\" data-tooltip=\"Enable to delete the thumbnails that were used longest ago when they take up more space than this. They're made again when they're needed.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" class=\"hider\" id=\"thumb-prune\" name=\"thumb-prune\"{{ if .Conf.ThumbSizeEnable }} checked{{ end }}>\n        <label for=\"thumb-prune\">Limit Thumbnail Cache Size</label>\n        <div class=\"hidee\">\n          <label for=\"thumb-size\">Maximum Size (MB)</label>\n          <input type=\"number\" id=\"thumb-size\" name=\"thumb-size\" value=\"{{ .Conf.ThumbSize }}\" min=\"1\"{{ if not .Conf.ThumbSizeEnable }} disabled{{ end }}>\n        </div>\n      </div>\n      <div class=\"box\" data-tooltip=\"How many thumbnails are made at the same time. The rest wait their turn, which keeps a page of large images from using up all of the memory.\" data-tt-pos=\"left\">\n        <label for=\"thumb-jobs\">Thumbnails Made at Once</label>\n        <input type=\"number\" id=\"thumb-jobs\" name=\"thumb-jobs\" value=\"{{ .Conf.ThumbWorkers }}\" min=\"1\">\n      </div>\n      <div class=\"box\" data-tooltip=\"Images with more pixels than this don't get thumbnails, and can't be resized. Each megapixel takes 4 MB of memory to decode.\" data-tt-pos=\"left\">\n        <label for=\"thumb-pixels\">Largest Image for Thumbnails (Megapixels)</label>\n        <input type=\"number\" id=\"thumb-pixels\" name=\"thumb-pixels\" value=\"{{ .Conf.ThumbPixels }}\" min=\"1\">\n      </div>\n      <div class=\"box check-enable\" data-tooltip=\"Enable to show previews of uploads when their links are posted in chats and on social media.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" class=\"hider\" id=\"link-preview\" name=\"link-preview\"{{ if .Conf.PreviewEnable }} checked{{ end }}>\n        <label for=\"link-preview\">Enable Link Previews</label>\n        <div class=\"hidee\">\n          <label for=\"twitter-handle\">Twitter Handle (Optional)</label>\n          <input type=\"text\" id=\"twitter-handle\" name=\"twitter-handle\" value=\"{{ .Conf.TwitterHandle }}\" placeholder=\"@handle\"{{ if not .Conf.PreviewEnable }} disabled{{ end }}>\n        </div>\n      </div>\n      <div class=\"box check-enable\" data-tooltip=\"Enable to format code text files with syntax highlighting.\" data-tt-pos=\"left\">\n        <input type=\"checkbox\" class=\"hider\" id=\"syntax-enable\" name=\"syntax-enable\"{{ if .Conf.SyntaxEnable }} checked{{ end }}>\n        <label for=\"syntax-enable\">Syntax Highlighting</label>\n        <small>\n          <a href=\"https://xyproto.github.io/splash/docs/\" target=\"_blank\">View theme examples</a>\n        </small>\n        <div class=\"hidee\">\n          <label for=\"syntax-theme\">Syntax Theme</label>\n          <select id=\"syntax-theme\" name=\"syntax-theme\">\n            {{ range .SyntaxThemes }}\n              <option value=\"{{ . }}\" {{ if eq . $.Data.Data.Conf.SyntaxTheme }} selected {{ end }} >{{ . }}</option>\n            {{ end }}\n          </select>\n        </div>\n      </div>\n      <div class=\"box\" data-tooltip=\"CSV and TSV files are shown as tables of up to this many rows.\" data-tt-pos=\"left\">\n        <label for=\"table-rows\">Rows Shown in Table Previews</label>\n        <input type=\"number\" id=\"table-rows\" name=\"table-rows\" value=\"{{ .Conf.TableRows }}\" min=\"1\">\n      </div>\n      <div class=\"box\" data-tooltip=\"Sizes that images can be resized to by adding ?w= and ?h= to their links, like 640x0 or 128x128. A side of 0 is left free.\" data-tt-pos=\"left\">\n        <label for=\"image-sizes\">Image Sizes</label>\n        <input type=\"text\" id=\"image-sizes\" name=\"image-sizes\" value=\"{{ .Conf.ImageSizes }}\" placeholder=\"640x0 128x128\">\n      </div>\n      <div class=\"box\" data-tooltip=\"Addresses or networks of the reverse proxies in front of the server, like 127.0.0.1 or 10.0.0.0/8. The client address in X-Forwarded-For is only believed from these.\" data-tt-pos=\"left\">\n        <label for=\"proxies\">Trusted Proxies</label>\n        <input type=\"text\" id=\"proxies\" name=\"proxies\" value=\"{{ .Conf.TrustedProxies }}\" placeholder=\"127.0.0.1 ::1\">\n      </div>\n      <div class=\"box\" id=\"directory-box\">\n        <label for=\"directory\">Upload Directory</label>\n        <input type=\"text\" id=\"directory\" name=\"directory\" value=\"{{ .Conf.Directory }}\" placeholder=\"/home/user/uploads\">\n      </div>\n      {{ if .Setup }}\n      <div class=\"box\" id=\"username-box\" data-tooltip=\"Name of the admin account.\" data-tt-pos=\"right\">\n        <label for=\"username\">Admin Username</label>\n        <input type=\"text\" id=\"username\" name=\"username\" placeholder=\"admin\">\n      </div>\n      <div class=\"box\" id=\"newpass-box\" data-tooltip=\"Password for the admin account.\" data-tt-pos=\"right\">\n        <label for=\"newpass\">New Password</label>\n        <input type=\"password\" id=\"newpass\" name=\"newpass\" placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      <div class=\"box\" id=\"newpass-confirm-box\" data-tooltip=\"Confirm new password\" data-tt-pos=\"left\">\n        <label for=\"newpass-confirm\">Confirm New Password</label>\n        <input type=\"password\" id=\"newpass-confirm\" name=\"newpass-confirm\" required placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      {{ end }}\n      <button id=\"submit\" type=\"button\">Update configuration</button>\n    </form>\n  </section>\n{{ end }}{{ end }}\n{{ end }}\n\n{{ define \"%account\" }}\n{{ with $.Data.Data.Account }}\n  <section id=\"section-account\" class=\"floating-section\">\n    <h1>Account</h1>\n    <p>Logged in as <strong>{{ .User.Name }}</strong>{{ if .User.Admin }} (admin){{ end }}. Your uploads take up <strong>{{ .Used }}</strong>{{ if gt .User.Quota 0 }} of your <strong>{{ .User.Quota }} MB</strong> quota{{ end }}.</p>\n    <form id=\"account\" autocomplete=\"off\">\n      <div class=\"box\">\n        <label for=\"pass\">Current Password</label>\n        <input type=\"password\" id=\"pass\" name=\"pass\" required placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      <div class=\"box\">\n        <label for=\"account-newpass\">New Password</label>\n        <input type=\"password\" id=\"account-newpass\" name=\"newpass\" required placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      <div class=\"box\">\n        <label for=\"account-newpass-confirm\">Confirm New Password</label>\n        <input type=\"password\" id=\"account-newpass-confirm\" name=\"newpass-confirm\" required placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      <button id=\"account-submit\" type=\"button\">Change password</button>\n    </form>\n    <h2>API Keys</h2>\n    <p>Keys can be sent in an <code>Authorization: Bearer</code> header in place of your password, and can only do what their scopes allow.</p>\n    <ul id=\"tokens\">\n      {{ range .User.Tokens }}\n      <li data-id=\"{{ .ID }}\"><strong>{{ .Name }}</strong> <code>{{ .ID }}\xe2\x80\xa6</code> \xe2\x80\x94 {{ range $i, $s := .Scopes }}{{ if $i }}, {{ end }}{{ $s }}{{ end }}; created {{ .Created.Format \"2006-01-02\" }}{{ if not .Expires.IsZero }}, {{ if .Expired }}expired{{ else }}expires{{ end }} {{ .Expires.Format \"2006-01-02\" }}{{ end }}, {{ if .LastUsed.IsZero }}never used{{ else }}last used {{ .LastUsed.Format \"2006-01-02 15:04\" }}{{ end }} (<a href=\"javascript:void(0)\" class=\"revoke-token\">revoke</a>)</li>\n      {{ else }}\n      <li>No API keys.</li>\n      {{ end }}\n    </ul>\n    <p id=\"new-token\"></p>\n    <form id=\"token\" autocomplete=\"off\">\n      <div class=\"box\">\n        <label for=\"token-name\">Name</label>\n        <input type=\"text\" id=\"token-name\" name=\"name\" required placeholder=\"CI uploads\">\n      </div>\n      {{ range .Scopes }}\n      <div class=\"box checkbox\">\n        <input type=\"checkbox\" id=\"token-scope-{{ . }}\" name=\"scope\" value=\"{{ . }}\"{{ if eq . \"upload\" }} checked{{ end }}>\n        <label for=\"token-scope-{{ . }}\">Can {{ . }}</label>\n      </div>\n      {{ end }}\n      <div class=\"box\">\n        <label for=\"token-expires\">Expires After (Days, 0 for never)</label>\n        <input type=\"number\" id=\"token-expires\" name=\"expires\" value=\"0\" min=\"0\">\n      </div>\n      <button id=\"token-submit\" type=\"button\">Create API key</button>\n    </form>\n    <h2>SSH Keys</h2>\n    <p>{{ if $.Data.Config.SSHPort }}Upload with <code>ssh -p {{ $.Data.Config.SSHPort }}</code> or <code>scp -P {{ $.Data.Config.SSHPort }}</code> using these public keys, one per line as in <code>authorized_keys</code>.{{ else }}The SSH server is not enabled.{{ end }}</p>\n    <form id=\"ssh-keys\" autocomplete=\"off\">\n      <div class=\"box\">\n        <label for=\"ssh-keys-text\">Public Keys</label>\n        <textarea id=\"ssh-keys-text\" name=\"keys\" rows=\"4\" placeholder=\"ssh-ed25519 AAAA... me@laptop\">{{ range .User.SSHKeys }}{{ . }}\n{{ end }}</textarea>\n      </div>\n      <button id=\"ssh-keys-submit\" type=\"button\">Save SSH keys</button>\n    </form>\n  </section>\n{{ end }}\n{{ end }}\n\n{{ define \"%users\" }}\n{{ with $.Data.Data.Account }}{{ if .IsAdmin }}\n  <section id=\"section-users\" class=\"floating-section\">\n    <h1>Users</h1>\n    <ul id=\"users\">\n      {{ range .Users }}\n      <li data-name=\"{{ .Name }}\"><strong>{{ .Name }}</strong>{{ if .Admin }} (admin){{ end }} \xe2\x80\x94 {{ index $.Data.Data.Account.UserSizes .Name }}{{ if gt .Quota 0 }} of {{ .Quota }} MB{{ end }} (<a href=\"javascript:void(0)\" class=\"delete-user\">delete</a>)</li>\n      {{ end }}\n    </ul>\n    <form id=\"user\" autocomplete=\"off\">\n      <div class=\"box\" data-tooltip=\"Enter the name of an existing user to change their settings.\" data-tt-pos=\"right\">\n        <label for=\"user-name\">Username</label>\n        <input type=\"text\" id=\"user-name\" name=\"name\" required>\n      </div>\n      <div class=\"box\" data-tooltip=\"Leave empty to keep an existing user's password.\" data-tt-pos=\"right\">\n        <label for=\"user-pass\">Password</label>\n        <input type=\"password\" id=\"user-pass\" name=\"pass\" placeholder=\"\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\xe2\x80\xa2\">\n      </div>\n      <div class=\"box\">\n        <label for=\"user-quota\">Quota (MB, 0 for none)</label>\n        <input type=\"number\" id=\"user-quota\" name=\"quota\" value=\"0\" min=\"0\">\n      </div>\n      <div class=\"box checkbox\">\n        <input type=\"checkbox\" id=\"user-admin\" name=\"admin\">\n        <label for=\"user-admin\">Admin</label>\n      </div>\n      <button id=\"user-submit\" type=\"button\">Save user</button>\n    </form>\n  </section>\n{{ end }}{{ end }}\n{{ end }}\n\n{{ define \"%overview\" }}\n{{ with $.Data.Data }}\n  <section id=\"section-overview\" class=\"floating-section\">\n    <h1>Overview</h1>\n    <p><strong><a href=\"/-/history/0\">{{ .NumUploads }} upload{{ if ne .NumUploads 1 }}s{{ end }}</a></strong> totalling <strong>{{ .UploadsSize }}</strong>.{{ if .IsAdmin }} (<a id=\"purge-all-link\" href=\"javascript:void(0)\">purge</a>){{ end }}</p>\n    {{ if .IsAdmin }}<p>Thumbnail cache is <strong>{{ .ThumbsSize }}</strong>{{ if .ThumbsMax }} of <strong>{{ .ThumbsMax }}</strong>{{ end }} in {{ .NumThumbs }} thumbnail{{ if ne .NumThumbs 1 }}s{{ end }}. (<a id=\"purge-thumbs-link\" href=\"javascript:void(0)\">purge</a>)</p>{{ end }}\n    {{ if and .IsAdmin (or .ThumbsBusy .ThumbsQueue) }}<p>Making <strong>{{ .ThumbsBusy }}</strong> thumbnail{{ if ne .ThumbsBusy 1 }}s{{ end }}, with <strong>{{ .ThumbsQueue }}</strong> waiting.</p>{{ end }}\n  </section>\n{{ end }}\n{{ end }}\n"))
 	bindata.RegisterFile(filepath.Join("templates", "content", "default-index.tmpl"), time.Unix(1527653725, 0), []byte("{{ define \"content\" }}\n    <section id=\"front\">\n      <div id=\"big-logo\">\n        <div id=\"big-logo-text\">{{ $.Data.Config.Host }} is powered by <a href=\"https://github.com/moshee/airlift\">Airlift</a>.</div>\n      </div>\n      <div class=\"login-link\"><a href=\"/-/login\">Log in</a></div>\n    </section>\n{{ end }}\n"))
-	bindata.RegisterFile(filepath.Join("templates", "content", "errors.tmpl"), time.Unix(1616369412, 0), []byte("{{ define \"400\" }}<!doctype html>\n<html>\n  <head>\n    <title>400</title>\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n  </head>\n  <body>\n    <div class=\"error\">\n      <h1>You're doing it wrong.</h1>\n      {{ with $.Data }}<p>{{ .Err }}</p>{{ end }}\n    </div>\n  </body>\n</html>\n{{ end }}\n{{ define \"404\" }}<!doctype html>\n<html>\n  <head>\n    <title>404</title>\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n  </head>\n  <body>\n    <div class=\"error\">\n      <h1>This isn't the page you're looking for.</h1>\n    </div>\n  </body>\n</html>\n{{ end }}\n{{ define \"500\" }}<!doctype html>\n<html>\n  <head>\n    <title>500</title>\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n  </head>\n  <body>\n    <div class=\"error\">\n      <h1>Something went wrong.</h1>\n      {{ with $.Data }}<p>{{ .Err }}</p>{{ end }}\n    </div>\n  </body>\n</html>\n{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "errors.tmpl"), time.Unix(1792199039, 0), []byte("{{ define \"400\" }}<!doctype html>\n<html>\n  <head>\n    <title>400</title>\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n  </head>\n  <body>\n    <div class=\"error\">\n      <h1>You're doing it wrong.</h1>\n      {{ with $.Data }}<p>{{ .Err }}</p>{{ end }}\n    </div>\n  </body>\n</html>\n{{ end }}\n{{ define \"404\" }}<!doctype html>\n<html>\n  <head>\n    <title>404</title>\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n  </head>\n  <body>\n    <div class=\"error\">\n      <h1>This isn't the page you're looking for.</h1>\n    </div>\n  </body>\n</html>\n{{ end }}\n{{ define \"410\" }}<!doctype html>\n<html>\n  <head>\n    <title>410</title>\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n  </head>\n  <body>\n    <div class=\"error\">\n      <h1>This upload has self-destructed.</h1>\n    </div>\n  </body>\n</html>\n{{ end }}\n{{ define \"500\" }}<!doctype html>\n<html>\n  <head>\n    <title>500</title>\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n  </head>\n  <body>\n    <div class=\"error\">\n      <h1>Something went wrong.</h1>\n      {{ with $.Data }}<p>{{ .Err }}</p>{{ end }}\n    </div>\n  </body>\n</html>\n{{ end }}\n"))
 	bindata.RegisterFile(filepath.Join("templates", "content", "history.tmpl"), time.Unix(1527698648, 0), []byte("{{ define \"title\" }} \xe2\x80\xa2 Uploads{{ end }}\n\n{{ define \"content\" }}\n{{ template \"%history\" . }}\n<script src=\"/-/static/common.js\"></script>\n<script src=\"/-/static/history.js\"></script>\n{{ end }}\n\n{{ define \"%history\" }}\n{{ with $.Data.Data }}\n<section id=\"history\">\n  {{ if len .List | lt 25 }}{{ template \"%pagination\" . }}{{ end }}\n  <ul>\n    {{ range .List }}\n    <li class=\"history-item\" data-id=\"{{ .ID }}\">\n      <a href=\"/{{ .ID }}{{ if $.Data.Data.AppendExt }}{{ .Ext }}{{ end }}\" class=\"upload-link\">{{ if .HasThumb }}<img src=\"/-/thumb/{{ .ID }}.jpg\">{{ else }}<img src=\"/-/static/file.svg\"><div class=\"file-ext-overlay\">{{ .Ext }}</div>{{ end }}</a>\n      <div class=\"history-item-name\" title=\"{{ .Name }}\">{{ .Name }}</div>\n      <div class=\"history-item-data\">{{ .Size }} / <span title=\"{{ .Uploaded.Format \"2006-01-02 15:04:05 MST\" }}\">{{ .Ago }}</span></div>\n      <div class=\"history-item-data\"><a href=\"javascript:\" class=\"delete-upload\">Delete</a></div>\n    </li>\n    {{ end }}\n  </ul>\n  {{ template \"%pagination\" . }}\n</section>\n{{ end }}\n{{ end }}\n\n{{ define \"%pagination\" }}\n<nav class=\"pagination\">\n  <span class=\"prevnext{{ if gt .CurrentPage 1 }} active{{ end }}\"><a href=\"/-/history/{{ .PrevPage }}\">Back</a> \xe2\x80\x94</span>\n  Page {{ .CurrentPage }} of {{ .TotalPages }}\n  <span class=\"prevnext{{ if ne .NextPage 0 }} active{{ end }}\">\xe2\x80\x94 <a href=\"/-/history/{{ .NextPage }}\">Next</a></span>\n</nav>\n{{ end }}\n"))
 	bindata.RegisterFile(filepath.Join("templates", "content", "index.tmpl"), time.Unix(1527653732, 0), []byte("{{ define \"content\" }}\n  <section id=\"upload\" class=\"floating-section\">\n    <input type=\"file\" id=\"picker\" name=\"picker[]\" multiple>\n    <div id=\"drop-zone\">\n      <div class=\"progress-bar\"></div>\n      <div id=\"drop-zone-text\">Click/tap/drop/paste</div>\n    </div>\n    <div id=\"uploaded-urls\">\n      <ul></ul>\n    </div>\n  </section>\n  <script src=\"/-/static/common.js\"></script>\n  <script src=\"/-/static/uploader.js\"></script>\n{{ end }}\n"))
-	bindata.RegisterFile(filepath.Join("templates", "content", "login.tmpl"), time.Unix(1527653743, 0), []byte("{{ define \"title\" }} \xe2\x80\xa2 Log In{{ end }}\n\n{{ define \"content\" }}\n    <section id=\"section-login\" class=\"floating-section\">\n      <form method=\"post\" action=\"/-/login\" id=\"login\">\n        {{ if $.Data }}<p id=\"message-box\" class=\"bad active\">Incorrect password.</p>{{ end }}\n        <label for=\"password\">Password: </label><input name=\"pass\" id=\"password\" type=\"password\" placeholder=\"password\" autofocus required>\n        <hr>\n        <button type=\"submit\" id=\"submit\">Log in</button>\n      </form>\n    </section>\n{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "login.tmpl"), time.Unix(1792199363, 0), []byte("{{ define \"title\" }} \xe2\x80\xa2 Log In{{ end }}\n\n{{ define \"content\" }}\n    <section id=\"section-login\" class=\"floating-section\">\n      <form method=\"post\" action=\"/-/login\" id=\"login\">\n        {{ if $.Data }}<p id=\"message-box\" class=\"bad active\">Incorrect username or password.</p>{{ end }}\n        <label for=\"username\">Username: </label><input name=\"user\" id=\"username\" type=\"text\" placeholder=\"username\" autofocus required>\n        <label for=\"password\">Password: </label><input name=\"pass\" id=\"password\" type=\"password\" placeholder=\"password\" required>\n        <hr>\n        <button type=\"submit\" id=\"submit\">Log in</button>\n      </form>\n    </section>\n{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "paste.tmpl"), time.Unix(1792200618, 0), []byte("{{ define \"title\" }} \xe2\x80\xa2 Paste{{ end }}\n\n{{ define \"content\" }}\n  <section id=\"section-paste\" class=\"floating-section\">\n    <form id=\"paste\" autocomplete=\"off\">\n      <div class=\"box\">\n        <label for=\"paste-text\">Text</label>\n        <textarea id=\"paste-text\" name=\"text\" rows=\"20\" required spellcheck=\"false\"></textarea>\n      </div>\n      <div class=\"box\">\n        <label for=\"paste-name\">File Name</label>\n        <input type=\"text\" id=\"paste-name\" name=\"name\" placeholder=\"paste.txt\">\n      </div>\n      <div class=\"box\">\n        <label for=\"paste-lang\">Language</label>\n        <select id=\"paste-lang\" name=\"lang\">\n          <option value=\"\">Detect automatically</option>\n          {{ range $.Data.Data.Languages }}\n          <option value=\"{{ . }}\">{{ . }}</option>\n          {{ end }}\n        </select>\n      </div>\n      <div class=\"box\">\n        <label for=\"paste-expires\">Expires</label>\n        <select id=\"paste-expires\" name=\"expires\">\n          <option value=\"\">Never</option>\n          <option value=\"1h\">After an hour</option>\n          <option value=\"24h\">After a day</option>\n          <option value=\"168h\">After a week</option>\n          <option value=\"720h\">After 30 days</option>\n        </select>\n      </div>\n      <button id=\"paste-submit\" type=\"button\">Paste</button>\n    </form>\n  </section>\n  <script src=\"/-/static/common.js\"></script>\n  <script src=\"/-/static/paste.js\"></script>\n{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "render.tmpl"), time.Unix(1792200739, 0), []byte("{{ define \"title\" }}{{ $.Data.Data.Filename }}{{ end }}\n\n{{ define \"content\" }}\n  <main class=\"rendered\">{{ $.Data.Data.HTML }}</main>\n  <script src=\"/-/static/render.js\"></script>\n{{ end }}\n"))
 	bindata.RegisterFile(filepath.Join("templates", "content", "syntax.tmpl"), time.Unix(1528666514, 0), []byte("{{ define \"title\" }}{{ $.Data.Data.Filename }}{{ end }}\n\n{{ define \"content\" }}\n  <main>{{ $.Data.Data.HTML }}</main>\n{{ end }}\n"))
-	bindata.RegisterFile(filepath.Join("templates", "content", "twitterbot.tmpl"), time.Unix(1527653775, 0), []byte("{{ define \"content\" }}<!doctype html>\n<html>\n  <head>\n    <title>{{ .ID }}</title>\n    <meta name=\"twitter:card\" content=\"summary_large_image\">\n    <meta name=\"twitter:site\" content=\"{{ .Handle }}\">\n    <meta name=\"twitter:title\" content=\"{{ .ID }}: {{ .Name }}\">\n    <meta name=\"twitter:description\" content=\"{{ .Size }} / uploaded {{ .Uploaded.Format \"2 Jan 2006 15:04\" }}\">\n    <meta name=\"twitter:image\" content=\"http://{{ .Host }}/-/twitterthumb/{{ .ID }}.jpg\">\n  </head>\n  <body>\n    hi twitterbot\n  </body>\n</html>\n{{ end }}\n"))
-	bindata.RegisterFile(filepath.Join("templates", "layout", "layout.tmpl"), time.Unix(1528666514, 0), []byte("{{ define \"head\" }}\n    <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n    <link rel=\"shortcut icon\" href=\"/-/static/favicon.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"76x76\" href=\"/-/static/airlift_76x76.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"120x120\" href=\"/-/static/airlift_120x120.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"152x152\" href=\"/-/static/airlift_152x152.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"180x180\" href=\"/-/static/airlift_180x180.png\">\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n{{ end }}\n\n{{ define \"layout-full\" }}\n<html>\n  <head>\n    <title>Airlift{{ block \"title\" . }}{{ end }}</title>\n    {{ template \"head\" }}\n  </head>\n  <body>\n    <div id=\"message-box\"></div>\n    <nav id=\"nav\">\n      <a href=\"/\">Upload</a> /\n      <a href=\"/-/history/1\">History</a> /\n      <a href=\"/-/config\">Configure</a> /\n      <a href=\"/-/logout\">Log out</a>\n    </nav>\n    {{ block \"content\" $ }}{{ end  }}\n    <div id=\"version\">airliftd {{ $.Data.Version }}</div>\n  </body>\n</html>\n{{ end }}\n\n{{ define \"layout-lite\" }}\n<html>\n  <head>\n    <title>Airlift{{ block \"title\" . }}{{ end }}</title>\n    {{ template \"head\" }}\n  </head>\n  <body>\n    {{ block \"content\" $ }}{{ end  }}\n  </body>\n</html>\n{{ end }}\n\n{{ define \"layout-syntax\" }}\n<html>\n<head>\n  <title>{{ block \"title\" . }}{{ end }}</title>\n  <link rel=\"stylesheet\" href=\"/-/static/syntax.css\">\n  <link rel=\"stylesheet\" href=\"/-/theme/{{ .Data.Data.SyntaxTheme }}.css\">\n</head>\n<body class=\"syntax chroma\">\n  <a href=\"?raw=1\" class=\"raw\">{{ $.Data.Data.Filename }}<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"24\" height=\"24\" viewBox=\"0 0 24 24\" fill=\"none\" stroke=\"currentColor\" stroke-width=\"2\" stroke-linecap=\"round\" stroke-linejoin=\"round\"><path d=\"M21 15v4a2 2 0 0 1-2 2H5a2 2 0 0 1-2-2v-4\"></path><polyline points=\"7 10 12 15 17 10\"></polyline><line x1=\"12\" y1=\"15\" x2=\"12\" y2=\"3\"></line></svg></a>\n  {{ block \"content\" . }}{{ end }}\n</body>\n{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "unfurl.tmpl"), time.Unix(1792200446, 0), []byte("{{ define \"%head\" }}{{ with $.Data.Data }}\n    <meta charset=\"utf-8\">\n    <title>{{ .Name }}</title>\n    <link rel=\"alternate\" type=\"application/json+oembed\" href=\"{{ .OEmbed }}\" title=\"{{ .Name }}\">\n    <meta property=\"og:site_name\" content=\"{{ .Site }}\">\n    <meta property=\"og:url\" content=\"{{ .URL }}\">\n    <meta property=\"og:title\" content=\"{{ .Name }}\">\n    <meta property=\"og:description\" content=\"{{ if .Snippet }}{{ .Snippet }}{{ else }}{{ .Size }} / uploaded {{ .Uploaded.Format \"2 Jan 2006 15:04\" }}{{ end }}\">\n    <meta name=\"twitter:title\" content=\"{{ .Name }}\">\n    <meta name=\"twitter:description\" content=\"{{ if .Snippet }}{{ .Snippet }}{{ else }}{{ .Size }} / uploaded {{ .Uploaded.Format \"2 Jan 2006 15:04\" }}{{ end }}\">\n    {{ with .Handle }}<meta name=\"twitter:site\" content=\"{{ . }}\">{{ end }}\n{{ end }}{{ end }}\n\n{{ define \"%body\" }}{{ with $.Data.Data }}\n  <body>\n    <a href=\"{{ .URL }}\">{{ .Name }}</a>\n  </body>\n{{ end }}{{ end }}\n\n{{ define \"image\" }}<!doctype html>\n<html>\n  <head>{{ template \"%head\" $ }}{{ with $.Data.Data }}\n    <meta property=\"og:type\" content=\"website\">\n    {{ if .Image }}\n    <meta property=\"og:image\" content=\"{{ .Image }}\">\n    <meta property=\"og:image:alt\" content=\"{{ .Name }}\">\n    <meta name=\"twitter:card\" content=\"summary_large_image\">\n    <meta name=\"twitter:image\" content=\"{{ .Image }}\">\n    {{ else }}\n    <meta name=\"twitter:card\" content=\"summary\">\n    {{ end }}\n  {{ end }}</head>\n  {{ template \"%body\" $ }}\n</html>\n{{ end }}\n\n{{ define \"video\" }}<!doctype html>\n<html>\n  <head>{{ template \"%head\" $ }}{{ with $.Data.Data }}\n    <meta name=\"twitter:card\" content=\"summary\">\n    {{ if .Raw }}\n    <meta property=\"og:type\" content=\"video.other\">\n    <meta property=\"og:video\" content=\"{{ .Raw }}\">\n    {{ if .Secure }}<meta property=\"og:video:secure_url\" content=\"{{ .Raw }}\">{{ end }}\n    <meta property=\"og:video:type\" content=\"{{ .ContentType }}\">\n    {{ else }}\n    <meta property=\"og:type\" content=\"website\">\n    {{ end }}\n  {{ end }}</head>\n  {{ template \"%body\" $ }}\n</html>\n{{ end }}\n\n{{ define \"audio\" }}<!doctype html>\n<html>\n  <head>{{ template \"%head\" $ }}{{ with $.Data.Data }}\n    <meta property=\"og:type\" content=\"website\">\n    <meta name=\"twitter:card\" content=\"summary\">\n    {{ if .Raw }}\n    <meta property=\"og:audio\" content=\"{{ .Raw }}\">\n    {{ if .Secure }}<meta property=\"og:audio:secure_url\" content=\"{{ .Raw }}\">{{ end }}\n    <meta property=\"og:audio:type\" content=\"{{ .ContentType }}\">\n    {{ end }}\n  {{ end }}</head>\n  {{ template \"%body\" $ }}\n</html>\n{{ end }}\n\n{{ define \"text\" }}<!doctype html>\n<html>\n  <head>{{ template \"%head\" $ }}\n    <meta property=\"og:type\" content=\"article\">\n    <meta name=\"twitter:card\" content=\"summary\">\n  </head>\n  {{ template \"%body\" $ }}\n</html>\n{{ end }}\n\n{{ define \"file\" }}<!doctype html>\n<html>\n  <head>{{ template \"%head\" $ }}\n    <meta property=\"og:type\" content=\"website\">\n    <meta name=\"twitter:card\" content=\"summary\">\n  </head>\n  {{ template \"%body\" $ }}\n</html>\n{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "content", "viewer.tmpl"), time.Unix(1792201064, 0), []byte("{{ define \"title\" }} \xe2\x80\xa2 {{ $.Data.Data.Name }}{{ end }}\n\n{{ define \"content\" }}{{ with $.Data.Data }}\n    <section id=\"viewer\" class=\"viewer-{{ .Kind }}\">\n      <div id=\"viewer-media\">\n        {{ if eq .Kind \"image\" }}\n        <a href=\"?raw=1\"><img src=\"?raw=1\" alt=\"{{ .Name }}\"></a>\n        {{ else if eq .Kind \"video\" }}\n        <video src=\"?raw=1\" controls preload=\"metadata\"></video>\n        {{ else }}\n        <audio src=\"?raw=1\" controls preload=\"metadata\"></audio>\n        {{ end }}\n      </div>\n      <div id=\"viewer-info\">\n        <h1 title=\"{{ .Name }}\">{{ .Name }}</h1>\n        <p>{{ .Size }} / {{ .ContentType }} / uploaded <span title=\"{{ .Uploaded.Format \"2006-01-02 15:04:05 MST\" }}\">{{ .Uploaded.Format \"2 Jan 2006\" }}</span></p>\n        <p>\n          <a href=\"?download=1\" download=\"{{ .Name }}\"><button type=\"button\">Download</button></a>\n          <a href=\"?raw=1\">Raw file</a>\n        </p>\n      </div>\n    </section>\n{{ end }}{{ end }}\n"))
+	bindata.RegisterFile(filepath.Join("templates", "layout", "layout.tmpl"), time.Unix(1792200618, 0), []byte("{{ define \"head\" }}\n    <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n    <link rel=\"shortcut icon\" href=\"/-/static/favicon.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"76x76\" href=\"/-/static/airlift_76x76.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"120x120\" href=\"/-/static/airlift_120x120.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"152x152\" href=\"/-/static/airlift_152x152.png\">\n    <link rel=\"apple-touch-icon\" sizes=\"180x180\" href=\"/-/static/airlift_180x180.png\">\n    <link rel=\"stylesheet\" href=\"/-/static/style.css\">\n{{ end }}\n\n{{ define \"layout-full\" }}\n<html>\n  <head>\n    <title>Airlift{{ block \"title\" . }}{{ end }}</title>\n    {{ template \"head\" }}\n  </head>\n  <body>\n    <div id=\"message-box\"></div>\n    <nav id=\"nav\">\n      <a href=\"/\">Upload</a> /\n      <a href=\"/-/paste\">Paste</a> /\n      <a href=\"/-/history/1\">History</a> /\n      <a href=\"/-/config\">Configure</a> /\n      <a href=\"/-/logout\">Log out</a>\n    </nav>\n    {{ block \"content\" $ }}{{ end  }}\n    <div id=\"version\">airliftd {{ $.Data.Version }}</div>\n  </body>\n</html>\n{{ end }}\n\n{{ define \"layout-lite\" }}\n<html>\n  <head>\n    <title>Airlift{{ block \"title\" . }}{{ end }}</title>\n    {{ template \"head\" }}\n  </head>\n  <body>\n    {{ block \"content\" $ }}{{ end  }}\n  </body>\n</html>\n{{ end }}\n\n{{ define \"layout-syntax\" }}\n<html>\n<head>\n  <title>{{ block \"title\" . }}{{ end }}</title>\n  <link rel=\"stylesheet\" href=\"/-/static/syntax.css\">\n  <link rel=\"stylesheet\" href=\"/-/theme/{{ .Data.Data.SyntaxTheme }}.css\">\n</head>\n<body class=\"syntax chroma\">\n  <a href=\"?raw=1\" class=\"raw\" title=\"{{ $.Data.Data.Language }}\">{{ $.Data.Data.Filename }}<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"24\" height=\"24\" viewBox=\"0 0 24 24\" fill=\"none\" stroke=\"currentColor\" stroke-width=\"2\" stroke-linecap=\"round\" stroke-linejoin=\"round\"><path d=\"M21 15v4a2 2 0 0 1-2 2H5a2 2 0 0 1-2-2v-4\"></path><polyline points=\"7 10 12 15 17 10\"></polyline><line x1=\"12\" y1=\"15\" x2=\"12\" y2=\"3\"></line></svg></a>\n  {{ block \"content\" . }}{{ end }}\n  <script src=\"/-/static/syntax.js\"></script>\n</body>\n{{ end }}\n"))
 }
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/out"
+)
+
+// The WebDAV interface shows a user's uploads (or everyone's, to admins) as a
+// single flat collection of files named "<id> - <original name>". Files can be
+// added, read and deleted, but not changed in place, moved or organized.
+
+const davRoot = "/-/dav/"
+
+// davMethods are the methods routed to serveDAV.
+var davMethods = []string{
+	"OPTIONS", "PROPFIND", "PROPPATCH", "GET", "HEAD", "PUT", "DELETE",
+	"MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK",
+}
+
+// davJunk are names that file managers write next to everything they copy,
+// which are accepted and thrown away rather than becoming uploads.
+var davJunk = []string{".DS_Store", "Thumbs.db", "desktop.ini"}
+
+func isDAVJunk(name string) bool {
+	if strings.HasPrefix(name, "._") {
+		return true
+	}
+	for _, j := range davJunk {
+		if name == j {
+			return true
+		}
+	}
+	return false
+}
+
+// davAliasAge is how long the plain name a file was PUT under keeps referring
+// to the upload it created. Clients often look a file up or write it again
+// right after creating it, under the name they gave it.
+const davAliasAge = 10 * time.Minute
+
+type davAlias struct {
+	id      string
+	created time.Time
+}
+
+var (
+	davAliasMu sync.Mutex
+	davAliases = make(map[string]davAlias) // map[owner "/" name]alias
+)
+
+func setDAVAlias(owner, name, id string) {
+	davAliasMu.Lock()
+	defer davAliasMu.Unlock()
+	now := time.Now()
+	for k, a := range davAliases {
+		if now.Sub(a.created) > davAliasAge {
+			delete(davAliases, k)
+		}
+	}
+	if id == "" {
+		delete(davAliases, owner+"/"+name)
+	} else {
+		davAliases[owner+"/"+name] = davAlias{id, now}
+	}
+}
+
+func getDAVAlias(owner, name string) string {
+	davAliasMu.Lock()
+	defer davAliasMu.Unlock()
+	a, ok := davAliases[owner+"/"+name]
+	if !ok || time.Since(a.created) > davAliasAge {
+		return ""
+	}
+	return a.id
+}
+
+// davName returns the name an upload is listed under.
+func davName(m *cache.Meta) string {
+	return m.ID + " - " + m.Name
+}
+
+// davLookup returns the upload that name refers to, if the requester may see
+// it.
+func davLookup(g *gas.Gas, name string) *cache.Meta {
+	var m *cache.Meta
+	if i := strings.Index(name, " - "); i > 0 {
+		if mm := fileCache.Stat(name[:i]); mm != nil && davName(mm) == name {
+			m = mm
+		}
+	}
+	if m == nil {
+		if id := getDAVAlias(ownerName(g), name); id != "" {
+			m = fileCache.Stat(id)
+		}
+	}
+	if m == nil || !canManage(g, m) {
+		return nil
+	}
+	return m
+}
+
+// checkDAV is checkUploader for WebDAV clients, which need to be asked for
+// Basic credentials.
+func checkDAV(g *gas.Gas) (int, gas.Outputter) {
+	if sess, u, ok := isLoggedIn(g); ok {
+		if sess != nil {
+			sessions.Update(sess.Id)
+			setRequestUser(g, u, nil)
+		}
+		return g.Continue()
+	}
+	u, t, err := headerUser(g, config.Get())
+	if err != nil {
+		g.Header().Set("WWW-Authenticate", `Basic realm="airlift"`)
+		return 401, out.Error(g, err)
+	}
+	setRequestUser(g, u, t)
+	return g.Continue()
+}
+
+func serveDAV(g *gas.Gas) (int, gas.Outputter) {
+	name := strings.TrimPrefix(strings.TrimPrefix(g.URL.Path, strings.TrimSuffix(davRoot, "/")), "/")
+	if strings.Contains(name, "/") {
+		return 404, nil
+	}
+
+	scope := config.ScopeList
+	switch g.Request.Method {
+	case "PUT", "LOCK", "UNLOCK", "PROPPATCH":
+		scope = config.ScopeUpload
+	case "DELETE":
+		scope = config.ScopeDelete
+	}
+	if t := requestToken(g); t != nil && !t.Allows(scope) {
+		return 403, out.Error(g, fmt.Errorf("API key lacks the %s scope", scope))
+	}
+
+	switch g.Request.Method {
+	case "OPTIONS":
+		g.Header().Set("DAV", "1, 2")
+		g.Header().Set("MS-Author-Via", "DAV")
+		g.Header().Set("Allow", strings.Join(davMethods, ", "))
+		return 200, nil
+	case "PROPFIND":
+		return davPropfind(g, name)
+	case "PROPPATCH":
+		// times and other properties of uploads can't be changed, but
+		// clients that set them after writing a file expect it to work
+		return davMultistatus(g, []davResponse{{Href: davRoot + url.PathEscape(name), Status: "HTTP/1.1 200 OK"}})
+	case "GET", "HEAD":
+		if name == "" {
+			return 302, out.Redirect("/-/history")
+		}
+		m := davLookup(g, name)
+		if m == nil {
+			return 404, nil
+		}
+		// reading a file here counts as a download, the same as through its
+		// link
+		if g.Request.Method == "GET" {
+			hit, last, err := fileCache.Hit(m.ID)
+			switch err {
+			case nil:
+			case cache.ErrGone:
+				return 410, out.Error(g, err)
+			default:
+				return 404, out.Error(g, err)
+			}
+			if last {
+				defer func() {
+					if err := fileCache.Expire(hit.ID); err != nil {
+						log.Println(g.Request.Method, "serveDAV:", err)
+					}
+				}()
+			}
+			m = hit
+		}
+		f, err := fileCache.Open(m.ID)
+		if err != nil {
+			log.Println(g.Request.Method, "serveDAV:", err)
+			return 500, out.Error(g, err)
+		}
+		defer f.Close()
+		g.Header().Set("ETag", `"`+m.Digest+`"`)
+		http.ServeContent(g, g.Request, m.Name, m.Uploaded, f)
+		return g.Stop()
+	case "PUT":
+		return davPut(g, name)
+	case "DELETE":
+		if name == "" {
+			return 403, nil
+		}
+		m := davLookup(g, name)
+		if m == nil {
+			return 404, nil
+		}
+		if err := fileCache.Remove(m.ID); err != nil {
+			log.Println(g.Request.Method, "serveDAV:", err)
+			return 500, out.Error(g, err)
+		}
+		setDAVAlias(ownerName(g), name, "")
+		return 204, nil
+	case "LOCK":
+		return davLock(g, name)
+	case "UNLOCK":
+		return 204, nil
+	default:
+		// MKCOL, COPY and MOVE
+		return 403, nil
+	}
+}
+
+func davPut(g *gas.Gas, name string) (int, gas.Outputter) {
+	defer g.Body.Close()
+	if name == "" {
+		return 405, nil
+	}
+	if isDAVJunk(name) {
+		io.Copy(ioutil.Discard, g.Body)
+		return 201, nil
+	}
+
+	// uploads can't change, so writing to an existing one replaces it
+	old := davLookup(g, name)
+	info := &cache.Meta{
+		Name:   path.Base(name),
+		Addr:   remoteAddr(g),
+		Client: g.Request.UserAgent(),
+		Owner:  ownerName(g),
+	}
+	if old != nil {
+		info.Name = old.Name
+	}
+	token, err := newDeleteToken(info)
+	if err != nil {
+		log.Println(g.Request.Method, "davPut:", err)
+		return 500, out.Error(g, err)
+	}
+
+	var body io.Reader = g.Body
+	if left := quotaLeft(g); left >= 0 {
+		if g.Request.ContentLength > left {
+			return 507, out.Error(g, errOverQuota)
+		}
+		body = &quotaReader{g.Body, left}
+	}
+
+	conf := config.Get()
+	id, err := fileCache.Put(body, info, conf)
+	if err == errOverQuota {
+		return 507, out.Error(g, err)
+	}
+	if errors.Is(err, cache.ErrNotStripped) {
+		return 422, out.Error(g, err)
+	}
+	if err != nil {
+		log.Println(g.Request.Method, "davPut:", err)
+		return 500, out.Error(g, err)
+	}
+	if old != nil {
+		if err := fileCache.Remove(old.ID); err != nil {
+			log.Println(g.Request.Method, "davPut:", err)
+		}
+	}
+	setDAVAlias(info.Owner, name, id)
+
+	host := linkHost(g, conf)
+	g.Header().Set("X-Airlift-URL", uploadURL(host, conf, id, info.Name))
+	g.Header().Set("X-Airlift-Delete-URL", deleteURL(host, id, token))
+	setStrippedHeader(g, id)
+	g.Header().Set("Location", davRoot+url.PathEscape(id+" - "+info.Name))
+	return 201, nil
+}
+
+type davProp struct {
+	DisplayName   string   `xml:"D:displayname"`
+	ResourceType  *davType `xml:"D:resourcetype"`
+	ContentLength int64    `xml:"D:getcontentlength"`
+	ContentType   string   `xml:"D:getcontenttype,omitempty"`
+	LastModified  string   `xml:"D:getlastmodified"`
+	CreationDate  string   `xml:"D:creationdate"`
+	ETag          string   `xml:"D:getetag,omitempty"`
+}
+
+type davType struct {
+	Collection *struct{} `xml:"D:collection"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"D:prop"`
+	Status string  `xml:"D:status"`
+}
+
+type davResponse struct {
+	Href     string        `xml:"D:href"`
+	Propstat []davPropstat `xml:"D:propstat,omitempty"`
+	Status   string        `xml:"D:status,omitempty"`
+}
+
+func davMultistatus(g *gas.Gas, responses []davResponse) (int, gas.Outputter) {
+	b, err := xml.Marshal(struct {
+		XMLName   xml.Name      `xml:"D:multistatus"`
+		NS        string        `xml:"xmlns:D,attr"`
+		Responses []davResponse `xml:"D:response"`
+	}{NS: "DAV:", Responses: responses})
+	if err != nil {
+		return 500, out.Error(g, err)
+	}
+	g.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	g.WriteHeader(207)
+	io.WriteString(g, xml.Header)
+	g.Write(b)
+	return g.Stop()
+}
+
+func davFile(m *cache.Meta) davResponse {
+	return davResponse{
+		Href: davRoot + url.PathEscape(davName(m)),
+		Propstat: []davPropstat{{
+			Prop: davProp{
+				DisplayName:   davName(m),
+				ResourceType:  &davType{},
+				ContentLength: m.Size,
+				ContentType:   m.ContentType,
+				LastModified:  m.Uploaded.UTC().Format(http.TimeFormat),
+				CreationDate:  m.Uploaded.UTC().Format(time.RFC3339),
+				ETag:          `"` + m.Digest + `"`,
+			},
+			Status: "HTTP/1.1 200 OK",
+		}},
+	}
+}
+
+func davPropfind(g *gas.Gas, name string) (int, gas.Outputter) {
+	// the properties asked for don't matter; everything is always returned
+	io.Copy(ioutil.Discard, g.Body)
+
+	if name != "" {
+		m := davLookup(g, name)
+		if m == nil {
+			return 404, nil
+		}
+		resp := davFile(m)
+		// answer to the name that was asked about
+		resp.Href = davRoot + url.PathEscape(name)
+		return davMultistatus(g, []davResponse{resp})
+	}
+
+	ids := visibleIDs(g)
+	responses := []davResponse{{
+		Href: davRoot,
+		Propstat: []davPropstat{{
+			Prop: davProp{
+				DisplayName:  "airlift",
+				ResourceType: &davType{Collection: &struct{}{}},
+				LastModified: time.Now().UTC().Format(http.TimeFormat),
+				CreationDate: time.Now().UTC().Format(time.RFC3339),
+			},
+			Status: "HTTP/1.1 200 OK",
+		}},
+	}}
+	if g.Request.Header.Get("Depth") != "0" {
+		for _, id := range ids {
+			if m := fileCache.Stat(id); m != nil {
+				responses = append(responses, davFile(m))
+			}
+		}
+	}
+	return davMultistatus(g, responses)
+}
+
+// davLock pretends to lock a file. Uploads can't be changed in place anyway,
+// but some clients won't write files without locking them first.
+func davLock(g *gas.Gas, name string) (int, gas.Outputter) {
+	io.Copy(ioutil.Discard, g.Body)
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return 500, out.Error(g, err)
+	}
+	token := "opaquelocktoken:" + hex.EncodeToString(buf)
+
+	status := 200
+	if name != "" && davLookup(g, name) == nil {
+		// locking a name that doesn't exist yet reserves it
+		status = 201
+	}
+
+	g.Header().Set("Lock-Token", "<"+token+">")
+	g.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	g.WriteHeader(status)
+	fmt.Fprintf(g, `%s<D:prop xmlns:D="DAV:"><D:lockdiscovery><D:activelock>`+
+		`<D:locktype><D:write/></D:locktype><D:lockscope><D:exclusive/></D:lockscope>`+
+		`<D:depth>0</D:depth><D:timeout>Second-3600</D:timeout>`+
+		`<D:locktoken><D:href>%s</D:href></D:locktoken>`+
+		`<D:lockroot><D:href>%s</D:href></D:lockroot>`+
+		`</D:activelock></D:lockdiscovery></D:prop>`,
+		xml.Header, token, davRoot+url.PathEscape(name))
+	return g.Stop()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/out"
+)
+
+func getPaste(g *gas.Gas) (int, gas.Outputter) {
+	data := &struct {
+		Languages []string
+	}{
+		lexers.Names(false),
+	}
+	return 200, out.HTML("paste/layout-full", &context{data})
+}
+
+// postPaste stores text sent in a form as a new upload, optionally marked with
+// the language to highlight it as.
+func postPaste(g *gas.Gas) (int, gas.Outputter) {
+	conf := config.Get()
+
+	var form struct {
+		Text    string `form:"text"`
+		Lang    string `form:"lang"`
+		Name    string `form:"name"`
+		Expires string `form:"expires"`
+	}
+	if err := g.UnmarshalForm(&form); err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	if form.Text == "" {
+		return 400, out.JSON(&Resp{Err: "missing text"})
+	}
+
+	info := &cache.Meta{
+		Name:   form.Name,
+		Addr:   remoteAddr(g),
+		Client: g.Request.UserAgent(),
+		Owner:  ownerName(g),
+	}
+	if err := uploadLimits(g, info); err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	if form.Expires != "" {
+		t, err := parseExpiry(form.Expires)
+		if err != nil {
+			return 400, out.JSON(&Resp{Err: err.Error()})
+		}
+		info.Expires = t
+	}
+
+	var lexer chroma.Lexer
+	if form.Lang != "" {
+		if lexer = lexers.Get(form.Lang); lexer == nil {
+			return 400, out.JSON(&Resp{Err: fmt.Sprintf("unknown language %q", form.Lang)})
+		}
+		info.Lang = lexer.Config().Name
+	}
+	if info.Name == "" {
+		info.Name = "paste" + pasteExt(lexer)
+	}
+
+	token, err := newDeleteToken(info)
+	if err != nil {
+		log.Println(g.Request.Method, "postPaste:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+	if left := quotaLeft(g); left >= 0 && int64(len(form.Text)) > left {
+		return 413, out.JSON(&Resp{Err: errOverQuota.Error()})
+	}
+
+	hash, err := fileCache.Put(strings.NewReader(form.Text), info, conf)
+	if err != nil {
+		log.Println(g.Request.Method, "postPaste:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+
+	return 201, out.JSON(&Resp{
+		URL:         uploadURL(linkHost(g, conf), conf, hash, info.Name),
+		DeleteToken: token,
+		DeleteURL:   deleteURL(linkHost(g, conf), hash, token),
+	})
+}
+
+// pasteExt returns the file extension that a paste in the language of lexer
+// is named with.
+func pasteExt(lexer chroma.Lexer) string {
+	if lexer != nil {
+		for _, glob := range lexer.Config().Filenames {
+			if ext := filepath.Ext(glob); strings.HasPrefix(glob, "*.") && !strings.ContainsAny(ext, "*?[") {
+				return ext
+			}
+		}
+	}
+	return ".txt"
+}
+
+// highlightLines formats contents as HTML with numbered lines. Each line is a
+// span with an ID like "L10" so that it can be linked to, starting with a link
+// to itself.
+func highlightLines(lexer chroma.Lexer, style *chroma.Style, contents string) (template.HTML, error) {
+	iterator, err := lexer.Tokenise(nil, contents)
+	if err != nil {
+		return "", err
+	}
+	formatter := html.New(html.WithClasses(true), html.PreventSurroundingPre(true))
+	lines := chroma.SplitTokensIntoLines(iterator.Tokens())
+	digits := len(strconv.Itoa(len(lines)))
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(`<pre class="chroma">`)
+	for i, tokens := range lines {
+		n := i + 1
+		fmt.Fprintf(buf, `<span class="line" id="L%d"><a class="ln" href="#L%d">%*d</a>`, n, n, digits, n)
+		if err := formatter.Format(buf, style, chroma.Literator(tokens...)); err != nil {
+			return "", err
+		}
+		buf.WriteString("</span>")
+	}
+	buf.WriteString("</pre>")
+	return template.HTML(buf.String()), nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/russross/blackfriday/v2"
+
+	"ktkr.us/pkg/airlift/config"
+)
+
+// A renderer turns the contents of a text upload into a document to show in
+// place of its highlighted source. Code in the document is highlighted with
+// style.
+type renderer func(conf *config.Config, style *chroma.Style, contents []byte) (template.HTML, error)
+
+var (
+	renderersByExt = map[string]renderer{
+		".md":       renderMarkdown,
+		".markdown": renderMarkdown,
+		".csv":      renderCSV,
+		".tsv":      renderTSV,
+		".tab":      renderTSV,
+		".ipynb":    renderNotebook,
+	}
+	renderersByType = map[string]renderer{
+		"text/markdown":             renderMarkdown,
+		"text/x-markdown":           renderMarkdown,
+		"text/csv":                  renderCSV,
+		"text/tab-separated-values": renderTSV,
+	}
+)
+
+// findRenderer returns the renderer for a file with the given name and
+// content type, or nil if it should be highlighted as source.
+func findRenderer(name, contentType string) renderer {
+	if r := renderersByExt[strings.ToLower(filepath.Ext(name))]; r != nil {
+		return r
+	}
+	t := contentType
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	return renderersByType[strings.TrimSpace(t)]
+}
+
+// renderedCSP keeps anything that got past the renderers from running
+// scripts or loading content from elsewhere, images aside.
+const renderedCSP = "default-src 'none'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src * data:"
+
+// highlightCode formats a snippet of code in the named language, if it is
+// known, or else as plain preformatted text.
+func highlightCode(style *chroma.Style, lang, code string) string {
+	lexer := lexers.Get(lang)
+	if lang == "" || lexer == nil {
+		return `<pre class="chroma">` + html.EscapeString(code) + "</pre>"
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return `<pre class="chroma">` + html.EscapeString(code) + "</pre>"
+	}
+	buf := new(bytes.Buffer)
+	if err := chromahtml.New(chromahtml.WithClasses(true)).Format(buf, style, iterator); err != nil {
+		return `<pre class="chroma">` + html.EscapeString(code) + "</pre>"
+	}
+	return buf.String()
+}
+
+// markdownRenderer is blackfriday's HTML renderer with raw HTML and unsafe
+// links left out, and code blocks highlighted.
+type markdownRenderer struct {
+	*blackfriday.HTMLRenderer
+	style *chroma.Style
+}
+
+var safeImage = regexp.MustCompile(`^(?i)(https?://|/|\./|\.\./|data:image/(png|jpeg|gif|webp);)`)
+
+func (r *markdownRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	switch node.Type {
+	case blackfriday.CodeBlock:
+		lang := string(node.CodeBlockData.Info)
+		if i := strings.IndexAny(lang, "\t {"); i >= 0 {
+			lang = lang[:i]
+		}
+		io.WriteString(w, highlightCode(r.style, lang, string(node.Literal)))
+		return blackfriday.GoToNext
+	case blackfriday.Image:
+		// the alt text is all that's left of images from elsewhere
+		if !safeImage.Match(node.LinkData.Destination) {
+			return blackfriday.GoToNext
+		}
+	}
+	return r.HTMLRenderer.RenderNode(w, node, entering)
+}
+
+func markdownHTML(style *chroma.Style, contents []byte) []byte {
+	r := &markdownRenderer{
+		HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+			Flags: blackfriday.CommonHTMLFlags | blackfriday.SkipHTML | blackfriday.Safelink |
+				blackfriday.NofollowLinks | blackfriday.NoreferrerLinks | blackfriday.HrefTargetBlank,
+		}),
+		style: style,
+	}
+	return blackfriday.Run(contents,
+		blackfriday.WithRenderer(r),
+		blackfriday.WithExtensions(blackfriday.CommonExtensions|blackfriday.AutoHeadingIDs))
+}
+
+func renderMarkdown(conf *config.Config, style *chroma.Style, contents []byte) (template.HTML, error) {
+	return template.HTML(`<article class="markdown">` + string(markdownHTML(style, contents)) + "</article>"), nil
+}
+
+func renderCSV(conf *config.Config, style *chroma.Style, contents []byte) (template.HTML, error) {
+	return renderTable(conf, contents, ',')
+}
+
+func renderTSV(conf *config.Config, style *chroma.Style, contents []byte) (template.HTML, error) {
+	return renderTable(conf, contents, '\t')
+}
+
+// renderTable shows delimited data as a table whose first row is the heading,
+// cut off after the configured number of rows.
+func renderTable(conf *config.Config, contents []byte, comma rune) (template.HTML, error) {
+	r := csv.NewReader(bytes.NewReader(contents))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.ReuseRecord = true
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(`<table class="sortable">`)
+	rows := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if rows == 0 {
+			buf.WriteString("<thead><tr>")
+			for _, field := range record {
+				buf.WriteString(`<th title="Sort">` + html.EscapeString(field) + "</th>")
+			}
+			buf.WriteString("</tr></thead><tbody>")
+			rows++
+			continue
+		}
+		if rows > conf.TableRows {
+			fmt.Fprintf(buf, `</tbody></table><p class="truncated">Only the first %d rows are shown. <a href="?raw=1">Download the whole file.</a></p>`, conf.TableRows)
+			return template.HTML(buf.String()), nil
+		}
+		buf.WriteString("<tr>")
+		for _, field := range record {
+			buf.WriteString("<td>" + html.EscapeString(field) + "</td>")
+		}
+		buf.WriteString("</tr>")
+		rows++
+	}
+	if rows == 0 {
+		return "", errors.New("empty table")
+	}
+	buf.WriteString("</tbody></table>")
+	return template.HTML(buf.String()), nil
+}
+
+// nbText is a multiline string in a notebook, which may be stored as a list
+// of lines. Anything else, like the JSON outputs of some cells, is left empty.
+type nbText string
+
+func (t *nbText) UnmarshalJSON(b []byte) error {
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err == nil {
+		*t = nbText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = nbText(s)
+	}
+	return nil
+}
+
+// notebook is the part of the Jupyter notebook format (version 4) that is
+// shown.
+type notebook struct {
+	Format   int `json:"nbformat"`
+	Metadata struct {
+		Kernel struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		Language struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	Cells []struct {
+		Type    string     `json:"cell_type"`
+		Source  nbText     `json:"source"`
+		Count   *int       `json:"execution_count"`
+		Outputs []nbOutput `json:"outputs"`
+	} `json:"cells"`
+}
+
+// nbOutput is something that running a notebook cell printed or returned.
+type nbOutput struct {
+	Type      string            `json:"output_type"`
+	Text      nbText            `json:"text"`
+	Data      map[string]nbText `json:"data"`
+	Name      string            `json:"ename"`
+	Value     string            `json:"evalue"`
+	Traceback []string          `json:"traceback"`
+}
+
+// ansiEscape matches the terminal color codes in tracebacks.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+// nbImages are the image types that notebook outputs are shown as, in order
+// of preference. They are only ever loaded by <img>, so SVG can't run scripts.
+var nbImages = []string{"image/png", "image/jpeg", "image/gif", "image/svg+xml"}
+
+func renderNotebook(conf *config.Config, style *chroma.Style, contents []byte) (template.HTML, error) {
+	var nb notebook
+	if err := json.Unmarshal(contents, &nb); err != nil {
+		return "", err
+	}
+	if nb.Format < 4 {
+		return "", fmt.Errorf("unsupported notebook format %d", nb.Format)
+	}
+	lang := nb.Metadata.Language.Name
+	if lang == "" {
+		lang = nb.Metadata.Kernel.Language
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(`<article class="notebook">`)
+	for _, cell := range nb.Cells {
+		switch cell.Type {
+		case "markdown":
+			buf.WriteString(`<div class="cell markdown">`)
+			buf.Write(markdownHTML(style, []byte(cell.Source)))
+			buf.WriteString("</div>")
+		case "code":
+			prompt := " "
+			if cell.Count != nil {
+				prompt = fmt.Sprint(*cell.Count)
+			}
+			fmt.Fprintf(buf, `<div class="cell code"><div class="prompt">In [%s]:</div>`, prompt)
+			buf.WriteString(highlightCode(style, lang, string(cell.Source)))
+			for _, o := range cell.Outputs {
+				buf.WriteString(`<div class="output">`)
+				o.write(buf, style)
+				buf.WriteString("</div>")
+			}
+			buf.WriteString("</div>")
+		default:
+			buf.WriteString(`<div class="cell raw"><pre>` + html.EscapeString(string(cell.Source)) + "</pre></div>")
+		}
+	}
+	buf.WriteString("</article>")
+	return template.HTML(buf.String()), nil
+}
+
+func (o *nbOutput) write(buf *bytes.Buffer, style *chroma.Style) {
+	switch o.Type {
+	case "stream":
+		buf.WriteString("<pre>" + html.EscapeString(string(o.Text)) + "</pre>")
+		return
+	case "error":
+		tb := strings.Join(o.Traceback, "\n")
+		if tb == "" {
+			tb = o.Name + ": " + o.Value
+		}
+		buf.WriteString(`<pre class="error">` + html.EscapeString(ansiEscape.ReplaceAllString(tb, "")) + "</pre>")
+		return
+	}
+
+	// execute_result and display_data hold the same thing in several types.
+	// HTML outputs can't be shown safely, so they fall back to another one.
+	for _, t := range nbImages {
+		s, ok := o.Data[t]
+		if !ok {
+			continue
+		}
+		b64 := strings.Join(strings.Fields(string(s)), "")
+		if t == "image/svg+xml" {
+			b64 = base64.StdEncoding.EncodeToString([]byte(s))
+		}
+		buf.WriteString(`<img src="data:` + t + ";base64," + html.EscapeString(b64) + `">`)
+		return
+	}
+	if s, ok := o.Data["text/markdown"]; ok {
+		buf.Write(markdownHTML(style, []byte(s)))
+		return
+	}
+	for _, t := range []string{"text/plain", "text/latex"} {
+		if s, ok := o.Data[t]; ok {
+			buf.WriteString("<pre>" + html.EscapeString(string(s)) + "</pre>")
+			return
+		}
+	}
+}
@@ -65,20 +65,18 @@ const (
 	thumbWidth       = 100
 	thumbHeight      = 100
 
-	// https://dev.twitter.com/cards/types/summary-large-image
-	// "Images for this Card should be at least 280px in width, and at least
-	// 150px in height. Image must be less than 1MB in size."
-	twitterThumbWidth  = 700
-	twitterThumbHeight = 375
-
 	appDirName = ".airliftd"
 )
 
 // Resp represents a server response, containing either the generated resource
-// URL or an error.
+// URL or an error. New uploads also come with a token and URL for deleting
+// them, and the metadata that was stripped from them.
 type Resp struct {
-	URL string `json:",omitempty"`
-	Err string `json:",omitempty"`
+	URL         string   `json:",omitempty"`
+	DeleteToken string   `json:",omitempty"`
+	DeleteURL   string   `json:",omitempty"`
+	Stripped    []string `json:",omitempty"` // kinds of metadata removed from an image
+	Err         string   `json:",omitempty"`
 }
 
 func main() {
@@ -114,12 +112,24 @@ func main() {
 		HashLen:     4,
 		Directory:   filepath.Join(appDir, "uploads"),
 		SyntaxTheme: "trac",
+		TableRows:   1000,
+		ImageSizes:  "128x128 256x256 320x0 640x0 1280x0",
+
+		// a frontend on the same machine, as in the sample nginx config
+		TrustedProxies: "127.0.0.1 ::1",
+
+		ThumbSizeEnable: true,
+		ThumbSize:       512,
+		ThumbWorkers:    runtime.NumCPU(),
+		ThumbPixels:     50,
 	}
 	if err := config.Init(filepath.Join(appDir, "config")); err != nil {
 		log.Fatal(err)
 	}
 	config.OnSave = func(c *config.Config) {
-		fileCache.SetDir(c.Directory)
+		if err := fileCache.SetDir(c.Directory); err != nil {
+			log.Print(err)
+		}
 	}
 
 	gas.Hook(syscall.SIGHUP, func() {
@@ -127,6 +137,7 @@ func main() {
 		if err := config.Reload(); err != nil {
 			log.Print(err)
 		} else {
+			setThumbLimits(config.Get())
 			log.Print("reloaded config")
 		}
 	})
@@ -156,13 +167,17 @@ func main() {
 	auth.UseSessionStore(sessions)
 
 	conf := config.Get()
-	fileCache, err = cache.New(conf.Directory)
+	store, err := newStore(conf.Storage)
+	if err != nil {
+		log.Fatalln("storage:", err)
+	}
+	fileCache, err = cache.New(conf.Directory, store)
 	if err != nil {
 		log.Fatalln("file list:", err)
 	}
 	thumbDir := filepath.Join(appDir, "thumb-cache")
 	thumbEnc := thumb.JPEGEncoder{Options: &jpeg.Options{Quality: 88}}
-	thumbCache, err = thumb.NewCache(thumbDir, thumbEnc, fileCache, draw.BiLinear)
+	thumbCache, err = thumb.NewCache(thumbDir, thumbEnc, thumbStore{}, draw.BiLinear)
 	if err != nil {
 		log.Fatalln("thumb cache:", err)
 	}
@@ -174,7 +189,17 @@ func main() {
 	}
 
 	go fileCache.WatchAges(conf)
+	go watchPartials()
+	thumbCache.AddEncoder(thumb.PNGEncoder{Paletted: true})
+	thumbCache.AddEncoder(thumb.GIFEncoder{})
+	registerThumbGenerators()
 	go thumbCache.Serve()
+	setThumbLimits(conf)
+	if conf.SSHPort > 0 {
+		go func() {
+			log.Print("ssh: ", serveSSH(":"+strconv.Itoa(conf.SSHPort)))
+		}()
+	}
 
 	r := gas.New()
 
@@ -196,27 +221,55 @@ func main() {
 		}(code)
 	}
 
+	for _, method := range davMethods {
+		r.Add(method, "/-/dav", checkDAV, serveDAV).
+			Add(method, "/-/dav/", checkDAV, serveDAV).
+			Add(method, "/-/dav/{name}", checkDAV, serveDAV)
+	}
+
+	entryRoute := "/{id}/-/entry"
+	for i := 0; i < maxEntryDepth; i++ {
+		entryRoute += "/{p" + strconv.Itoa(i) + "}"
+		r.Get(entryRoute, getArchiveEntry)
+	}
+
 	r.StaticHandler("/-/static", vfs.Subdir(fs, "static")).
 		Get("/-/login", getLogin).
 		Get("/-/logout", getLogout).
 		Post("/-/login", postLogin).
 		Get("/-/config", checkLogin, getConfig).
-		Post("/-/config", checkLogin, postConfig).
-		Post("/-/config/size", checkLogin, getSizeLimitPrune).
-		Post("/-/config/age", checkLogin, getAgeLimitPrune).
+		Post("/-/config", checkLogin, checkAdmin, postConfig).
+		Post("/-/config/size", checkLogin, checkAdmin, getSizeLimitPrune).
+		Post("/-/config/age", checkLogin, checkAdmin, getAgeLimitPrune).
 		Get("/-/config/overview", checkLogin, getConfigOverview).
+		Post("/-/account", checkLogin, postAccount).
+		Post("/-/account/ssh-keys", checkLogin, postSSHKeys).
+		Post("/-/tokens", checkLogin, postToken).
+		Post("/-/tokens/{id}/revoke", checkLogin, revokeToken).
+		Post("/-/users", checkLogin, checkAdmin, postUser).
+		Post("/-/users/{name}/delete", checkLogin, checkAdmin, deleteUser).
 		Get("/-/theme/{name}.css", getThemeCSS).
+		Get("/-/paste", checkLogin, getPaste).
+		Post("/paste/web", checkLogin, postPaste).
+		Post("/paste", checkPassword, needScope(config.ScopeUpload), postPaste).
 		Post("/upload/web", checkLogin, postFile).
-		Post("/upload/file", checkPassword, postFile).
-		Post("/oops", checkPassword, oops).
-		Get("/-/l", checkPassword, getList).
+		Post("/upload/file", checkPassword, needScope(config.ScopeUpload), postFile).
+		Add("OPTIONS", "/upload/tus", checkTus, tusOptions).
+		Post("/upload/tus", checkTus, checkUploader, needScope(config.ScopeUpload), tusCreate).
+		Add("HEAD", "/upload/tus/{uid}", checkTus, checkUploader, needScope(config.ScopeUpload), tusHead).
+		Add("PATCH", "/upload/tus/{uid}", checkTus, checkUploader, needScope(config.ScopeUpload), tusPatch).
+		Delete("/upload/tus/{uid}", checkTus, checkUploader, needScope(config.ScopeUpload), tusDelete).
+		Post("/oops", checkPassword, needScope(config.ScopeDelete), oops).
+		Get("/-/l", checkPassword, needScope(config.ScopeList), getList).
 		Get("/-/history", checkLogin, getHistory).
 		Get("/-/history/{page}", checkLogin, getHistoryPage).
-		Post("/purge/thumbs", checkLogin, purgeThumbs).
-		Post("/purge/all", checkLogin, purgeAll).
+		Post("/purge/thumbs", checkLogin, checkAdmin, purgeThumbs).
+		Post("/purge/all", checkLogin, checkAdmin, purgeAll).
 		Get("/-/thumb/{id}.jpg", checkLogin, getThumb).
-		Get("/-/twitterthumb/{id}.jpg", getTwitterThumb).
-		Delete("/{id}", checkPassword, deleteFile).
+		Get("/-/preview/{id}.jpg", getPreviewThumb).
+		Get("/-/twitterthumb/{id}.jpg", getPreviewThumb).
+		Get("/-/oembed", getOEmbed).
+		Delete("/{id}", checkDeleter, needScope(config.ScopeDelete), deleteFile).
 		Post("/-/delete/{id}", checkLogin, deleteFile).
 		Get("/{id}/{filename}", getFile).
 		Get("/{id}.{ext}", getFile).
@@ -225,69 +278,73 @@ func main() {
 		Ignition()
 }
 
+type overview struct {
+	NumUploads  int
+	UploadsSize fmtutil.Bytes
+	ThumbsSize  fmtutil.Bytes
+	ThumbsMax   fmtutil.Bytes // zero if there is no limit
+	NumThumbs   int
+	ThumbsBusy  int // being made
+	ThumbsQueue int // waiting to be made
+	IsAdmin     bool
+}
+
+// getOverview summarizes the uploads visible to the requesting user.
+func getOverview(g *gas.Gas) *overview {
+	u := requestUser(g)
+	if u == nil || u.Admin {
+		return &overview{
+			fileCache.Len(),
+			fmtutil.Bytes(fileCache.Size()),
+			fmtutil.Bytes(thumbCache.Size()),
+			fmtutil.Bytes(thumbCache.MaxSize()),
+			thumbCache.Len(),
+			thumbCache.Working(),
+			thumbCache.Queued(),
+			true,
+		}
+	}
+	return &overview{
+		len(fileCache.SortedIDsOwnedBy(u.Name)),
+		fmtutil.Bytes(fileCache.SizeOwnedBy(u.Name)),
+		0,
+		0,
+		0,
+		0,
+		0,
+		false,
+	}
+}
+
 func getConfig(g *gas.Gas) (int, gas.Outputter) {
+	conf := config.Get()
 	data := &struct {
+		*overview
 		Conf         *config.Config
-		NumUploads   int
-		UploadsSize  fmtutil.Bytes
-		ThumbsSize   fmtutil.Bytes
+		Setup        bool
+		Account      *accountData
 		SyntaxThemes []string
 	}{
-		config.Get(),
-		fileCache.Len(),
-		fmtutil.Bytes(fileCache.Size()),
-		fmtutil.Bytes(thumbCache.Size()),
+		getOverview(g),
+		conf,
+		conf.NeedsSetup(),
+		getAccount(g),
 		styles.Names(),
 	}
 	return 200, out.HTML("config/layout-full", &context{data})
 }
 
 func getConfigOverview(g *gas.Gas) (int, gas.Outputter) {
-	data := &struct {
-		NumUploads  int
-		UploadsSize fmtutil.Bytes
-		ThumbsSize  fmtutil.Bytes
-	}{
-		fileCache.Len(),
-		fmtutil.Bytes(fileCache.Size()),
-		fmtutil.Bytes(thumbCache.Size()),
-	}
-
-	return 200, out.HTML("config/%overview", &context{data})
+	return 200, out.HTML("config/%overview", &context{getOverview(g)})
 }
 
 func postConfig(g *gas.Gas) (int, gas.Outputter) {
-	conf := config.Get()
 	newconf := config.Config{}
 
 	if err := g.UnmarshalForm(&newconf); err != nil {
 		return 400, out.JSON(&Resp{Err: err.Error()})
 	}
 
-	newpass := g.FormValue("newpass")
-	newpassConfirm := g.FormValue("newpass-confirm")
-	if newpass != newpassConfirm {
-		return 400, out.JSON(&Resp{Err: "passwords do not match"})
-	}
-	if conf.Password != nil {
-		if newpass != "" {
-			conf.SetPass(newpass)
-		}
-	} else {
-		if newpass == "" {
-			return 400, out.JSON(&Resp{Err: "cannot set empty password"})
-		}
-
-		conf.SetPass(newpass)
-		if err := auth.SignIn(g, conf, newpass); err != nil {
-			return 400, out.JSON(&Resp{Err: err.Error()})
-		}
-	}
-
-	newconf.Password = conf.Password
-	newconf.Salt = conf.Salt
-	newconf.Port = conf.Port
-
 	if newconf.HashLen < 1 {
 		newconf.HashLen = 1
 	} else if newconf.HashLen > 64 {
@@ -296,10 +353,32 @@ func postConfig(g *gas.Gas) (int, gas.Outputter) {
 
 	newconf.Directory = filepath.Clean(newconf.Directory)
 
-	if newconf.TwitterCardEnable && newconf.TwitterHandle == "" {
-		return 400, out.JSON(&Resp{Err: "you must provide a Twitter handle to use Twitter Cards"})
+	if newconf.TableRows < 1 {
+		newconf.TableRows = config.Default.TableRows
+	}
+
+	if newconf.ThumbSize < 1 {
+		newconf.ThumbSize = config.Default.ThumbSize
+	}
+	if newconf.ThumbWorkers < 1 {
+		newconf.ThumbWorkers = config.Default.ThumbWorkers
+	}
+	if newconf.ThumbPixels < 1 {
+		newconf.ThumbPixels = config.Default.ThumbPixels
 	}
 
+	sizes, err := parseImageSizes(newconf.ImageSizes)
+	if err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	newconf.ImageSizes = sizes
+
+	proxies, err := parseProxies(newconf.TrustedProxies)
+	if err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	newconf.TrustedProxies = proxies
+
 	if newconf.TwitterHandle != "" {
 		newconf.TwitterHandle = strings.TrimSpace(newconf.TwitterHandle)
 		if !strings.HasPrefix(newconf.TwitterHandle, "@") {
@@ -307,12 +386,55 @@ func postConfig(g *gas.Gas) (int, gas.Outputter) {
 		}
 	}
 
-	if err := config.Set(&newconf); err != nil {
+	var (
+		badForm error
+		newUser *config.User
+		newpass = g.FormValue("newpass")
+	)
+	err = config.Update(func(conf *config.Config) error {
+		if conf.NeedsSetup() {
+			// the first account is created along with the initial configuration
+			switch {
+			case newpass != g.FormValue("newpass-confirm"):
+				badForm = errors.New("passwords do not match")
+			case newpass == "":
+				badForm = errors.New("cannot set empty password")
+			}
+			if badForm != nil {
+				return badForm
+			}
+			name := g.FormValue("username")
+			if name == "" {
+				name = config.DefaultUsername
+			}
+			if newUser, badForm = newconf.AddUser(name, newpass, true); badForm != nil {
+				return badForm
+			}
+		} else {
+			newconf.Users = conf.Users
+		}
+
+		newconf.Port = conf.Port
+		newconf.SSHPort = conf.SSHPort
+		newconf.Storage = conf.Storage
+		*conf = newconf
+		return nil
+	})
+	if badForm != nil {
+		return 400, out.JSON(&Resp{Err: badForm.Error()})
+	} else if err != nil {
 		log.Println(g.Request.Method, "postConfig:", err)
 		return 500, out.JSON(&Resp{Err: err.Error()})
 	}
+	if newUser != nil {
+		if err := auth.SignIn(g, newUser, newpass); err != nil {
+			return 400, out.JSON(&Resp{Err: err.Error()})
+		}
+	}
 
-	conf = config.Get()
+	conf := config.Get()
+	fileCache.Reschedule()
+	setThumbLimits(conf)
 
 	if conf.MaxSizeEnable {
 		_, err := fileCache.CutToSize(conf.Size * 1024 * 1024)
@@ -338,7 +460,7 @@ func getLogin(g *gas.Gas) (int, gas.Outputter) {
 	}
 
 	conf := config.Get()
-	if conf.Password == nil {
+	if conf.NeedsSetup() {
 		return reroute(g)
 	}
 
@@ -353,7 +475,11 @@ func getLogin(g *gas.Gas) (int, gas.Outputter) {
 func postLogin(g *gas.Gas) (int, gas.Outputter) {
 	conf := config.Get()
 
-	if err := auth.SignIn(g, conf, g.FormValue("pass")); err != nil {
+	u := conf.User(g.FormValue("user"))
+	if u == nil {
+		return 200, out.HTML("login/layout-lite", true)
+	}
+	if err := auth.SignIn(g, u, g.FormValue("pass")); err != nil {
 		return 200, out.HTML("login/layout-lite", true)
 	}
 
@@ -384,14 +510,23 @@ func getThemeCSS(g *gas.Gas) (int, gas.Outputter) {
 
 func getFile(g *gas.Gas) (int, gas.Outputter) {
 	id := g.Arg("id")
-	file := fileCache.Get(id)
-	if file == "" {
-		return 404, out.Error(g, errors.New("ID not found"))
+	meta := fileCache.Stat(id)
+	if meta == nil {
+		if fileCache.Gone(id) {
+			return 410, out.Error(g, cache.ErrGone)
+		}
+		return 404, out.Error(g, cache.ErrNotFound)
 	}
 
 	form := struct {
-		Raw       bool `form:"raw"`
-		Formatted bool `form:"fmt"`
+		Raw       bool   `form:"raw"`
+		Formatted bool   `form:"fmt"`
+		Download  bool   `form:"download"`
+		Lang      string `form:"lang"`
+		W         int    `form:"w"`
+		H         int    `form:"h"`
+		Fit       string `form:"fit"`
+		Format    string `form:"format"`
 	}{}
 
 	if err := g.UnmarshalForm(&form); err != nil {
@@ -399,137 +534,203 @@ func getFile(g *gas.Gas) (int, gas.Outputter) {
 	}
 
 	conf := config.Get()
-	if conf.TwitterCardEnable && thumb.FormatSupported(filepath.Ext(file)) {
-		uas := g.UserAgents()
-		if len(uas) != 0 {
-			for _, ua := range uas {
-				if ua.Name == "Twitterbot" {
-					fi := fileCache.Stat(id)
-					host := conf.Host
-					if host == "" {
-						host = g.Request.Host
-					}
-					return 200, out.HTML("twitterbot/content", &struct {
-						ID       string
-						Name     string
-						Uploaded time.Time
-						Size     fmtutil.Bytes
-						Host     string
-						Handle   string
-					}{
-						id,
-						strings.SplitN(fi.Name(), ".", 2)[1],
-						fi.ModTime(),
-						fmtutil.Bytes(fi.Size()),
-						host,
-						conf.TwitterHandle,
-					})
-				}
+	if t := (&transform{form.W, form.H, form.Fit, form.Format}); t.requested() {
+		return serveTransform(g, conf, meta, t)
+	}
+	if conf.PreviewEnable && !form.Raw && isUnfurlBot(g.Request) {
+		return servePreview(g, conf, meta)
+	}
+
+	// everything past here counts as a download
+	meta, last, err := fileCache.Hit(id)
+	switch err {
+	case nil:
+	case cache.ErrGone:
+		return 410, out.Error(g, err)
+	default:
+		return 404, out.Error(g, err)
+	}
+	if last {
+		defer func() {
+			if err := fileCache.Expire(id); err != nil {
+				log.Println(g.Request.Method, "getFile:", err)
 			}
-		}
+		}()
 	}
 
-	if g.Arg("filename") == "" {
-		filename := strings.SplitN(filepath.Base(file), ".", 2)[1]
-		contentdisposition.SetFilename(g, filename)
+	wantsRaw := form.Raw || form.Download || clientWantsRaw(g, form.Formatted)
+	if !wantsRaw && canBrowse(meta) {
+		return serveArchive(g, conf, meta)
 	}
 
-	threeMonthsFromNow := time.Now().Add(time.Hour * 24 * 30 * 3)
-	g.Header().Set("Expires", threeMonthsFromNow.Format(http.TimeFormat))
-	// enable browser caching for resources behind TLS
-	g.Header().Set("Cache-Control", "public")
+	// media opened in a browser tab gets a page around it, but not when it's
+	// embedded somewhere, or the page and the media would both count as
+	// downloads
+	if !wantsRaw && meta.MaxDownloads == 0 && (form.Formatted || wantsPage(g.Request)) {
+		if kind := viewerKind(meta); kind != "" {
+			return serveViewer(g, meta, kind)
+		}
+	}
 
-	f, err := os.Open(file)
-	if err != nil {
-		return 500, out.Error(g, err)
+	if form.Download {
+		contentdisposition.SetAttachment(g, meta.Name)
+	} else if g.Arg("filename") == "" {
+		contentdisposition.SetFilename(g, meta.Name)
 	}
-	defer f.Close()
+	setCacheHeaders(g, conf, meta)
 
-	fi, err := f.Stat()
-	if err != nil {
-		return 500, out.Error(g, err)
+	if !strings.HasPrefix(meta.ContentType, "text/") {
+		return serveUpload(g, conf, meta)
+	}
+
+	// documents are rendered unless a language is asked for, and pastes are
+	// always highlighted
+	render := findRenderer(meta.Name, meta.ContentType)
+	if meta.Lang != "" || form.Lang != "" {
+		render = nil
 	}
+	highlight := conf.SyntaxEnable || meta.Lang != "" || form.Lang != ""
 
-	bufsize := 512
-	fsize := int(fi.Size())
-	if fsize < bufsize {
-		bufsize = fsize
+	// browser user-agents should get formatted. regardless, one can force
+	// either way with url param if it matters
+	if wantsRaw || !(highlight || render != nil) {
+		return serveUpload(g, conf, meta)
 	}
 
-	buf := make([]byte, bufsize)
-	_, err = io.ReadFull(f, buf)
+	f, err := fileCache.Open(id)
+	if err != nil {
+		return 500, out.Error(g, err)
+	}
+	buffer, err := ioutil.ReadAll(f)
+	f.Close()
 	if err != nil {
 		return 500, out.Error(g, err)
 	}
 
-	contentType := http.DetectContentType(buf)
-
-	if !strings.HasPrefix(contentType, "text/") {
-		http.ServeFile(g, g.Request, file)
-		return g.Stop()
+	view := &textView{
+		Name:      meta.Name,
+		Langs:     []string{form.Lang, meta.Lang},
+		Render:    render,
+		Highlight: highlight,
+		Contents:  buffer,
 	}
+	if code, o, ok := view.serve(g, conf); ok {
+		return code, o
+	}
+	return serveUpload(g, conf, meta)
+}
 
-	clientWantsRaw := form.Raw
+// clientWantsRaw returns true if the request comes from something that would
+// rather have the contents of a file than a page showing them, unless
+// formatted is set to ask for the page anyway.
+func clientWantsRaw(g *gas.Gas, formatted bool) bool {
 	uas := g.UserAgents()
 
 	// for no user-agent or command line client, default to raw
 	if len(uas) == 0 {
-		clientWantsRaw = !form.Formatted
+		return !formatted
 	}
 
 	for _, ua := range uas {
 		switch strings.ToLower(ua.Name) {
 		case "curl", "wget":
-			clientWantsRaw = !form.Formatted
+			return !formatted
 		}
 	}
+	return false
+}
 
-	// browser user-agents should get formatted. regardless, one can force
-	// either way with url param if it matters
-	if form.Raw || clientWantsRaw || !conf.SyntaxEnable {
-		http.ServeFile(g, g.Request, file)
-		return g.Stop()
-	}
-
-	// Find lexer for file
-	lexer := lexers.Analyse(string(buf))
-	if lexer == nil {
-		extension := strings.TrimLeft(filepath.Ext(file), ".")
-		lexer = lexers.Get(extension)
+// setCacheHeaders tells browsers and proxies how long they can keep the
+// contents of an upload.
+func setCacheHeaders(g *gas.Gas, conf *config.Config, meta *cache.Meta) {
+	if meta.Limited() {
+		// nobody else should be holding on to a copy
+		g.Header().Set("Cache-Control", "no-store")
+	} else if conf.Storage.Redirect {
+		// the signed URL we redirect to won't last
+		g.Header().Set("Cache-Control", "no-cache")
+	} else {
+		threeMonthsFromNow := time.Now().Add(time.Hour * 24 * 30 * 3)
+		g.Header().Set("Expires", threeMonthsFromNow.Format(http.TimeFormat))
+		// enable browser caching for resources behind TLS
+		g.Header().Set("Cache-Control", "public")
 	}
+}
 
-	if lexer == nil {
-		http.ServeFile(g, g.Request, file)
-		return g.Stop()
-	}
+// textView is a text file to show to a browser as a rendered document or
+// highlighted source.
+type textView struct {
+	Name      string   // decides the language when nothing else does
+	Langs     []string // languages to highlight as, in order of preference
+	Render    renderer // renders it as a document instead, if not nil
+	Highlight bool     // whether to fall back to highlighting it
+	Contents  []byte
+}
 
-	lexer = chroma.Coalesce(lexer)
+// serve responds with the formatted file. If ok is false it couldn't be
+// formatted, and nothing was sent.
+func (v *textView) serve(g *gas.Gas, conf *config.Config) (code int, o gas.Outputter, ok bool) {
+	contents := string(v.Contents)
 
 	s := styles.Get(conf.SyntaxTheme)
 	if s == nil {
 		s = styles.Fallback
 	}
 
-	// Serve non-text files that slipped through, read text files to string
-	f.Seek(0, os.SEEK_SET)
-	buffer, err := ioutil.ReadAll(f)
-	if err != nil {
-		return 500, out.Error(g, err)
+	if v.Render != nil {
+		doc, err := v.Render(conf, s, v.Contents)
+		if err == nil {
+			g.Header().Set("Content-Security-Policy", renderedCSP)
+			data := &struct {
+				SyntaxTheme string
+				HTML        template.HTML
+				Filename    string
+				Language    string
+			}{
+				s.Name,
+				doc,
+				path.Base(v.Name),
+				"",
+			}
+			return 200, out.HTML("render/layout-syntax", &context{data}), true
+		}
+		log.Println(g.Request.Method, "serve:", v.Name, err)
+	}
+	if !v.Highlight {
+		return 0, nil, false
+	}
+
+	// Find lexer for file, preferring the languages asked for
+	var lexer chroma.Lexer
+	for _, name := range v.Langs {
+		if name != "" {
+			if lexer = lexers.Get(name); lexer != nil {
+				break
+			}
+		}
+	}
+	if lexer == nil {
+		head := contents
+		if len(head) > 512 {
+			head = head[:512]
+		}
+		lexer = lexers.Analyse(head)
+	}
+	if lexer == nil {
+		extension := strings.TrimLeft(filepath.Ext(v.Name), ".")
+		lexer = lexers.Get(extension)
 	}
-	contents := string(buffer)
 
-	// Setup formatter & iterate over file
-	formatter := html.New(html.WithClasses(true))
-	iterator, err := lexer.Tokenise(nil, contents)
+	if lexer == nil {
+		return 0, nil, false
+	}
 
-	// Get HTML
-	htmlBuffer := new(bytes.Buffer)
-	err = formatter.Format(htmlBuffer, s, iterator)
+	lexer = chroma.Coalesce(lexer)
 
+	formatted, err := highlightLines(lexer, s, contents)
 	if err != nil {
 		log.Print(err)
-		http.ServeFile(g, g.Request, file)
-		return g.Stop()
+		return 0, nil, false
 	}
 
 	// Render template
@@ -537,12 +738,47 @@ func getFile(g *gas.Gas) (int, gas.Outputter) {
 		SyntaxTheme string
 		HTML        template.HTML
 		Filename    string
+		Language    string
 	}{
 		s.Name,
-		template.HTML(htmlBuffer.String()),
-		strings.SplitN(filepath.Base(file), ".", 2)[1],
+		formatted,
+		path.Base(v.Name),
+		lexer.Config().Name,
+	}
+	return 200, out.HTML("syntax/layout-syntax", &context{data}), true
+}
+
+// signedURLTTL is how long the links that downloads are redirected to last.
+const signedURLTTL = time.Hour
+
+// serveUpload sends the contents of an upload from the store, or redirects to
+// them if the store supports it and it is configured to. The contents are
+// stored under their digest, so the upload's original name is what decides
+// the Content-Type.
+func serveUpload(g *gas.Gas, conf *config.Config, meta *cache.Meta) (int, gas.Outputter) {
+	// a link that can be passed around would get around download limits
+	if conf.Storage.Redirect && !meta.Limited() {
+		u, err := fileCache.SignedURL(meta.ID, signedURLTTL)
+		if err == nil {
+			return 302, out.Redirect(u)
+		}
+		if err != cache.ErrNotSigner {
+			log.Println(g.Request.Method, "serveUpload:", err)
+		}
+	}
+
+	f, err := fileCache.Open(meta.ID)
+	if err != nil {
+		log.Println(g.Request.Method, "serveUpload:", err)
+		return 500, out.Error(g, err)
+	}
+	defer f.Close()
+	// images are sent as what they turned out to be, whatever they're named
+	if thumb.DecodeFunc(meta.ContentType, "") != nil {
+		g.Header().Set("Content-Type", meta.ContentType)
 	}
-	return 200, out.HTML("syntax/layout-syntax", &context{data})
+	http.ServeContent(g, g.Request, meta.Name, meta.Uploaded, f)
+	return g.Stop()
 }
 
 func postFile(g *gas.Gas) (int, gas.Outputter) {
@@ -557,20 +793,47 @@ func postFile(g *gas.Gas) (int, gas.Outputter) {
 	}
 	defer g.Body.Close()
 
-	hash, err := fileCache.Put(g.Body, filename, conf)
+	info := &cache.Meta{
+		Name:   filename,
+		Addr:   remoteAddr(g),
+		Client: g.Request.UserAgent(),
+		Owner:  ownerName(g),
+	}
+	if err := uploadLimits(g, info); err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	token, err := newDeleteToken(info)
 	if err != nil {
 		log.Println(g.Request.Method, "postFile:", err)
 		return 500, out.JSON(&Resp{Err: err.Error()})
 	}
 
-	host := conf.Host
-	if host == "" {
-		host = g.Request.Host
+	var body io.Reader = g.Body
+	if left := quotaLeft(g); left >= 0 {
+		if g.Request.ContentLength > left {
+			return 413, out.JSON(&Resp{Err: errOverQuota.Error()})
+		}
+		body = &quotaReader{g.Body, left}
+	}
+
+	hash, err := fileCache.Put(body, info, conf)
+	if err == errOverQuota {
+		return 413, out.JSON(&Resp{Err: err.Error()})
 	}
-	if conf.AppendExt {
-		hash += filepath.Ext(filename)
+	if errors.Is(err, cache.ErrNotStripped) {
+		return 422, out.JSON(&Resp{Err: err.Error()})
+	}
+	if err != nil {
+		log.Println(g.Request.Method, "postFile:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
 	}
-	return 201, out.JSON(&Resp{URL: path.Join(host, hash)})
+
+	return 201, out.JSON(&Resp{
+		URL:         uploadURL(linkHost(g, conf), conf, hash, filename),
+		DeleteToken: token,
+		DeleteURL:   deleteURL(linkHost(g, conf), hash, token),
+		Stripped:    strippedFrom(hash),
+	})
 }
 
 func deleteFile(g *gas.Gas) (int, gas.Outputter) {
@@ -579,6 +842,14 @@ func deleteFile(g *gas.Gas) (int, gas.Outputter) {
 		return 400, out.JSON(&Resp{Err: "file ID not specified"})
 	}
 
+	m := fileCache.Stat(id)
+	if m == nil {
+		return 404, out.JSON(&Resp{Err: cache.ErrNotFound.Error()})
+	}
+	if !canManage(g, m) {
+		return 403, out.JSON(&Resp{Err: "you can only delete your own uploads"})
+	}
+
 	if err := fileCache.Remove(id); err != nil {
 		log.Println(g.Request.Method, "deleteFile:", err)
 		return 500, out.JSON(&Resp{Err: err.Error()})
@@ -590,7 +861,15 @@ func deleteFile(g *gas.Gas) (int, gas.Outputter) {
 func oops(g *gas.Gas) (int, gas.Outputter) {
 	conf := config.Get()
 
-	pruned, err := fileCache.RemoveNewest()
+	var (
+		pruned string
+		err    error
+	)
+	if u := requestUser(g); u != nil {
+		pruned, err = fileCache.RemoveNewestOwnedBy(u.Name)
+	} else {
+		pruned, err = fileCache.RemoveNewest()
+	}
 	if err != nil {
 		log.Println(g.Request.Method, "oops:", err)
 		return 500, out.JSON(&Resp{Err: err.Error()})
@@ -608,7 +887,7 @@ func getList(g *gas.Gas) (int, gas.Outputter) {
 	if err != nil {
 		limit = 10
 	}
-	list := getSortedList(0, limit)
+	list := getSortedList(visibleIDs(g), 0, limit)
 	return 200, out.JSON(list)
 }
 
@@ -633,7 +912,8 @@ func getHistoryPage(g *gas.Gas) (int, gas.Outputter) {
 		return 303, out.Redirect("/-/history/1")
 	}
 
-	l := fileCache.Len()
+	ids := visibleIDs(g)
+	l := len(ids)
 
 	offset := (page - 1) * itemsPerPage
 	if offset > l {
@@ -652,14 +932,14 @@ func getHistoryPage(g *gas.Gas) (int, gas.Outputter) {
 	}
 
 	p := &historyPage{
-		List:        getSortedList(offset, limit),
+		List:        getSortedList(ids, offset, limit),
 		CurrentPage: page,
 		TotalPages:  totalPages,
 		AppendExt:   conf.AppendExt,
 	}
 
 	for i := range p.List {
-		if thumb.DecodeFunc(p.List[i].Name) != nil {
+		if thumb.Supported(p.List[i].ContentType, p.List[i].Name) {
 			p.List[i].HasThumb = true
 		}
 	}
@@ -675,19 +955,28 @@ func getHistoryPage(g *gas.Gas) (int, gas.Outputter) {
 	return 200, out.HTML("history/layout-full", &context{p})
 }
 
+// setThumbLimits applies the limits on the thumbnail cache in conf.
+func setThumbLimits(conf *config.Config) {
+	thumbCache.SetMaxSize(conf.MaxThumbSize() * 1024 * 1024)
+	thumbCache.SetWorkers(conf.ThumbWorkers)
+	thumbCache.SetMaxDecode(conf.ThumbPixels * 1000 * 1000)
+}
+
 func getThumb(g *gas.Gas) (int, gas.Outputter) {
-	t := thumbCache.Get(g.Arg("id"), thumbWidth, thumbHeight)
-	if t == "" {
+	m := fileCache.Stat(g.Arg("id"))
+	if m == nil || !canManage(g, m) {
 		return 302, out.Redirect(placeholderThumb)
 	}
-	http.ServeFile(g, g.Request, t)
-	return g.Stop()
-}
-
-func getTwitterThumb(g *gas.Gas) (int, gas.Outputter) {
-	t := thumbCache.Get(g.Arg("id"), twitterThumbWidth, twitterThumbHeight)
-	if t == "" {
-		return 404, out.Error(g, errors.New("no thumbnail available"))
+	// GIFs and animated WebP images keep moving in the list
+	opt := thumb.Options{Accept: g.Request.Header.Get("Accept")}
+	if strings.HasPrefix(m.ContentType, "image/gif") || thumbCache.Animated(m.ID) {
+		opt.Format = ".gif"
+	}
+	g.Header().Add("Vary", "Accept")
+	t, err := thumbCache.Transform(g.Request.Context(), m.ID, thumbWidth, thumbHeight, opt)
+	if err != nil {
+		logThumbErr(err)
+		return 302, out.Redirect(placeholderThumb)
 	}
 	http.ServeFile(g, g.Request, t)
 	return g.Stop()
@@ -734,7 +1023,7 @@ func getAgeLimitPrune(g *gas.Gas) (int, gas.Outputter) {
 }
 
 func getIndex(g *gas.Gas) (int, gas.Outputter) {
-	if sess, ok := isLoggedIn(g); ok {
+	if sess, _, ok := isLoggedIn(g); ok {
 		if sess != nil {
 			sessions.Update(sess.Id)
 		}
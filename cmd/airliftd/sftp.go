@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path"
+	"strconv"
+
+	"ktkr.us/pkg/airlift/config"
+)
+
+// This is just enough of SFTP version 3 to accept new files, which is what
+// scp uses in place of its own protocol since OpenSSH 9.0. The client sees an
+// empty, write-only root directory.
+
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpOpen     = 3
+	fxpClose    = 4
+	fxpWrite    = 6
+	fxpLstat    = 7
+	fxpFstat    = 8
+	fxpSetstat  = 9
+	fxpFsetstat = 10
+	fxpRealpath = 16
+	fxpStat     = 17
+	fxpStatus   = 101
+	fxpHandle   = 102
+	fxpName     = 104
+	fxpAttrs    = 105
+
+	fxOK               = 0
+	fxNoSuchFile       = 2
+	fxPermissionDenied = 3
+	fxFailure          = 4
+	fxBadMessage       = 5
+	fxOpUnsupported    = 8
+
+	fxfWrite = 0x02
+
+	attrSize        = 0x01
+	attrPermissions = 0x04
+
+	// maxSFTPPacket is well above the 256 KiB the OpenSSH client sends at most.
+	maxSFTPPacket = 1 << 20
+)
+
+var errShortPacket = errors.New("sftp: short packet")
+
+// sftpReader decodes the fields of a packet, remembering the first error.
+type sftpReader struct {
+	b   []byte
+	err error
+}
+
+func (r *sftpReader) next(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = errShortPacket
+		return make([]byte, n)
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *sftpReader) uint32() uint32 { return binary.BigEndian.Uint32(r.next(4)) }
+func (r *sftpReader) uint64() uint64 { return binary.BigEndian.Uint64(r.next(8)) }
+
+func (r *sftpReader) string() string {
+	n := r.uint32()
+	if int64(n) > int64(len(r.b)) {
+		r.err = errShortPacket
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUint32(b, uint32(len(s))), s...)
+}
+
+func appendAttrs(b []byte, size int64, dir bool) []byte {
+	if dir {
+		return appendUint32(appendUint32(b, attrPermissions), 040755)
+	}
+	b = appendUint32(b, attrSize|attrPermissions)
+	return appendUint32(appendUint64(b, uint64(size)), 0100644)
+}
+
+// sftpFile is a file being written by the client, spooled to a temporary file
+// until it is closed.
+type sftpFile struct {
+	name string
+	f    *os.File
+	size int64 // the end of the furthest write
+}
+
+type sftpServer struct {
+	s     *sshSession
+	files map[string]*sftpFile
+	next  int
+}
+
+func (s *sshSession) serveSFTP() uint32 {
+	srv := &sftpServer{s: s, files: make(map[string]*sftpFile)}
+	defer func() {
+		// files the client never closed are thrown away
+		for _, f := range srv.files {
+			f.f.Close()
+			os.Remove(f.f.Name())
+		}
+	}()
+
+	var head [4]byte
+	for {
+		if _, err := io.ReadFull(s.ch, head[:]); err != nil {
+			if err == io.EOF {
+				return 0
+			}
+			log.Print("ssh: sftp: ", err)
+			return 1
+		}
+		n := binary.BigEndian.Uint32(head[:])
+		if n < 1 || n > maxSFTPPacket {
+			log.Print("ssh: sftp: bad packet length ", n)
+			return 1
+		}
+		pkt := make([]byte, n)
+		if _, err := io.ReadFull(s.ch, pkt); err != nil {
+			log.Print("ssh: sftp: ", err)
+			return 1
+		}
+		if err := srv.handle(pkt[0], &sftpReader{b: pkt[1:]}); err != nil {
+			log.Print("ssh: sftp: ", err)
+			return 1
+		}
+	}
+}
+
+func (srv *sftpServer) send(typ byte, id uint32, payload []byte) error {
+	b := appendUint32(nil, uint32(1+4+len(payload)))
+	b = appendUint32(append(b, typ), id)
+	_, err := srv.s.ch.Write(append(b, payload...))
+	return err
+}
+
+func (srv *sftpServer) status(id, code uint32, msg string) error {
+	b := appendUint32(nil, code)
+	b = appendString(appendString(b, msg), "")
+	return srv.send(fxpStatus, id, b)
+}
+
+// sftpClean resolves p against the root directory.
+func sftpClean(p string) string {
+	return path.Clean("/" + p)
+}
+
+func (srv *sftpServer) handle(typ byte, r *sftpReader) error {
+	if typ == fxpInit {
+		// the version goes where the request ID would be
+		return srv.send(fxpVersion, 3, nil)
+	}
+	id := r.uint32()
+	if r.err != nil {
+		return r.err
+	}
+
+	switch typ {
+	case fxpRealpath:
+		p := sftpClean(r.string())
+		if r.err != nil {
+			break
+		}
+		b := appendUint32(nil, 1)
+		b = appendString(appendString(b, p), p)
+		return srv.send(fxpName, id, appendAttrs(b, 0, p == "/"))
+
+	case fxpStat, fxpLstat:
+		p := sftpClean(r.string())
+		if r.err != nil {
+			break
+		}
+		if p != "/" {
+			return srv.status(id, fxNoSuchFile, "uploads can't be listed")
+		}
+		return srv.send(fxpAttrs, id, appendAttrs(nil, 0, true))
+
+	case fxpFstat:
+		f := srv.files[r.string()]
+		if r.err != nil {
+			break
+		}
+		if f == nil {
+			return srv.status(id, fxFailure, "bad handle")
+		}
+		return srv.send(fxpAttrs, id, appendAttrs(nil, f.size, false))
+
+	case fxpOpen:
+		p := sftpClean(r.string())
+		flags := r.uint32()
+		if r.err != nil {
+			break
+		}
+		if flags&fxfWrite == 0 {
+			return srv.status(id, fxPermissionDenied, "uploads can't be read back")
+		}
+		if p == "/" {
+			return srv.status(id, fxFailure, "missing file name")
+		}
+		tmp, err := ioutil.TempFile("", "airlift-sftp-")
+		if err != nil {
+			return srv.status(id, fxFailure, err.Error())
+		}
+		handle := strconv.Itoa(srv.next)
+		srv.next++
+		srv.files[handle] = &sftpFile{name: path.Base(p), f: tmp}
+		return srv.send(fxpHandle, id, appendString(nil, handle))
+
+	case fxpWrite:
+		f := srv.files[r.string()]
+		off := r.uint64()
+		data := r.string()
+		if r.err != nil {
+			break
+		}
+		if f == nil {
+			return srv.status(id, fxFailure, "bad handle")
+		}
+		if off > math.MaxInt64-uint64(len(data)) {
+			return srv.status(id, fxFailure, "offset out of range")
+		}
+		end := int64(off) + int64(len(data))
+		if max := config.Get().MaxSize() * 1024 * 1024; max > 0 && end > max {
+			return srv.status(id, fxFailure, "upload is larger than the maximum size")
+		}
+		if u, err := srv.s.user(); err != nil {
+			return srv.status(id, fxFailure, err.Error())
+		} else if left := userQuotaLeft(u); left >= 0 && end > left {
+			return srv.status(id, fxFailure, errOverQuota.Error())
+		}
+		if _, err := f.f.WriteAt([]byte(data), int64(off)); err != nil {
+			return srv.status(id, fxFailure, err.Error())
+		}
+		if end > f.size {
+			f.size = end
+		}
+		return srv.status(id, fxOK, "")
+
+	case fxpClose:
+		handle := r.string()
+		if r.err != nil {
+			break
+		}
+		f := srv.files[handle]
+		if f == nil {
+			return srv.status(id, fxFailure, "bad handle")
+		}
+		delete(srv.files, handle)
+		defer os.Remove(f.f.Name())
+		defer f.f.Close()
+
+		// only what was written is uploaded, whatever else is in the file
+		link, _, err := srv.s.upload(io.NewSectionReader(f.f, 0, f.size), f.name, f.size)
+		if err != nil {
+			srv.s.errorf("%s: %v", f.name, err)
+			return srv.status(id, fxFailure, err.Error())
+		}
+		fmt.Fprintf(srv.s.ch.Stderr(), "%s: %s\n", f.name, link)
+		return srv.status(id, fxOK, "")
+
+	case fxpSetstat, fxpFsetstat:
+		// modes and times mean nothing to uploads
+		return srv.status(id, fxOK, "")
+
+	default:
+		return srv.status(id, fxOpUnsupported, "not supported")
+	}
+
+	return srv.status(id, fxBadMessage, r.err.Error())
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+)
+
+// sshHostKeyFile is the name of the SSH server's private key in appDir. It is
+// generated the first time the server starts.
+const sshHostKeyFile = "ssh_host_ed25519_key"
+
+const sshUsage = `Upload a file by piping it to a command naming it:
+
+    cat log.txt | ssh -p PORT HOST log.txt
+
+or copy files with scp:
+
+    scp -P PORT file.jpg HOST:
+`
+
+func loadHostKey(file string) (ssh.Signer, error) {
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		b = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := ioutil.WriteFile(file, b, 0600); err != nil {
+			return nil, err
+		}
+		log.Print("ssh: generated new host key in ", file)
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(b)
+}
+
+// serveSSH runs an SSH server on addr that takes uploads from users
+// authenticated by their public keys. It only returns if the server can't
+// start.
+func serveSSH(addr string) error {
+	hostKey, err := loadHostKey(filepath.Join(appDir, sshHostKeyFile))
+	if err != nil {
+		return err
+	}
+	sc := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			u := config.Get().UserBySSHKey(key)
+			if u == nil {
+				return nil, errors.New("unknown public key")
+			}
+			return &ssh.Permissions{Extensions: map[string]string{"user": u.Name}}, nil
+		},
+		ServerVersion: "SSH-2.0-airliftd",
+	}
+	sc.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Print("ssh: listening on ", addr)
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		go handleSSHConn(c, sc)
+	}
+}
+
+func handleSSHConn(nc net.Conn, sc *ssh.ServerConfig) {
+	conn, chans, reqs, err := ssh.NewServerConn(nc, sc)
+	if err != nil {
+		log.Printf("ssh: %s: %v", nc.RemoteAddr(), err)
+		nc.Close()
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for nch := range chans {
+		if nch.ChannelType() != "session" {
+			nch.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, reqs, err := nch.Accept()
+		if err != nil {
+			log.Print("ssh: ", err)
+			continue
+		}
+		s := &sshSession{conn: conn, ch: ch}
+		go s.serve(reqs)
+	}
+}
+
+// sshSession is a single command run over an SSH connection.
+type sshSession struct {
+	conn *ssh.ServerConn
+	ch   ssh.Channel
+}
+
+func (s *sshSession) serve(reqs <-chan *ssh.Request) {
+	defer s.ch.Close()
+	for req := range reqs {
+		switch req.Type {
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			s.exit(s.exec(payload.Command))
+			return
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			s.exit(s.serveSFTP())
+			return
+		case "shell":
+			req.Reply(true, nil)
+			io.WriteString(s.ch.Stderr(), sshUsage)
+			s.exit(1)
+			return
+		case "pty-req", "env":
+			// harmless, but nothing here uses them
+			req.Reply(req.Type == "pty-req", nil)
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func (s *sshSession) exit(status uint32) {
+	s.ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+}
+
+// errorf writes an error message to the client's stderr.
+func (s *sshSession) errorf(format string, args ...interface{}) {
+	fmt.Fprintf(s.ch.Stderr(), "airlift: "+format+"\n", args...)
+}
+
+func (s *sshSession) exec(cmd string) uint32 {
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "scp" || strings.HasPrefix(cmd, "scp ") {
+		return s.scp(strings.Fields(cmd)[1:])
+	}
+	if cmd == "" {
+		io.WriteString(s.ch.Stderr(), sshUsage)
+		return 1
+	}
+
+	// the whole command is the file name, so that it can contain spaces
+	link, del, err := s.upload(s.ch, cmd, -1)
+	if err != nil {
+		s.errorf("%v", err)
+		return 1
+	}
+	fmt.Fprintln(s.ch, link)
+	fmt.Fprintln(s.ch.Stderr(), "delete with: curl -X DELETE '"+del+"'")
+	return 0
+}
+
+// user returns the account the session authenticated as.
+func (s *sshSession) user() (*config.User, error) {
+	u := config.Get().User(s.conn.Permissions.Extensions["user"])
+	if u == nil {
+		return nil, config.ErrUserNotFound
+	}
+	return u, nil
+}
+
+// host returns the host that links handed out over SSH are on. Without a
+// configured host, it's the address the client connected to.
+func (s *sshSession) host(conf *config.Config) string {
+	if conf.Host != "" {
+		return conf.Host
+	}
+	host, _, err := net.SplitHostPort(s.conn.LocalAddr().String())
+	if err != nil {
+		host = s.conn.LocalAddr().String()
+	}
+	return net.JoinHostPort(host, strconv.Itoa(conf.Port))
+}
+
+// upload stores the contents of r as a new upload called name on behalf of
+// the session's user, and returns its link and deletion link. size is the
+// length of the contents if it is known beforehand, or -1.
+func (s *sshSession) upload(r io.Reader, name string, size int64) (link, del string, err error) {
+	u, err := s.user()
+	if err != nil {
+		return "", "", err
+	}
+	name = path.Base(name)
+	if name == "." || name == "/" {
+		return "", "", errors.New("missing file name")
+	}
+
+	addr, _, err := net.SplitHostPort(s.conn.RemoteAddr().String())
+	if err != nil {
+		addr = s.conn.RemoteAddr().String()
+	}
+	info := &cache.Meta{
+		Name:   name,
+		Addr:   addr,
+		Client: string(s.conn.ClientVersion()),
+		Owner:  u.Name,
+	}
+	token, err := newDeleteToken(info)
+	if err != nil {
+		return "", "", err
+	}
+
+	if left := userQuotaLeft(u); left >= 0 {
+		if size > left {
+			return "", "", errOverQuota
+		}
+		r = &quotaReader{r, left}
+	}
+
+	conf := config.Get()
+	id, err := fileCache.Put(r, info, conf)
+	if err != nil {
+		return "", "", err
+	}
+	if stripped := strippedFrom(id); len(stripped) > 0 {
+		s.errorf("removed %s from %s", strings.Join(stripped, ", "), name)
+	}
+	host := s.host(conf)
+	return uploadURL(host, conf, id, name), deleteURL(host, id, token), nil
+}
+
+// scp acts as the remote end of the original scp protocol in sink mode (-t),
+// which is what scp runs when copying files to a server.
+func (s *sshSession) scp(args []string) uint32 {
+	var (
+		sink   bool
+		target string
+	)
+	for _, arg := range args {
+		switch {
+		case arg == "-t":
+			sink = true
+		case arg == "-f":
+			s.errorf("uploads can't be downloaded with scp")
+			return 1
+		case strings.HasPrefix(arg, "-"):
+			// -d, -p, -r, -v and friends don't change anything here
+		default:
+			target = arg
+		}
+	}
+	if !sink {
+		s.errorf("only copying files to the server is supported")
+		return 1
+	}
+	// copying to anything that isn't a directory names the upload
+	rename := ""
+	if target != "" && target != "." && target != "~" && !strings.HasSuffix(target, "/") {
+		rename = target
+	}
+
+	r := bufio.NewReader(s.ch)
+	ack := func() { s.ch.Write([]byte{0}) }
+	warn := func(err error) { fmt.Fprintf(s.ch, "\x01airlift: %v\n", err) }
+	fatal := func(err error) uint32 {
+		fmt.Fprintf(s.ch, "\x02airlift: %v\n", err)
+		return 1
+	}
+
+	ack()
+	status := uint32(0)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return status
+		}
+		if err != nil {
+			log.Print("ssh: scp: ", err)
+			return 1
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return fatal(errors.New("empty scp message"))
+		}
+
+		switch line[0] {
+		case 'T', 'E':
+			// timestamps and the end of a directory
+			ack()
+		case 'D':
+			return fatal(errors.New("directories can't be uploaded"))
+		case 'C':
+			// C<mode> <size> <name>
+			parts := strings.SplitN(line[1:], " ", 3)
+			if len(parts) != 3 {
+				return fatal(fmt.Errorf("bad scp message %q", line))
+			}
+			size, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil || size < 0 {
+				return fatal(fmt.Errorf("bad file size %q", parts[1]))
+			}
+			name := parts[2]
+			if rename != "" {
+				name = rename
+			}
+
+			u, err := s.user()
+			if err == nil {
+				if left := userQuotaLeft(u); left >= 0 && size > left {
+					err = errOverQuota
+				}
+			}
+			if err != nil {
+				// refusing now means the contents are never sent
+				warn(err)
+				status = 1
+				continue
+			}
+			ack()
+
+			body := io.LimitReader(r, size)
+			link, _, err := s.upload(body, name, size)
+			// keep in step with the client even if the upload failed
+			io.Copy(ioutil.Discard, body)
+			if b, rerr := r.ReadByte(); rerr != nil || b != 0 {
+				return fatal(errors.New("file contents were not terminated"))
+			}
+			if err != nil {
+				warn(err)
+				status = 1
+				continue
+			}
+			fmt.Fprintf(s.ch.Stderr(), "%s: %s\n", name, link)
+			ack()
+		default:
+			return fatal(fmt.Errorf("unknown scp message %q", line))
+		}
+	}
+}
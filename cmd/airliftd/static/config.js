@@ -11,6 +11,14 @@
 		reloadSection('/-/config/overview', '#section-overview', setupOverview);
 	}
 
+	function reloadAccount() {
+		reloadSection('/-/config', '#section-account', setupAccount);
+	}
+
+	function reloadUsers() {
+		reloadSection('/-/config', '#section-users', setupUsers);
+	}
+
 	function updateSample() {
 		var a = new Array(parseInt(idSize.value));
 		for (var i = 0; i < a.length; i++) {
@@ -48,12 +56,101 @@
 	}
 
 	function setupOverview() {
+		// only admins can purge
+		if ($('#purge-all-link') == null) {
+			return;
+		}
 		$('#purge-all-link').addEventListener('click', purgeAll, false);
 		$('#purge-thumbs-link').addEventListener('click', purgeThumbs, false);
 	}
 
+	// done returns a json callback that runs next on a 2xx response.
+	function done(next) {
+		return function(code, resp) {
+			if (code >= 200 && code < 300) {
+				next(resp);
+			} else if (code == 403 && resp.Err == null) {
+				redirectLogin();
+			} else {
+				errorMessage(resp);
+			}
+		};
+	}
+
+	function setupAccount() {
+		if ($('#section-account') == null) {
+			return;
+		}
+
+		$('#account-submit').addEventListener('click', function(e) {
+			e.preventDefault();
+			json('POST', '/-/account', new FormData($('#account')), done(function() {
+				$('#account').reset();
+				showMessage('Password changed.', 'good');
+			}));
+		}, false);
+
+		$('#token-submit').addEventListener('click', function(e) {
+			e.preventDefault();
+			json('POST', '/-/tokens', new FormData($('#token')), done(function(resp) {
+				reloadSection('/-/config', '#section-account', function() {
+					setupAccount();
+					$('#new-token').textContent = 'New API key (it won\'t be shown again): ' + resp.Token;
+				});
+			}));
+		}, false);
+
+		$('#ssh-keys-submit').addEventListener('click', function(e) {
+			e.preventDefault();
+			json('POST', '/-/account/ssh-keys', new FormData($('#ssh-keys')), done(function() {
+				showMessage('SSH keys saved.', 'good');
+			}));
+		}, false);
+
+		var links = $$('.revoke-token');
+		for (var i = 0, a; a = links[i]; i++) {
+			a.addEventListener('click', function() {
+				var id = this.parentNode.getAttribute('data-id');
+				if (!window.confirm('Revoke API key ' + id + '? Clients using it will stop working.')) {
+					return;
+				}
+				json('POST', '/-/tokens/' + id + '/revoke', null, done(reloadAccount));
+			}, false);
+		}
+	}
+
+	function setupUsers() {
+		if ($('#section-users') == null) {
+			return;
+		}
+
+		$('#user-submit').addEventListener('click', function(e) {
+			e.preventDefault();
+			json('POST', '/-/users', new FormData($('#user')), done(function() {
+				showMessage('User saved.', 'good');
+				reloadUsers();
+			}));
+		}, false);
+
+		var links = $$('.delete-user');
+		for (var i = 0, a; a = links[i]; i++) {
+			a.addEventListener('click', function() {
+				var name = this.parentNode.getAttribute('data-name');
+				if (!window.confirm('Delete user ' + name + '? Their uploads will be kept.')) {
+					return;
+				}
+				json('POST', '/-/users/' + encodeURIComponent(name) + '/delete', null, done(reloadUsers));
+			}, false);
+		}
+	}
+
 	function setupConfig() {
-		var buttons = $$('button'), host = $('#host');
+		// only admins can change the configuration
+		if ($('#section-config') == null) {
+			return;
+		}
+
+		var buttons = $$('#config button'), host = $('#host');
 		oldMaxSize  = parseInt($('#max-size').value);
 		oldMaxAge   = parseInt($('#max-age').value);
 		sampleID    = $('#sample-id');
@@ -134,8 +231,12 @@
 				host.value = host.value.replace(/\w+:\/\//, '');
 				var fd     = new FormData($('#config'));
 
+				var setup = $('#newpass') != null;
+
 				json('POST', '/-/config', fd, function(code, resp) {
-					$('#newpass-confirm').value = '';
+					if (setup) {
+						$('#newpass-confirm').value = '';
+					}
 
 					for (var i = 0, button; button = buttons[i]; i++) {
 						button.removeAttribute('disabled');
@@ -143,7 +244,11 @@
 
 					switch (code) {
 					case 204:
-						$('#newpass').value = '';
+						if (setup) {
+							// reload everything now that there is an account
+							window.location.reload();
+							return;
+						}
 						reloadConfigValues();
 						reloadOverview();
 						showMessage('Configuration updated.', 'good');
@@ -165,4 +270,6 @@
 
 	window.addEventListener('DOMContentLoaded', setupOverview, false);
 	window.addEventListener('DOMContentLoaded', setupConfig, false);
+	window.addEventListener('DOMContentLoaded', setupAccount, false);
+	window.addEventListener('DOMContentLoaded', setupUsers, false);
 })();
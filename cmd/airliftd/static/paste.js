@@ -0,0 +1,32 @@
+(function() {
+	'use strict';
+
+	function setupPaste() {
+		var button = $('#paste-submit');
+		button.addEventListener('click', function(e) {
+			e.preventDefault();
+			if ($('#paste-text').value === '') {
+				showMessage('There is nothing to paste.', 'bad');
+				return;
+			}
+
+			button.setAttribute('disabled', true);
+			json('POST', '/paste/web', new FormData($('#paste')), function(code, resp) {
+				button.removeAttribute('disabled');
+				switch (code) {
+				case 201:
+					window.location = window.location.protocol + '//' + resp.URL;
+					break;
+				case 403:
+					redirectLogin();
+					break;
+				default:
+					errorMessage(resp);
+					break;
+				}
+			});
+		}, false);
+	}
+
+	window.addEventListener('DOMContentLoaded', setupPaste, true);
+})();
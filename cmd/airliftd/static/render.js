@@ -0,0 +1,55 @@
+(function() {
+	'use strict';
+
+	// cellValue returns something to sort a table cell by: its number if it
+	// is one, or else its text. A data-sort attribute stands in for the text.
+	function cellValue(row, col) {
+		var cell = row.cells[col];
+		var text = '';
+		if (cell != null) {
+			text = cell.hasAttribute('data-sort') ? cell.getAttribute('data-sort') : cell.textContent.trim();
+		}
+		var n = Number(text.replace(/,/g, ''));
+		return text !== '' && !isNaN(n) ? n : text.toLowerCase();
+	}
+
+	function compare(a, b) {
+		if (typeof a === typeof b) {
+			return a < b ? -1 : a > b ? 1 : 0;
+		}
+		// numbers before text
+		return typeof a === 'number' ? -1 : 1;
+	}
+
+	function sortBy(table, th) {
+		var col = th.cellIndex;
+		var desc = th.classList.contains('asc');
+		var headings = table.tHead.rows[0].cells;
+		for (var i = 0; i < headings.length; i++) {
+			headings[i].classList.remove('asc', 'desc');
+		}
+		th.classList.add(desc ? 'desc' : 'asc');
+
+		var body = table.tBodies[0];
+		var rows = Array.prototype.slice.call(body.rows);
+		rows.sort(function(a, b) {
+			var c = compare(cellValue(a, col), cellValue(b, col));
+			return desc ? -c : c;
+		});
+		rows.forEach(function(row) { body.appendChild(row); });
+	}
+
+	function setupTables() {
+		var tables = document.querySelectorAll('table.sortable');
+		Array.prototype.forEach.call(tables, function(table) {
+			table.tHead.addEventListener('click', function(e) {
+				var th = e.target.closest('th');
+				if (th != null) {
+					sortBy(table, th);
+				}
+			}, false);
+		});
+	}
+
+	window.addEventListener('DOMContentLoaded', setupTables, true);
+})();
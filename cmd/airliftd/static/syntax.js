@@ -0,0 +1,60 @@
+(function() {
+	'use strict';
+
+	// range returns the first and last line in a fragment like #L10 or
+	// #L10-L20, or null.
+	function range() {
+		var m = /^#L(\d+)(?:-L?(\d+))?$/.exec(window.location.hash);
+		if (m == null) {
+			return null;
+		}
+		var a = parseInt(m[1], 10);
+		var b = m[2] != null ? parseInt(m[2], 10) : a;
+		return a <= b ? [a, b] : [b, a];
+	}
+
+	function highlight(scroll) {
+		var lit = document.querySelectorAll('.line.hl');
+		for (var i = 0; i < lit.length; i++) {
+			lit[i].classList.remove('hl');
+		}
+
+		var r = range();
+		if (r == null) {
+			return;
+		}
+		for (var n = r[0]; n <= r[1]; n++) {
+			var line = document.getElementById('L' + n);
+			if (line == null) {
+				break;
+			}
+			line.classList.add('hl');
+		}
+		var first = document.getElementById('L' + r[0]);
+		if (scroll && first != null) {
+			first.scrollIntoView({block: 'center'});
+		}
+	}
+
+	// clicking a line number selects it, and shift-clicking another one
+	// selects the lines in between
+	document.addEventListener('click', function(e) {
+		var a = e.target.closest('.ln');
+		if (a == null) {
+			return;
+		}
+		e.preventDefault();
+
+		var hash = '#' + a.parentNode.id;
+		var r = range();
+		if (e.shiftKey && r != null) {
+			var n = parseInt(a.parentNode.id.slice(1), 10);
+			hash = '#L' + Math.min(r[0], n) + '-L' + Math.max(r[0], n);
+		}
+		history.replaceState(null, '', hash);
+		highlight(false);
+	}, false);
+
+	window.addEventListener('hashchange', function() { highlight(true); }, false);
+	highlight(true);
+})();
@@ -3,6 +3,159 @@
 
 	var dropZone, dropZoneText, picker, urlList, bar;
 
+	var chunkSize  = 16 * 1024 * 1024;
+	var maxRetries = 5;
+
+	function b64(s) {
+		return window.btoa(unescape(encodeURIComponent(s)));
+	}
+
+	function parseResp(x) {
+		try {
+			return JSON.parse(x.response);
+		} catch (err) {
+			return { Err: x.statusText || 'network error' };
+		}
+	}
+
+	// tusUpload sends a file through the resumable upload endpoint. If the
+	// connection drops, the upload picks up from wherever the server left off,
+	// including after a page reload.
+	//
+	// progress func(loaded Number)
+	// done     func(url String)
+	// fail     func(code Number, resp Object)
+	function tusUpload(file, progress, done, fail) {
+		var key = 'tus:' + [file.name, file.size, file.lastModified].join(':');
+		var location = null, offset = 0, retries = 0, x = null, aborted = false;
+
+		function req(method, url, cb) {
+			x = new XMLHttpRequest();
+			x.open(method, url, true);
+			x.setRequestHeader('Tus-Resumable', '1.0.0');
+			x.addEventListener('load', function(e) { cb(e.target); }, false);
+			x.addEventListener('error', retry, false);
+			return x;
+		}
+
+		function retry() {
+			if (aborted) {
+				return;
+			}
+			if (retries++ < maxRetries && location != null) {
+				window.setTimeout(head, 1000 * retries);
+			} else {
+				fail(0, { Err: 'network error' });
+			}
+		}
+
+		function finish(url) {
+			window.localStorage.removeItem(key);
+			done(url);
+		}
+
+		function create() {
+			var x = req('POST', '/upload/tus', function(x) {
+				switch (x.status) {
+				case 201:
+					location = x.getResponseHeader('Location');
+					window.localStorage.setItem(key, location);
+					var url = x.getResponseHeader('X-Airlift-URL');
+					if (url) {
+						finish(url);
+					} else {
+						patch();
+					}
+					break;
+				default:
+					fail(x.status, parseResp(x));
+					break;
+				}
+			});
+			x.setRequestHeader('Upload-Length', file.size);
+			x.setRequestHeader('Upload-Metadata', 'filename ' + b64(file.name));
+			x.send(null);
+		}
+
+		function head() {
+			req('HEAD', location, function(x) {
+				switch (x.status) {
+				case 200:
+					offset = parseInt(x.getResponseHeader('Upload-Offset'));
+					var url = x.getResponseHeader('X-Airlift-URL');
+					if (url) {
+						finish(url);
+					} else {
+						patch();
+					}
+					break;
+				case 403:
+					fail(x.status, {});
+					break;
+				default:
+					// gone or never existed; start over
+					window.localStorage.removeItem(key);
+					location = null;
+					offset = 0;
+					create();
+					break;
+				}
+			}).send(null);
+		}
+
+		function patch() {
+			var chunk = file.slice(offset, offset + chunkSize);
+			var x = req('PATCH', location, function(x) {
+				switch (x.status) {
+				case 204:
+					retries = 0;
+					offset = parseInt(x.getResponseHeader('Upload-Offset'));
+					var url = x.getResponseHeader('X-Airlift-URL');
+					if (url) {
+						finish(url);
+					} else {
+						patch();
+					}
+					break;
+				case 409:
+					head();
+					break;
+				default:
+					fail(x.status, parseResp(x));
+					break;
+				}
+			});
+			x.setRequestHeader('Content-Type', 'application/offset+octet-stream');
+			x.setRequestHeader('Upload-Offset', offset);
+			x.upload.addEventListener('progress', function(e) {
+				if (e.lengthComputable) {
+					progress(offset + e.loaded);
+				}
+			}, false);
+			x.send(chunk);
+		}
+
+		location = window.localStorage.getItem(key);
+		if (location != null) {
+			head();
+		} else {
+			create();
+		}
+
+		return {
+			abort: function() {
+				aborted = true;
+				if (x != null) {
+					x.abort();
+				}
+				if (location != null) {
+					window.localStorage.removeItem(key);
+					req('DELETE', location, function() {}).send(null);
+				}
+			}
+		};
+	}
+
 	function paste(e) {
 		var item;
 		var c = chain();
@@ -130,33 +283,18 @@
 		for (var i = 0; i < fileList.length; i++) {
 			(function(file) {
 				c.then(function(pass, fail, result, totalLoaded) {
-					json('POST', '/upload/web', file, function(code, resp) {
-						switch (code) {
-						case 201:
-							result.push(window.location.protocol + '//' + resp.URL);
-							pass(result, totalLoaded);
-							break;
-						case 403:
+					x = tusUpload(file, function(loaded) {
+						bar.style.width = ((totalLoaded + loaded)*100 / totalSize) + '%';
+					}, function(url) {
+						totalLoaded += file.size;
+						bar.style.width = totalLoaded*100 / totalSize + '%';
+						result.push(window.location.protocol + '//' + url);
+						pass(result, totalLoaded);
+					}, function(code, resp) {
+						if (code == 403) {
 							redirectLogin();
-							break;
-						default:
-							fail(resp);
-							break;
-						}
-					}, function(x, afteropen) {
-						if (!afteropen) {
-							x.upload.addEventListener('progress', function(e) {
-								if (e.lengthComputable) {
-									bar.style.width = ((totalLoaded + e.loaded)*100 / totalSize) + '%';
-								}
-							}, false);
-
-							x.upload.addEventListener('load', function() {
-								totalLoaded += file.size;
-								bar.style.width = totalLoaded*100 / totalSize + '%';
-							}, false);
 						} else {
-							x.setRequestHeader('X-Airlift-Filename', encodeURIComponent(file.name));
+							fail(resp);
 						}
 					});
 				});
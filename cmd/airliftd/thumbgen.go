@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/inconsolata"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"ktkr.us/pkg/airlift/thumb"
+)
+
+// Files that aren't images get thumbnails made by these generators, which
+// are tried in the order they're registered.
+func registerThumbGenerators() {
+	thumb.RegisterGenerator("text/", textThumb)
+	// FLAC isn't always known as audio, so the contents decide
+	thumb.RegisterGenerator("", coverArtThumb)
+
+	f, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		log.Print("thumb: icon font: ", err)
+		return
+	}
+	iconFont = f
+	thumb.RegisterGenerator("", iconThumb)
+}
+
+const (
+	textThumbCols   = 60
+	textThumbLines  = 40
+	textThumbMargin = 16
+	textThumbRead   = 8 << 10 // how much of a text file is looked at
+)
+
+var (
+	thumbBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	thumbText       = color.RGBA{0x33, 0x33, 0x33, 0xff}
+)
+
+// textThumb draws the first page of a text file.
+func textThumb(r io.ReadSeeker, name, contentType string) (image.Image, error) {
+	b := make([]byte, textThumbRead)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b[:n])) == 0 {
+		return nil, thumb.ErrNoPreview
+	}
+	lines := strings.Split(string(b[:n]), "\n")
+	if len(lines) > textThumbLines {
+		lines = lines[:textThumbLines]
+	}
+
+	face := inconsolata.Regular8x16
+	lineHeight := face.Metrics().Height.Ceil()
+	cellWidth := font.MeasureString(face, "m").Ceil()
+	img := image.NewRGBA(image.Rect(0, 0,
+		textThumbCols*cellWidth+2*textThumbMargin,
+		textThumbLines*lineHeight+2*textThumbMargin))
+	draw.Draw(img, img.Bounds(), image.NewUniform(thumbBackground), image.Point{}, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(thumbText),
+		Face: face,
+	}
+	for i, line := range lines {
+		line = strings.Replace(strings.TrimRight(line, "\r"), "\t", "    ", -1)
+		if runes := []rune(line); len(runes) > textThumbCols {
+			line = string(runes[:textThumbCols])
+		}
+		d.Dot = fixed.P(textThumbMargin, textThumbMargin+i*lineHeight+face.Metrics().Ascent.Ceil())
+		d.DrawString(line)
+	}
+	return img, nil
+}
+
+// maxCoverArt is the most that is read of a song's tags to find its cover.
+const maxCoverArt = 16 << 20
+
+// coverArtThumb decodes the picture embedded in an MP3 or FLAC file, which
+// is usually the album cover.
+func coverArtThumb(r io.ReadSeeker, name, contentType string) (image.Image, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, thumb.ErrNoPreview
+	}
+	var (
+		pic []byte
+		err error
+	)
+	switch {
+	case string(magic[:3]) == "ID3":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		pic, err = id3Picture(r)
+	case string(magic[:]) == "fLaC":
+		pic, err = flacPicture(r)
+	default:
+		return nil, thumb.ErrNoPreview
+	}
+	if err != nil {
+		return nil, err
+	}
+	decode := thumb.DecodeFunc(thumb.Sniff(pic), "")
+	if decode == nil {
+		return nil, thumb.ErrNoPreview
+	}
+	// the picture is held to the same limit as images that are uploaded
+	pr := bytes.NewReader(pic)
+	if err := thumbCache.CheckPixels(pr); err != nil {
+		return nil, err
+	}
+	return decode(pr)
+}
+
+// synchsafe reads the 28-bit integers in ID3v2 tags, which leave the top bit
+// of each byte clear.
+func synchsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// id3Picture returns the front cover in the ID3v2 tag at the start of r, or
+// the first picture if there isn't one.
+func id3Picture(r io.Reader) ([]byte, error) {
+	var h [10]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, thumb.ErrNoPreview
+	}
+	major, flags, size := h[3], h[5], synchsafe(h[6:])
+	if major < 2 || major > 4 || size > maxCoverArt {
+		return nil, thumb.ErrNoPreview
+	}
+	tag := make([]byte, size)
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return nil, err
+	}
+	if flags&0x80 != 0 { // unsynchronisation
+		tag = bytes.Replace(tag, []byte{0xff, 0}, []byte{0xff}, -1)
+	}
+	if flags&0x40 != 0 && major >= 3 && len(tag) >= 4 { // extended header
+		n := int(binary.BigEndian.Uint32(tag)) + 4
+		if major == 4 {
+			n = synchsafe(tag)
+		}
+		if n > len(tag) {
+			return nil, thumb.ErrNoPreview
+		}
+		tag = tag[n:]
+	}
+
+	idLen, headLen := 4, 10
+	if major == 2 {
+		idLen, headLen = 3, 6
+	}
+	var first []byte
+	for len(tag) >= headLen && tag[0] != 0 {
+		id := string(tag[:idLen])
+		var n int
+		switch major {
+		case 2:
+			n = int(tag[3])<<16 | int(tag[4])<<8 | int(tag[5])
+		case 3:
+			n = int(binary.BigEndian.Uint32(tag[4:]))
+		default:
+			n = synchsafe(tag[4:])
+		}
+		if n < 0 || headLen+n > len(tag) {
+			break
+		}
+		data := tag[headLen : headLen+n]
+		tag = tag[headLen+n:]
+		if id != "APIC" && id != "PIC" {
+			continue
+		}
+
+		kind, pic := apicPicture(data, major == 2)
+		if kind == 3 && pic != nil { // front cover
+			return pic, nil
+		}
+		if first == nil {
+			first = pic
+		}
+	}
+	if first == nil {
+		return nil, thumb.ErrNoPreview
+	}
+	return first, nil
+}
+
+// apicPicture splits the picture type and data out of an attached picture
+// frame.
+func apicPicture(data []byte, v22 bool) (kind byte, pic []byte) {
+	if len(data) < 2 {
+		return 0, nil
+	}
+	enc := data[0]
+	data = data[1:]
+	if v22 { // a three letter format
+		if len(data) < 3 {
+			return 0, nil
+		}
+		data = data[3:]
+	} else { // a MIME type
+		i := bytes.IndexByte(data, 0)
+		if i < 0 {
+			return 0, nil
+		}
+		data = data[i+1:]
+	}
+	if len(data) < 1 {
+		return 0, nil
+	}
+	kind, data = data[0], data[1:]
+
+	// then a description, ended by a null character of its encoding
+	if enc == 1 || enc == 2 { // UTF-16
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return kind, data[i+2:]
+			}
+		}
+		return kind, nil
+	}
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return kind, nil
+	}
+	return kind, data[i+1:]
+}
+
+// flacPicture returns the front cover in the metadata of a FLAC file, or the
+// first picture if there isn't one. r is just past the file's "fLaC".
+func flacPicture(r io.ReadSeeker) ([]byte, error) {
+	var first []byte
+	for {
+		var h [4]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			break
+		}
+		last, typ := h[0]&0x80 != 0, h[0]&0x7f
+		length := int64(h[1])<<16 | int64(h[2])<<8 | int64(h[3])
+
+		if typ != 6 { // PICTURE
+			if _, err := r.Seek(length, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		} else {
+			block := make([]byte, length)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return nil, err
+			}
+			kind, pic := flacPictureBlock(block)
+			if kind == 3 && pic != nil {
+				return pic, nil
+			}
+			if first == nil {
+				first = pic
+			}
+		}
+		if last {
+			break
+		}
+	}
+	if first == nil {
+		return nil, thumb.ErrNoPreview
+	}
+	return first, nil
+}
+
+func flacPictureBlock(b []byte) (kind uint32, pic []byte) {
+	// each of these fields is a big-endian 32-bit number, and the MIME type,
+	// description and data are preceded by their lengths
+	next := func() (uint32, bool) {
+		if len(b) < 4 {
+			return 0, false
+		}
+		n := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		return n, true
+	}
+	skip := func() bool {
+		n, ok := next()
+		if !ok || uint64(n) > uint64(len(b)) {
+			return false
+		}
+		b = b[n:]
+		return true
+	}
+	kind, ok := next()
+	if !ok || !skip() || !skip() { // MIME type and description
+		return 0, nil
+	}
+	for i := 0; i < 4; i++ { // width, height, depth and colors
+		if _, ok := next(); !ok {
+			return 0, nil
+		}
+	}
+	n, ok := next()
+	if !ok || uint64(n) > uint64(len(b)) {
+		return 0, nil
+	}
+	return kind, b[:n]
+}
+
+// iconFont labels the icons with file extensions.
+var iconFont *opentype.Font
+
+const iconSize = 200
+
+var (
+	iconPage   = color.RGBA{0xfd, 0xfd, 0xfd, 0xff}
+	iconBorder = color.RGBA{0xab, 0xab, 0xab, 0xff}
+	iconFold   = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+
+	// the label is colored by what sort of file it is
+	iconColors = map[string]color.RGBA{
+		"text":    {0x46, 0x82, 0xb4, 0xff},
+		"image":   {0x4a, 0xa0, 0x5a, 0xff},
+		"audio":   {0x8a, 0x5c, 0xc6, 0xff},
+		"video":   {0xc6, 0x4a, 0x4a, 0xff},
+		"archive": {0xa0, 0x7a, 0x3c, 0xff},
+		"":        {0xcc, 0x66, 0x44, 0xff},
+	}
+)
+
+// iconThumb draws a page labeled with the file's extension, for files that
+// nothing else can show.
+func iconThumb(r io.ReadSeeker, name, contentType string) (image.Image, error) {
+	kind := contentType
+	if i := strings.IndexByte(kind, '/'); i >= 0 {
+		kind = kind[:i]
+	}
+	if archiveFormat(name) != "" {
+		kind = "archive"
+	}
+	label, ok := iconColors[kind]
+	if !ok {
+		label = iconColors[""]
+	}
+
+	const (
+		left, right  = 30, 170
+		top, bottom  = 3, 197
+		fold         = 40
+		labelTop     = 116
+		labelBottom  = 160
+		borderWidth  = 2
+		labelPadding = 10
+	)
+	img := image.NewRGBA(image.Rect(0, 0, iconSize, iconSize))
+	for y := 0; y < iconSize; y++ {
+		for x := 0; x < iconSize; x++ {
+			c := thumbBackground
+			// the corner above the diagonal is folded over
+			inPage := x >= left && x < right && y >= top && y < bottom && x-(right-fold) <= y-top
+			inside := x >= left+borderWidth && x < right-borderWidth &&
+				y >= top+borderWidth && y < bottom-borderWidth &&
+				x-(right-fold) < y-top-borderWidth
+			switch {
+			case inPage && !inside:
+				c = iconBorder
+			case x >= right-fold && y < top+fold && inPage:
+				c = iconFold
+			case inPage:
+				c = iconPage
+			}
+			if y >= labelTop && y < labelBottom && x >= labelPadding && x < iconSize-labelPadding {
+				c = label
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	ext := strings.ToUpper(strings.TrimPrefix(path.Ext(name), "."))
+	if ext == "" {
+		return img, nil
+	}
+	if len(ext) > 5 {
+		ext = ext[:5]
+	}
+	// faces hold buffers, so each thumbnail gets its own
+	face, err := opentype.NewFace(iconFont, &opentype.FaceOptions{Size: 32, DPI: 72})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+	}
+	m := face.Metrics()
+	width := d.MeasureString(ext).Ceil()
+	baseline := (labelTop+labelBottom)/2 + (m.Ascent.Ceil()-m.Descent.Ceil())/2
+	d.Dot = fixed.P((iconSize-width)/2, baseline)
+	d.DrawString(ext)
+	return img, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+	"ktkr.us/pkg/airlift/contentdisposition"
+	"ktkr.us/pkg/airlift/thumb"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/out"
+)
+
+// Images can be resized by adding ?w=, ?h=, ?fit= and ?format= to their
+// links. The results are kept in the thumbnail cache, so only the sizes in
+// the config can be asked for.
+
+// maxImageSize is the longest side that images can be resized to.
+const maxImageSize = 4096
+
+// transformFormats are the values of ?format=, and the extensions of the
+// thumbnail encoders they pick.
+var transformFormats = map[string]string{
+	"jpeg": ".jpg",
+	"jpg":  ".jpg",
+	"png":  ".png",
+	"gif":  ".gif",
+}
+
+// transform is how a request for an upload asks to resize it.
+type transform struct {
+	W, H   int
+	Fit    string // contain or cover
+	Format string // jpeg, png or gif
+}
+
+func (t *transform) requested() bool {
+	return t.W != 0 || t.H != 0 || t.Fit != "" || t.Format != ""
+}
+
+// parseImageSizes checks a list of sizes like "640x0 128x128", separated by
+// spaces or commas, and returns it separated by spaces. A side of zero is
+// left free.
+func parseImageSizes(s string) (string, error) {
+	fields := strings.Fields(strings.Replace(s, ",", " ", -1))
+	for i, f := range fields {
+		w, h, err := parseImageSize(f)
+		if err != nil {
+			return "", err
+		}
+		fields[i] = strconv.Itoa(w) + "x" + strconv.Itoa(h)
+	}
+	return strings.Join(fields, " "), nil
+}
+
+func parseImageSize(s string) (w, h int, err error) {
+	i := strings.IndexByte(strings.ToLower(s), 'x')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("image size %q is not like WIDTHxHEIGHT", s)
+	}
+	if w, err = strconv.Atoi(s[:i]); err == nil {
+		h, err = strconv.Atoi(s[i+1:])
+	}
+	switch {
+	case err != nil:
+		return 0, 0, fmt.Errorf("image size %q is not like WIDTHxHEIGHT", s)
+	case w < 0 || h < 0 || w == 0 && h == 0:
+		return 0, 0, fmt.Errorf("image size %q is empty", s)
+	case w > maxImageSize || h > maxImageSize:
+		return 0, 0, fmt.Errorf("image size %q is bigger than %dx%d", s, maxImageSize, maxImageSize)
+	}
+	return w, h, nil
+}
+
+// imageSizeAllowed returns true if images can be resized to w×h.
+func imageSizeAllowed(conf *config.Config, w, h int) bool {
+	want := strconv.Itoa(w) + "x" + strconv.Itoa(h)
+	for _, s := range strings.Fields(conf.ImageSizes) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// thumbStatus returns the status of a response for an error from the
+// thumbnail cache.
+func thumbStatus(err error) int {
+	var decodeErr *thumb.DecodeError
+	switch {
+	case err == thumb.ErrUnsupported:
+		return 415
+	case err == thumb.ErrSourceMissing:
+		return 404
+	case err == thumb.ErrBadOptions:
+		return 400
+	case err == thumb.ErrTooLarge:
+		return 422
+	case err == stdcontext.Canceled || err == stdcontext.DeadlineExceeded:
+		// nobody is waiting for the response
+		return 503
+	case errors.As(err, &decodeErr):
+		return 422
+	}
+	return 500
+}
+
+// logThumbErr logs an error from the thumbnail cache if it points to a
+// problem: a file that couldn't be decoded, or anything unexpected.
+func logThumbErr(err error) {
+	var decodeErr *thumb.DecodeError
+	if errors.As(err, &decodeErr) || thumbStatus(err) == 500 {
+		log.Print("thumb: ", err)
+	}
+}
+
+// serveTransform sends an image upload resized as t says.
+func serveTransform(g *gas.Gas, conf *config.Config, meta *cache.Meta, t *transform) (int, gas.Outputter) {
+	if meta.MaxDownloads > 0 {
+		return 403, out.Error(g, errors.New("uploads with a download limit can't be resized"))
+	}
+
+	opt := thumb.Options{Accept: g.Request.Header.Get("Accept")}
+	switch t.Fit {
+	case "", "contain":
+	case "cover":
+		opt.Cover = true
+	default:
+		return 400, out.Error(g, fmt.Errorf("unknown fit %q", t.Fit))
+	}
+	if t.Format != "" {
+		if opt.Format = transformFormats[strings.ToLower(t.Format)]; opt.Format == "" {
+			return 400, out.Error(g, fmt.Errorf("unknown format %q", t.Format))
+		}
+	} else {
+		// the format depends on what the browser can show
+		g.Header().Add("Vary", "Accept")
+	}
+
+	// without a size, the image is only converted
+	w, h := t.W, t.H
+	if opt.Cover && (w == 0 || h == 0) {
+		return 400, out.Error(g, errors.New("fit=cover needs both w and h"))
+	}
+	if w == 0 && h == 0 {
+		w, h = maxImageSize, maxImageSize
+	} else if !imageSizeAllowed(conf, w, h) {
+		return 400, out.Error(g, fmt.Errorf("images can't be resized to %dx%d", w, h))
+	}
+	// the thumbnail cache decodes anything of these types itself, so a broken
+	// image is an error rather than an icon standing in for it
+	if thumb.DecodeFunc(meta.ContentType, meta.Name) == nil {
+		return 415, out.Error(g, errors.New("only images can be resized"))
+	}
+
+	meta, _, err := fileCache.Hit(meta.ID)
+	switch err {
+	case nil:
+	case cache.ErrGone:
+		return 410, out.Error(g, err)
+	default:
+		return 404, out.Error(g, err)
+	}
+
+	p, err := thumbCache.Transform(g.Request.Context(), meta.ID, w, h, opt)
+	if err != nil {
+		logThumbErr(err)
+		return thumbStatus(err), out.Error(g, err)
+	}
+	name := strings.TrimSuffix(meta.Name, filepath.Ext(meta.Name)) + filepath.Ext(p)
+	contentdisposition.SetFilename(g, name)
+	setCacheHeaders(g, conf, meta)
+	http.ServeFile(g, g.Request, p)
+	return g.Stop()
+}
@@ -0,0 +1,216 @@
+package main
+
+// Resumable uploads implementing the core tus 1.0 protocol plus the creation
+// and termination extensions. See https://tus.io/protocols/resumable-upload.
+
+import (
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/out"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination"
+
+	// unfinished uploads are thrown away after this long
+	partialMaxAge = 24 * time.Hour
+)
+
+// checkTus sets the headers common to every tus response and rejects clients
+// speaking an unsupported version of the protocol.
+func checkTus(g *gas.Gas) (int, gas.Outputter) {
+	g.Header().Set("Tus-Resumable", tusVersion)
+	if g.Request.Method != "OPTIONS" && g.Request.Header.Get("Tus-Resumable") != tusVersion {
+		g.Header().Set("Tus-Version", tusVersion)
+		return 412, out.JSON(&Resp{Err: "unsupported tus version"})
+	}
+	return g.Continue()
+}
+
+func tusOptions(g *gas.Gas) (int, gas.Outputter) {
+	conf := config.Get()
+	g.Header().Set("Tus-Version", tusVersion)
+	g.Header().Set("Tus-Extension", tusExtensions)
+	if conf.MaxSize() > 0 {
+		g.Header().Set("Tus-Max-Size", strconv.FormatInt(conf.MaxSize()*1024*1024, 10))
+	}
+	return 204, nil
+}
+
+// parseTusMetadata decodes an Upload-Metadata header, which is a
+// comma-separated list of keys and base64-encoded values.
+func parseTusMetadata(s string) map[string]string {
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if kv[0] == "" {
+			continue
+		}
+		if len(kv) == 1 {
+			meta[kv[0]] = ""
+			continue
+		}
+		v, err := base64.StdEncoding.DecodeString(kv[1])
+		if err != nil {
+			continue
+		}
+		meta[kv[0]] = string(v)
+	}
+	return meta
+}
+
+func tusCreate(g *gas.Gas) (int, gas.Outputter) {
+	conf := config.Get()
+
+	length, err := strconv.ParseInt(g.Request.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return 400, out.JSON(&Resp{Err: "missing or invalid Upload-Length header"})
+	}
+	if conf.MaxSize() > 0 && length > conf.MaxSize()*1024*1024 {
+		return 413, out.JSON(&Resp{Err: "upload is larger than the maximum size"})
+	}
+
+	meta := parseTusMetadata(g.Request.Header.Get("Upload-Metadata"))
+	filename := meta["filename"]
+	if filename == "" {
+		filename = meta["name"]
+	}
+	if filename == "" {
+		filename, _ = url.QueryUnescape(g.Request.Header.Get("X-Airlift-Filename"))
+	}
+	if filename == "" {
+		return 400, out.JSON(&Resp{Err: "missing filename in upload metadata"})
+	}
+
+	info := &cache.Meta{
+		Name:   filename,
+		Addr:   remoteAddr(g),
+		Client: g.Request.UserAgent(),
+		Owner:  ownerName(g),
+	}
+	if err := uploadLimits(g, info); err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	if left := quotaLeft(g); left >= 0 && length > left {
+		return 413, out.JSON(&Resp{Err: errOverQuota.Error()})
+	}
+	token, err := newDeleteToken(info)
+	if err != nil {
+		log.Println(g.Request.Method, "tusCreate:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+	p, err := fileCache.CreatePartial(length, info)
+	if err != nil {
+		log.Println(g.Request.Method, "tusCreate:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+
+	if length == 0 {
+		// nothing will ever be PATCHed, so finish it now
+		if p, err = fileCache.AppendPartial(p.UID, 0, strings.NewReader(""), conf); err != nil {
+			log.Println(g.Request.Method, "tusCreate:", err)
+			return 500, out.JSON(&Resp{Err: err.Error()})
+		}
+		g.Header().Set("X-Airlift-URL", uploadURL(linkHost(g, conf), conf, p.ID, p.Info.Name))
+	}
+
+	// the upload doesn't have an ID yet, so only the token can be handed back
+	g.Header().Set("X-Airlift-Delete-Token", token)
+	g.Header().Set("Location", "/upload/tus/"+p.UID)
+	return 201, nil
+}
+
+// tusStatus returns the HTTP status code that corresponds to an error from the
+// cache's chunked upload methods.
+func tusStatus(err error) int {
+	if errors.Is(err, cache.ErrNotStripped) {
+		return 422
+	}
+	switch err {
+	case cache.ErrPartialNotFound:
+		return 404
+	case cache.ErrOffsetMismatch:
+		return 409
+	case cache.ErrPartialTooLarge:
+		return 413
+	case cache.ErrPartialBusy:
+		return 423
+	default:
+		return 500
+	}
+}
+
+func setTusOffset(g *gas.Gas, p *cache.Partial) {
+	g.Header().Set("Upload-Offset", strconv.FormatInt(p.Offset, 10))
+	g.Header().Set("Upload-Length", strconv.FormatInt(p.Length, 10))
+	if p.Done() {
+		conf := config.Get()
+		g.Header().Set("X-Airlift-URL", uploadURL(linkHost(g, conf), conf, p.ID, p.Info.Name))
+		setStrippedHeader(g, p.ID)
+	}
+}
+
+func tusHead(g *gas.Gas) (int, gas.Outputter) {
+	g.Header().Set("Cache-Control", "no-store")
+	p, err := fileCache.Partial(g.Arg("uid"))
+	if err != nil {
+		return tusStatus(err), nil
+	}
+	setTusOffset(g, p)
+	return 200, nil
+}
+
+func tusPatch(g *gas.Gas) (int, gas.Outputter) {
+	if g.Request.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		return 415, out.JSON(&Resp{Err: "content type must be application/offset+octet-stream"})
+	}
+	offset, err := strconv.ParseInt(g.Request.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return 400, out.JSON(&Resp{Err: "missing or invalid Upload-Offset header"})
+	}
+	defer g.Body.Close()
+
+	p, err := fileCache.AppendPartial(g.Arg("uid"), offset, g.Body, config.Get())
+	if p != nil {
+		setTusOffset(g, p)
+	}
+	if err != nil {
+		code := tusStatus(err)
+		if code == 500 {
+			log.Println(g.Request.Method, "tusPatch:", err)
+		}
+		return code, out.JSON(&Resp{Err: err.Error()})
+	}
+
+	return 204, nil
+}
+
+func tusDelete(g *gas.Gas) (int, gas.Outputter) {
+	if err := fileCache.RemovePartial(g.Arg("uid")); err != nil {
+		code := tusStatus(err)
+		if code == 500 {
+			log.Println(g.Request.Method, "tusDelete:", err)
+		}
+		return code, out.JSON(&Resp{Err: err.Error()})
+	}
+	return 204, nil
+}
+
+// watchPartials periodically throws away chunked uploads that were abandoned.
+// It should be run in its own goroutine.
+func watchPartials() {
+	for {
+		fileCache.RemoveStalePartials(time.Now().Add(-partialMaxAge))
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"html"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+	"ktkr.us/pkg/airlift/thumb"
+	"ktkr.us/pkg/fmtutil"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/out"
+)
+
+// Services that fetch links to show previews of them get a page of OpenGraph
+// and Twitter Card metadata instead of the upload itself, and can ask the
+// oEmbed endpoint about it.
+
+const (
+	// https://developer.twitter.com/en/docs/twitter-for-websites/cards/overview/summary-card-with-large-image
+	// "Images for this Card support an aspect ratio of 2:1 with minimum
+	// dimensions of 300x157 or maximum of 4096x4096 pixels."
+	previewThumbWidth  = 700
+	previewThumbHeight = 375
+
+	// snippetSize is how much of a text upload goes in its preview.
+	snippetSize = 300
+
+	// default size of embedded video players in oEmbed responses
+	oembedVideoWidth  = 640
+	oembedVideoHeight = 360
+)
+
+// unfurlBots are lowercased parts of the User-Agents sent by link preview
+// crawlers.
+var unfurlBots = []string{
+	"twitterbot",
+	"facebookexternalhit",
+	"slackbot",
+	"discordbot",
+	"mattermost",
+	"synapse", // Matrix homeservers
+	"telegrambot",
+	"whatsapp",
+	"linkedinbot",
+	"skypeuripreview",
+	"redditbot",
+	"iframely",
+	"embedly",
+}
+
+func isUnfurlBot(r *http.Request) bool {
+	ua := strings.ToLower(r.UserAgent())
+	if ua == "" {
+		return false
+	}
+	for _, bot := range unfurlBots {
+		if strings.Contains(ua, bot) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkScheme guesses the scheme that the upload links given to the client
+// are reached on.
+func linkScheme(g *gas.Gas) string {
+	if g.TLS != nil || gas.Env.TLSPort > 0 || g.Request.Header.Get("X-Forwarded-Proto") == "https" {
+		return "https"
+	}
+	return "http"
+}
+
+// preview describes an upload for the unfurl templates.
+type preview struct {
+	Kind        string // image, video, audio, text or file
+	ID          string
+	Name        string
+	ContentType string
+	Size        fmtutil.Bytes
+	Uploaded    time.Time
+	Site        string // host the upload is on
+	Handle      string // Twitter handle of the site
+	URL         string // link to the upload
+	Raw         string // link to the contents, empty if they can't be shown
+	Image       string // image to show in the preview, if any
+	Snippet     string // start of a text upload
+	OEmbed      string // oEmbed endpoint for the upload
+	Secure      bool
+}
+
+// previewKind returns the template that an upload is previewed with.
+func previewKind(meta *cache.Meta) string {
+	switch {
+	case thumb.DecodeFunc(meta.ContentType, meta.Name) != nil,
+		strings.HasPrefix(meta.ContentType, "image/"):
+		return "image"
+	case strings.HasPrefix(meta.ContentType, "video/"):
+		return "video"
+	case strings.HasPrefix(meta.ContentType, "audio/"):
+		return "audio"
+	case strings.HasPrefix(meta.ContentType, "text/"):
+		return "text"
+	}
+	return "file"
+}
+
+func newPreview(g *gas.Gas, conf *config.Config, meta *cache.Meta) *preview {
+	scheme := linkScheme(g)
+	host := linkHost(g, conf)
+	base := scheme + "://" + host
+	link := scheme + "://" + uploadURL(host, conf, meta.ID, meta.Name)
+	p := &preview{
+		Kind:        previewKind(meta),
+		ID:          meta.ID,
+		Name:        meta.Name,
+		ContentType: meta.ContentType,
+		Size:        fmtutil.Bytes(meta.Size),
+		Uploaded:    meta.Uploaded,
+		Site:        host,
+		Handle:      conf.TwitterHandle,
+		URL:         link,
+		OEmbed:      base + "/-/oembed?format=json&url=" + url.QueryEscape(link),
+		Secure:      scheme == "https",
+	}
+
+	// every fetch of the contents would count against a download limit
+	if meta.MaxDownloads > 0 {
+		return p
+	}
+	p.Raw = base + "/" + meta.ID + filepath.Ext(meta.Name) + "?raw=1"
+	switch p.Kind {
+	case "image":
+		if thumb.DecodeFunc(meta.ContentType, meta.Name) != nil {
+			p.Image = base + "/-/preview/" + meta.ID + ".jpg"
+		} else {
+			p.Image = p.Raw
+		}
+	case "text":
+		s, err := readSnippet(meta.ID)
+		if err != nil {
+			log.Print("preview: ", err)
+		}
+		p.Snippet = s
+	}
+	return p
+}
+
+// readSnippet returns the first few hundred bytes of a text upload, cut at a
+// line or character boundary.
+func readSnippet(id string) (string, error) {
+	f, err := fileCache.Open(id)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, snippetSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	buf = buf[:n]
+	if n == snippetSize {
+		if i := strings.LastIndexByte(string(buf), '\n'); i > 0 {
+			buf = buf[:i]
+		}
+		for len(buf) > 0 && !utf8.Valid(buf) {
+			buf = buf[:len(buf)-1]
+		}
+		buf = append(buf, "…"...)
+	}
+	return strings.TrimSpace(string(buf)), nil
+}
+
+// servePreview responds to a link preview crawler with the metadata of an
+// upload. It doesn't count as a download.
+func servePreview(g *gas.Gas, conf *config.Config, meta *cache.Meta) (int, gas.Outputter) {
+	p := newPreview(g, conf, meta)
+	return 200, out.HTML("unfurl/"+p.Kind, &context{p})
+}
+
+func getPreviewThumb(g *gas.Gas) (int, gas.Outputter) {
+	meta := fileCache.Stat(g.Arg("id"))
+	if meta == nil || meta.MaxDownloads > 0 || !config.Get().PreviewEnable {
+		return 404, out.Error(g, cache.ErrNotFound)
+	}
+	t, err := thumbCache.Get(g.Request.Context(), meta.ID, previewThumbWidth, previewThumbHeight)
+	if err != nil {
+		logThumbErr(err)
+		return thumbStatus(err), out.Error(g, err)
+	}
+	http.ServeFile(g, g.Request, t)
+	return g.Stop()
+}
+
+// oembed is a response from the oEmbed endpoint. See https://oembed.com.
+type oembed struct {
+	Version         string `json:"version"`
+	Type            string `json:"type"`
+	Title           string `json:"title"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	URL             string `json:"url,omitempty"`
+	HTML            string `json:"html,omitempty"`
+	Width           int    `json:"width,omitempty"`
+	Height          int    `json:"height,omitempty"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+}
+
+// oembedID picks the ID of an upload out of a link to it.
+func oembedID(link, host string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	p := u.Path
+	// the base URL may have a path of its own
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		p = strings.TrimPrefix(p, path.Clean(host[i:]))
+	}
+	p = strings.TrimPrefix(p, "/")
+	if i := strings.IndexAny(p, "/."); i >= 0 {
+		p = p[:i]
+	}
+	return p
+}
+
+// fitSize scales w×h down to fit within maxw×maxh, where a bound of zero
+// means there is none.
+func fitSize(w, h, maxw, maxh int) (int, int) {
+	if maxw > 0 && w > maxw {
+		h = h * maxw / w
+		w = maxw
+	}
+	if maxh > 0 && h > maxh {
+		w = w * maxh / h
+		h = maxh
+	}
+	return w, h
+}
+
+func imageSize(r io.Reader) (int, int, error) {
+	c, _, err := image.DecodeConfig(r)
+	return c.Width, c.Height, err
+}
+
+func getOEmbed(g *gas.Gas) (int, gas.Outputter) {
+	conf := config.Get()
+	if !conf.PreviewEnable {
+		return 404, out.JSON(&Resp{Err: "link previews are disabled"})
+	}
+	var form struct {
+		URL       string `form:"url"`
+		Format    string `form:"format"`
+		MaxWidth  int    `form:"maxwidth"`
+		MaxHeight int    `form:"maxheight"`
+	}
+	if err := g.UnmarshalForm(&form); err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	if form.Format != "" && form.Format != "json" {
+		return 501, out.JSON(&Resp{Err: "only the json format is supported"})
+	}
+	meta := fileCache.Stat(oembedID(form.URL, linkHost(g, conf)))
+	if meta == nil {
+		return 404, out.JSON(&Resp{Err: cache.ErrNotFound.Error()})
+	}
+
+	p := newPreview(g, conf, meta)
+	o := &oembed{
+		Version:      "1.0",
+		Type:         "link",
+		Title:        meta.Name,
+		ProviderName: "airlift",
+		ProviderURL:  linkScheme(g) + "://" + p.Site,
+	}
+
+	if p.Image != "" && p.Image != p.Raw {
+		if t, err := thumbCache.Get(g.Request.Context(), meta.ID, previewThumbWidth, previewThumbHeight); err == nil {
+			if f, err := os.Open(t); err == nil {
+				w, h, err := imageSize(f)
+				f.Close()
+				if err == nil {
+					o.ThumbnailURL, o.ThumbnailWidth, o.ThumbnailHeight = p.Image, w, h
+				}
+			}
+		}
+	}
+
+	if p.Raw == "" {
+		return 200, out.JSON(o)
+	}
+	src := html.EscapeString(p.Raw)
+	switch p.Kind {
+	case "image":
+		f, err := fileCache.Open(meta.ID)
+		if err != nil {
+			log.Println(g.Request.Method, "getOEmbed:", err)
+			break
+		}
+		w, h, err := imageSize(f)
+		f.Close()
+		if err != nil {
+			break
+		}
+		o.Type, o.URL = "photo", p.Raw
+		o.Width, o.Height = fitSize(w, h, form.MaxWidth, form.MaxHeight)
+	case "video":
+		o.Type = "video"
+		o.Width, o.Height = fitSize(oembedVideoWidth, oembedVideoHeight, form.MaxWidth, form.MaxHeight)
+		o.HTML = `<video src="` + src + `" controls width="` + strconv.Itoa(o.Width) + `" height="` + strconv.Itoa(o.Height) + `"></video>`
+	case "audio":
+		o.Type = "rich"
+		o.Width, o.Height = fitSize(oembedVideoWidth, 54, form.MaxWidth, form.MaxHeight)
+		o.HTML = `<audio src="` + src + `" controls></audio>`
+	}
+	return 200, out.JSON(o)
+}
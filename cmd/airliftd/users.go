@@ -0,0 +1,498 @@
+package main
+
+import (
+	stdcontext "context"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/airlift/config"
+	"ktkr.us/pkg/fmtutil"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/auth"
+	"ktkr.us/pkg/gas/out"
+)
+
+var errOverQuota = errors.New("upload would exceed your storage quota")
+
+type requesterKey struct{}
+
+// requester is who a request was authenticated as.
+type requester struct {
+	user  *config.User
+	token *config.Token // nil unless an API key was used
+}
+
+// setRequestUser remembers which account (and API key, if any) is making the
+// request for the handlers further down the chain.
+func setRequestUser(g *gas.Gas, u *config.User, t *config.Token) {
+	ctx := stdcontext.WithValue(g.Request.Context(), requesterKey{}, requester{u, t})
+	g.Request = g.Request.WithContext(ctx)
+}
+
+// requestUser returns the account making the request. It is nil if the server
+// hasn't been set up with any accounts yet.
+func requestUser(g *gas.Gas) *config.User {
+	r, _ := g.Request.Context().Value(requesterKey{}).(requester)
+	return r.user
+}
+
+// requestToken returns the API key the request was made with, or nil if it
+// was made some other way.
+func requestToken(g *gas.Gas) *config.Token {
+	r, _ := g.Request.Context().Value(requesterKey{}).(requester)
+	return r.token
+}
+
+// canManage returns true if the requesting user may see and delete the given
+// upload. Requests without a user have either been let through by the
+// upload's deletion token or were made before any accounts existed.
+func canManage(g *gas.Gas, m *cache.Meta) bool {
+	u := requestUser(g)
+	return u == nil || u.Admin || m.Owner == u.Name
+}
+
+// ownerName returns the name recorded as the owner of uploads made by the
+// request.
+func ownerName(g *gas.Gas) string {
+	if u := requestUser(g); u != nil {
+		return u.Name
+	}
+	return ""
+}
+
+// visibleIDs returns the IDs of the uploads the requesting user may see,
+// sorted ascending by upload time.
+func visibleIDs(g *gas.Gas) []string {
+	u := requestUser(g)
+	if u == nil || u.Admin {
+		return fileCache.SortedIDs()
+	}
+	return fileCache.SortedIDsOwnedBy(u.Name)
+}
+
+// bearerToken returns the API key sent in the request, if any. The
+// X-Airlift-Token header is accepted as well as the standard Authorization
+// header.
+func bearerToken(g *gas.Gas) string {
+	const prefix = "Bearer "
+	if h := g.Request.Header.Get("Authorization"); len(h) > len(prefix) && strings.EqualFold(h[:len(prefix)], prefix) {
+		return strings.TrimSpace(h[len(prefix):])
+	}
+	return g.Request.Header.Get("X-Airlift-Token")
+}
+
+// headerUser authenticates a request by its API key or password headers, or
+// HTTP Basic credentials where the password may also be an API key. A password
+// must be accompanied by a username unless there is only one account.
+func headerUser(g *gas.Gas, conf *config.Config) (*config.User, *config.Token, error) {
+	if secret := bearerToken(g); secret != "" {
+		return tokenUser(conf, secret)
+	}
+
+	if name, pass, ok := g.Request.BasicAuth(); ok {
+		if strings.HasPrefix(pass, config.TokenPrefix) {
+			return tokenUser(conf, pass)
+		}
+		u := conf.User(name)
+		if u == nil || !u.CheckPass(pass) {
+			return nil, nil, errors.New("incorrect username or password")
+		}
+		return u, nil, nil
+	}
+
+	pass := g.Request.Header.Get("X-Airlift-Password")
+	if pass == "" {
+		return nil, nil, errors.New("password required")
+	}
+	name := g.Request.Header.Get("X-Airlift-User")
+	if name == "" {
+		if len(conf.Users) != 1 {
+			return nil, nil, errors.New("username required")
+		}
+		name = conf.Users[0].Name
+	}
+	u := conf.User(name)
+	if u == nil || !u.CheckPass(pass) {
+		return nil, nil, errors.New("incorrect username or password")
+	}
+	return u, nil, nil
+}
+
+func tokenUser(conf *config.Config, secret string) (*config.User, *config.Token, error) {
+	u, t := conf.UserByToken(secret)
+	if u == nil {
+		return nil, nil, errors.New("invalid or expired API key")
+	}
+	if err := config.TouchToken(u.Name, t.ID); err != nil {
+		log.Print("recording API key use: ", err)
+	}
+	return u, t, nil
+}
+
+// header password or API key
+func checkPassword(g *gas.Gas) (int, gas.Outputter) {
+	conf := config.Get()
+
+	if !conf.NeedsSetup() {
+		u, t, err := headerUser(g, conf)
+		if err != nil {
+			return 403, out.JSON(&Resp{Err: err.Error()})
+		}
+		setRequestUser(g, u, t)
+	}
+
+	return g.Continue()
+}
+
+// checkDeleter lets a request through if it carries the deletion token of the
+// upload it refers to, in which case no user is attached to the request.
+// Otherwise the password or an API key is required.
+func checkDeleter(g *gas.Gas) (int, gas.Outputter) {
+	token := g.Request.Header.Get("X-Airlift-Delete-Token")
+	if token == "" {
+		token = g.Request.URL.Query().Get("delete_token")
+	}
+	if token == "" {
+		return checkPassword(g)
+	}
+
+	m := fileCache.Stat(g.Arg("id"))
+	if m == nil {
+		return 404, out.JSON(&Resp{Err: cache.ErrNotFound.Error()})
+	}
+	if !m.CheckDeleteToken(token) {
+		return 403, out.JSON(&Resp{Err: "incorrect deletion token"})
+	}
+	return g.Continue()
+}
+
+// needScope returns a handler that rejects requests made with an API key that
+// wasn't given the scope. Requests authenticated any other way can do
+// anything.
+func needScope(scope string) gas.Handler {
+	return func(g *gas.Gas) (int, gas.Outputter) {
+		if t := requestToken(g); t != nil && !t.Allows(scope) {
+			return 403, out.JSON(&Resp{Err: "API key lacks the " + scope + " scope"})
+		}
+		return g.Continue()
+	}
+}
+
+// checkUploader allows requests from either a logged in session or a client
+// sending the password or API key headers.
+func checkUploader(g *gas.Gas) (int, gas.Outputter) {
+	if sess, u, ok := isLoggedIn(g); ok {
+		if sess != nil {
+			sessions.Update(sess.Id)
+			setRequestUser(g, u, nil)
+		}
+		return g.Continue()
+	}
+	return checkPassword(g)
+}
+
+func checkLogin(g *gas.Gas) (int, gas.Outputter) {
+	sess, u, ok := isLoggedIn(g)
+	if !ok {
+		if g.Request.Method == "POST" {
+			return 403, nil
+		}
+		return 303, out.Reroute("/-/login", g.URL.Path)
+	}
+
+	if sess != nil {
+		sessions.Update(sess.Id)
+		setRequestUser(g, u, nil)
+	}
+
+	return g.Continue()
+}
+
+// checkAdmin only lets admins through. It must come after one of the other
+// auth checks.
+func checkAdmin(g *gas.Gas) (int, gas.Outputter) {
+	if u := requestUser(g); u != nil && !u.Admin {
+		return 403, out.JSON(&Resp{Err: "only admins can do that"})
+	}
+	return g.Continue()
+}
+
+// isLoggedIn returns true if the request contains a valid session cookie for
+// an existing user OR there are no accounts set up on the server yet. A valid
+// session and its user will only be returned if the former case occurs.
+func isLoggedIn(g *gas.Gas) (*auth.Session, *config.User, bool) {
+	conf := config.Get()
+
+	if conf.NeedsSetup() {
+		return nil, nil, true
+	}
+
+	sess, _ := auth.GetSession(g)
+	if sess == nil {
+		return nil, nil, false
+	}
+	u := conf.User(sess.Username)
+	if u == nil {
+		// the account was deleted
+		return nil, nil, false
+	}
+	return sess, u, true
+}
+
+// quotaLeft returns how many more bytes the requesting user may upload, or -1
+// if there is no limit.
+func quotaLeft(g *gas.Gas) int64 {
+	return userQuotaLeft(requestUser(g))
+}
+
+// userQuotaLeft is quotaLeft for u, which may be nil.
+func userQuotaLeft(u *config.User) int64 {
+	if u == nil || u.Quota <= 0 {
+		return -1
+	}
+	n := u.MaxSize() - fileCache.SizeOwnedBy(u.Name)
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// quotaReader fails with errOverQuota once more than n bytes have been read.
+type quotaReader struct {
+	r io.Reader
+	n int64
+}
+
+func (q *quotaReader) Read(p []byte) (int, error) {
+	n, err := q.r.Read(p)
+	q.n -= int64(n)
+	if q.n < 0 {
+		return n, errOverQuota
+	}
+	return n, err
+}
+
+// accountData is the part of the config page that every user sees.
+type accountData struct {
+	User      *config.User
+	Used      fmtutil.Bytes
+	Scopes    []string
+	IsAdmin   bool
+	Users     []*config.User
+	UserSizes map[string]fmtutil.Bytes
+}
+
+func getAccount(g *gas.Gas) *accountData {
+	u := requestUser(g)
+	if u == nil {
+		return nil
+	}
+	data := &accountData{
+		User:    u,
+		Used:    fmtutil.Bytes(fileCache.SizeOwnedBy(u.Name)),
+		Scopes:  config.Scopes,
+		IsAdmin: u.Admin,
+	}
+	if u.Admin {
+		data.Users = config.Get().Users
+		data.UserSizes = make(map[string]fmtutil.Bytes, len(data.Users))
+		for _, uu := range data.Users {
+			data.UserSizes[uu.Name] = fmtutil.Bytes(fileCache.SizeOwnedBy(uu.Name))
+		}
+	}
+	return data
+}
+
+// updateUser applies f to the stored copy of the named user and saves the
+// config.
+func updateUser(name string, f func(*config.User) error) error {
+	return config.Update(func(conf *config.Config) error {
+		u := conf.User(name)
+		if u == nil {
+			return config.ErrUserNotFound
+		}
+		return f(u)
+	})
+}
+
+// postAccount changes the requesting user's password.
+func postAccount(g *gas.Gas) (int, gas.Outputter) {
+	u := requestUser(g)
+	if u == nil {
+		return 400, out.JSON(&Resp{Err: "no accounts have been set up"})
+	}
+	if !u.CheckPass(g.FormValue("pass")) {
+		return 400, out.JSON(&Resp{Err: "incorrect password"})
+	}
+	newpass := g.FormValue("newpass")
+	if newpass == "" {
+		return 400, out.JSON(&Resp{Err: "cannot set empty password"})
+	}
+	if newpass != g.FormValue("newpass-confirm") {
+		return 400, out.JSON(&Resp{Err: "passwords do not match"})
+	}
+
+	err := updateUser(u.Name, func(u *config.User) error {
+		u.SetPass(newpass)
+		return nil
+	})
+	if err != nil {
+		log.Println(g.Request.Method, "postAccount:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+	return 204, nil
+}
+
+// postSSHKeys replaces the requesting user's SSH public keys, which let them
+// upload through the SSH server.
+func postSSHKeys(g *gas.Gas) (int, gas.Outputter) {
+	u := requestUser(g)
+	if u == nil {
+		return 400, out.JSON(&Resp{Err: "no accounts have been set up"})
+	}
+	var badKeys error
+	err := config.Update(func(conf *config.Config) error {
+		badKeys = conf.SetSSHKeys(u.Name, g.FormValue("keys"))
+		return badKeys
+	})
+	if badKeys != nil {
+		return 400, out.JSON(&Resp{Err: badKeys.Error()})
+	} else if err != nil {
+		log.Println(g.Request.Method, "postSSHKeys:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+	return 204, nil
+}
+
+// postToken creates a new API key for the requesting user. The key is
+// returned only this once.
+func postToken(g *gas.Gas) (int, gas.Outputter) {
+	u := requestUser(g)
+	if u == nil {
+		return 400, out.JSON(&Resp{Err: "no accounts have been set up"})
+	}
+
+	// FormValue parses the form, so Form is safe to use after this
+	name := strings.TrimSpace(g.FormValue("name"))
+	if name == "" {
+		return 400, out.JSON(&Resp{Err: "API keys need a name"})
+	}
+	var expires time.Time
+	if s := g.FormValue("expires"); s != "" {
+		days, err := strconv.Atoi(s)
+		if err != nil || days < 0 {
+			return 400, out.JSON(&Resp{Err: "bad number of days until expiry"})
+		}
+		if days > 0 {
+			expires = time.Now().Add(time.Duration(days) * 24 * time.Hour)
+		}
+	}
+
+	var secret string
+	err := updateUser(u.Name, func(u *config.User) (err error) {
+		_, secret, err = u.NewToken(name, g.Form["scope"], expires)
+		return err
+	})
+	switch err {
+	case nil:
+	case config.ErrNoScopes, config.ErrBadScope:
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	default:
+		log.Println(g.Request.Method, "postToken:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+	return 201, out.JSON(&struct{ Token string }{secret})
+}
+
+func revokeToken(g *gas.Gas) (int, gas.Outputter) {
+	u := requestUser(g)
+	if u == nil {
+		return 400, out.JSON(&Resp{Err: "no accounts have been set up"})
+	}
+
+	err := updateUser(u.Name, func(u *config.User) error {
+		if !u.RevokeToken(g.Arg("id")) {
+			return errors.New("no such API key")
+		}
+		return nil
+	})
+	if err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	return 204, nil
+}
+
+// postUser creates a user or, if one by that name exists already, updates
+// their role, quota and (if given) password.
+func postUser(g *gas.Gas) (int, gas.Outputter) {
+	form := struct {
+		Name  string `form:"name"`
+		Pass  string `form:"pass"`
+		Admin bool   `form:"admin"`
+		Quota int64  `form:"quota"`
+	}{}
+	if err := g.UnmarshalForm(&form); err != nil {
+		return 400, out.JSON(&Resp{Err: err.Error()})
+	}
+	if form.Quota < 0 {
+		form.Quota = 0
+	}
+
+	var badForm error
+	err := config.Update(func(conf *config.Config) error {
+		u := conf.User(form.Name)
+		if u == nil {
+			if form.Pass == "" {
+				badForm = errors.New("cannot set empty password")
+				return badForm
+			}
+			if u, badForm = conf.AddUser(form.Name, form.Pass, form.Admin); badForm != nil {
+				return badForm
+			}
+		} else {
+			if u.Admin && !form.Admin && conf.Admins() == 1 {
+				badForm = errors.New("there must be at least one admin")
+				return badForm
+			}
+			u.Admin = form.Admin
+			if form.Pass != "" {
+				u.SetPass(form.Pass)
+			}
+		}
+		u.Quota = form.Quota
+		return nil
+	})
+	if badForm != nil {
+		return 400, out.JSON(&Resp{Err: badForm.Error()})
+	} else if err != nil {
+		log.Println(g.Request.Method, "postUser:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+	return 204, nil
+}
+
+// deleteUser removes an account. Their uploads are kept.
+func deleteUser(g *gas.Gas) (int, gas.Outputter) {
+	name := g.Arg("name")
+	if u := requestUser(g); u != nil && u.Name == name {
+		return 400, out.JSON(&Resp{Err: "you can't delete yourself"})
+	}
+
+	var notFound error
+	err := config.Update(func(conf *config.Config) error {
+		notFound = conf.RemoveUser(name)
+		return notFound
+	})
+	if notFound != nil {
+		return 404, out.JSON(&Resp{Err: notFound.Error()})
+	} else if err != nil {
+		log.Println(g.Request.Method, "deleteUser:", err)
+		return 500, out.JSON(&Resp{Err: err.Error()})
+	}
+	return 204, nil
+}
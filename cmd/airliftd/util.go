@@ -1,18 +1,23 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"ktkr.us/pkg/airlift/cache"
 	"ktkr.us/pkg/airlift/config"
 	"ktkr.us/pkg/fmtutil"
 	"ktkr.us/pkg/gas"
-	"ktkr.us/pkg/gas/auth"
 	"ktkr.us/pkg/gas/out"
 )
 
@@ -28,23 +33,6 @@ func (c *context) Config() *config.Config {
 	return config.Get()
 }
 
-// header password
-func checkPassword(g *gas.Gas) (int, gas.Outputter) {
-	conf := config.Get()
-
-	if conf.Password != nil {
-		pass := g.Request.Header.Get("X-Airlift-Password")
-		if pass == "" {
-			return 403, out.JSON(&Resp{Err: "password required"})
-		}
-		if !auth.VerifyHash([]byte(pass), conf.Password, conf.Salt) {
-			return 403, out.JSON(&Resp{Err: "incorrect password"})
-		}
-	}
-
-	return g.Continue()
-}
-
 func redirectTLS(g *gas.Gas) (int, gas.Outputter) {
 	if g.TLS == nil && gas.Env.TLSPort > 0 {
 		host := g.Host
@@ -60,37 +48,175 @@ func redirectTLS(g *gas.Gas) (int, gas.Outputter) {
 	return g.Continue()
 }
 
-func checkLogin(g *gas.Gas) (int, gas.Outputter) {
-	sess, ok := isLoggedIn(g)
-	if !ok {
-		if g.Request.Method == "POST" {
-			return 403, nil
+// remoteAddr returns the address of the client. Requests from a trusted
+// proxy are from the address it passed on in X-Forwarded-For instead: the
+// nearest one in the list that isn't another trusted proxy, since anything
+// before that could have been made up by the client.
+func remoteAddr(g *gas.Gas) string {
+	host, _, err := net.SplitHostPort(g.Request.RemoteAddr)
+	if err != nil {
+		host = g.Request.RemoteAddr
+	}
+	proxies := config.Get().TrustedProxies
+	if !trustedProxy(proxies, host) {
+		return host
+	}
+	hops := strings.Split(strings.Join(g.Request.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+		if !trustedProxy(proxies, hop) {
+			break
 		}
-		return 303, out.Reroute("/-/login", g.URL.Path)
 	}
+	return host
+}
 
-	if sess != nil {
-		sessions.Update(sess.Id)
+// trustedProxy returns true if addr is one of the addresses or networks in
+// proxies, a list separated by spaces.
+func trustedProxy(proxies, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, p := range strings.Fields(proxies) {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			if n.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(p)) {
+			return true
+		}
 	}
+	return false
+}
 
-	return g.Continue()
+// parseProxies checks a list of addresses and networks like "127.0.0.1
+// 10.0.0.0/8", separated by spaces or commas, and returns it separated by
+// spaces.
+func parseProxies(s string) (string, error) {
+	fields := strings.Fields(strings.Replace(s, ",", " ", -1))
+	for _, f := range fields {
+		if _, _, err := net.ParseCIDR(f); err != nil && net.ParseIP(f) == nil {
+			return "", fmt.Errorf("trusted proxy %q is not an IP address or network", f)
+		}
+	}
+	return strings.Join(fields, " "), nil
 }
 
-// isLoggedIn returns true if the request contains a valid session cookie OR
-// there is no password configured for the server yet. A valid session will
-// only be returned if the former case occurs.
-func isLoggedIn(g *gas.Gas) (*auth.Session, bool) {
-	conf := config.Get()
+// parseExpiry reads an expiry time given either as a duration from now or as
+// an RFC 3339 timestamp.
+func parseExpiry(s string) (time.Time, error) {
+	now := time.Now()
+	var t time.Time
+	if d, err := time.ParseDuration(s); err == nil {
+		t = now.Add(d)
+	} else if t, err = time.Parse(time.RFC3339, s); err != nil {
+		return time.Time{}, fmt.Errorf("bad format in expiry time: %q", s)
+	}
+	if !t.After(now) {
+		return time.Time{}, errors.New("expiry time must be in the future")
+	}
+	return t, nil
+}
 
-	if conf.Password != nil {
-		sess, _ := auth.GetSession(g)
-		if sess == nil {
-			return nil, false
+// uploadLimits reads the optional per-upload expiry and download limit headers
+// into info.
+func uploadLimits(g *gas.Gas, info *cache.Meta) error {
+	if s := g.Request.Header.Get("X-Airlift-Expires"); s != "" {
+		t, err := parseExpiry(s)
+		if err != nil {
+			return err
+		}
+		info.Expires = t
+	}
+	if s := g.Request.Header.Get("X-Airlift-Max-Downloads"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad format in max downloads header: %q", s)
 		}
-		return sess, true
+		info.MaxDownloads = n
+	}
+	return nil
+}
+
+// linkHost returns the host (and path) that links are handed out on: the
+// configured one, or whatever host the request was sent to.
+func linkHost(g *gas.Gas, conf *config.Config) string {
+	if conf.Host != "" {
+		return conf.Host
+	}
+	return g.Request.Host
+}
+
+// uploadURL returns the link (without scheme) on host that is handed back to
+// the uploader of the file with the given ID and original name.
+func uploadURL(host string, conf *config.Config, id, filename string) string {
+	if conf.AppendExt {
+		id += filepath.Ext(filename)
+	}
+	return path.Join(host, id)
+}
+
+// strippedFrom returns the kinds of metadata that were removed from an upload.
+func strippedFrom(id string) []string {
+	if m := fileCache.Stat(id); m != nil {
+		return m.Stripped
 	}
+	return nil
+}
 
-	return nil, true
+// setStrippedHeader lists the kinds of metadata that were removed from an
+// upload in a header, for responses that have no body to put them in.
+func setStrippedHeader(g *gas.Gas, id string) {
+	if s := strippedFrom(id); len(s) > 0 {
+		g.Header().Set("X-Airlift-Stripped", strings.Join(s, ", "))
+	}
+}
+
+// newDeleteToken generates a random token that lets the uploader delete the
+// upload described by info without logging in.
+func newDeleteToken(info *cache.Meta) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	info.SetDeleteToken(token)
+	return token, nil
+}
+
+// deleteURL returns the link (without scheme) on host that deletes the upload
+// with the given ID when it is sent a DELETE request.
+func deleteURL(host, id, token string) string {
+	return path.Join(host, id) + "?delete_token=" + url.QueryEscape(token)
+}
+
+// newStore returns the storage backend described by s, or nil for the default
+// of keeping uploads on local disk.
+func newStore(s config.Storage) (cache.Store, error) {
+	switch s.Backend {
+	case "", "local":
+		return nil, nil
+	case "s3":
+		if s.Endpoint == "" || s.Bucket == "" {
+			return nil, errors.New("the s3 backend needs an endpoint and a bucket")
+		}
+		return &cache.S3Store{
+			Endpoint:  s.Endpoint,
+			Region:    s.Region,
+			Bucket:    s.Bucket,
+			Prefix:    s.Prefix,
+			AccessKey: s.AccessKey,
+			SecretKey: s.SecretKey,
+			PathStyle: s.PathStyle,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", s.Backend)
+	}
 }
 
 // return to the URL that sent the reroute
@@ -107,11 +233,12 @@ func reroute(g *gas.Gas) (int, gas.Outputter) {
 
 // File represents a single upload on disk.
 type File struct {
-	ID       string
-	Name     string
-	Uploaded time.Time
-	HasThumb bool
-	Size     fmtutil.Bytes
+	ID          string
+	Name        string
+	ContentType string
+	Uploaded    time.Time
+	HasThumb    bool
+	Size        fmtutil.Bytes
 }
 
 // Ext returns the file extension of the upload's file name on disk.
@@ -130,8 +257,9 @@ func (f *File) Ago() string {
 	return fmtutil.LongDuration(n)
 }
 
-func getSortedList(offset, limit int) []*File {
-	ids := fileCache.SortedIDs()
+// getSortedList returns the files with the given IDs in reverse order,
+// skipping the last offset and returning at most limit of them.
+func getSortedList(ids []string, offset, limit int) []*File {
 	if offset > len(ids) {
 		offset = 0
 	}
@@ -139,21 +267,22 @@ func getSortedList(offset, limit int) []*File {
 		limit = len(ids)
 	}
 
-	fileCache.RLock()
-
-	list := make([]*File, limit)
+	list := make([]*File, 0, limit)
 	ids = ids[len(ids)-limit-offset : len(ids)-offset]
-	for i, id := range ids {
-		fi := fileCache.Stat(id)
-		list[len(list)-i-1] = &File{
-			ID:       id,
-			Name:     strings.SplitN(fi.Name(), ".", 2)[1],
-			Uploaded: fi.ModTime(),
-			Size:     fmtutil.Bytes(fi.Size()),
+	for i := len(ids) - 1; i >= 0; i-- {
+		m := fileCache.Stat(ids[i])
+		if m == nil {
+			// removed since the list was taken
+			continue
 		}
+		list = append(list, &File{
+			ID:          m.ID,
+			Name:        m.Name,
+			ContentType: m.ContentType,
+			Uploaded:    m.Uploaded,
+			Size:        fmtutil.Bytes(m.Size),
+		})
 	}
 
-	fileCache.RUnlock()
-
 	return list
 }
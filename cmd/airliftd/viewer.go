@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"ktkr.us/pkg/airlift/cache"
+	"ktkr.us/pkg/fmtutil"
+	"ktkr.us/pkg/gas"
+	"ktkr.us/pkg/gas/out"
+)
+
+// viewerImages are the image types that browsers can show on their own.
+var viewerImages = map[string]bool{
+	"image/jpeg":    true,
+	"image/png":     true,
+	"image/gif":     true,
+	"image/webp":    true,
+	"image/svg+xml": true,
+	"image/bmp":     true,
+	"image/avif":    true,
+	"image/x-icon":  true,
+}
+
+// viewerKind returns "image", "video" or "audio" for an upload that is shown
+// on a viewer page, or an empty string if it isn't.
+func viewerKind(meta *cache.Meta) string {
+	t := meta.ContentType
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	t = strings.TrimSpace(t)
+	switch {
+	case viewerImages[t]:
+		return "image"
+	case strings.HasPrefix(t, "video/"):
+		return "video"
+	case strings.HasPrefix(t, "audio/"):
+		return "audio"
+	}
+	return ""
+}
+
+// wantsPage returns true if the browser is going to show the response as a
+// page of its own, rather than embedding it with an <img> or <video> tag.
+func wantsPage(r *http.Request) bool {
+	if dest := r.Header.Get("Sec-Fetch-Dest"); dest != "" {
+		return dest == "document"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// serveViewer shows a browser an image, video or audio upload with its
+// details and a button to download it. The media itself is loaded from the
+// ?raw=1 link, so uploads with a download limit shouldn't get here.
+func serveViewer(g *gas.Gas, meta *cache.Meta, kind string) (int, gas.Outputter) {
+	data := &struct {
+		Kind        string
+		Name        string
+		ContentType string
+		Size        fmtutil.Bytes
+		Uploaded    time.Time
+	}{
+		kind,
+		meta.Name,
+		meta.ContentType,
+		fmtutil.Bytes(meta.Size),
+		meta.Uploaded,
+	}
+	return 200, out.HTML("viewer/layout-lite", &context{data})
+}
@@ -20,15 +20,20 @@ var (
 	flag_host     = flag.String("h", "", "Set host to upload to")
 	flag_port     = flag.String("p", "", "Set port or interface of remote server to upload to")
 	flag_addr     = flag.String("a", "", "Set whole address of server to upload to")
+	flag_user     = flag.String("u", "", "Set username to log in to the server with")
 	flag_name     = flag.String("f", "", "Specify a different filename to use. If -z, it names the zip archive")
 	flag_stdin    = flag.String("s", "", "Give stdin stream a filename")
-	flag_remove   = flag.String("r", "", "Instruct the server to delete the file with a given ID")
+	flag_remove   = flag.String("r", "", "Instruct the server to delete the file with a given ID or URL")
 	flag_zip      = flag.Bool("z", false, "Upload the input file(s) (and stdin) as a single zip file")
 	flag_inclname = flag.Bool("n", false, "Include filename in returned URL (overrides -e)")
 	flag_inclext  = flag.Bool("e", false, "Append file extension to returned URL")
 	flag_nocopy   = flag.Bool("C", false, "Do not copy link to clipboard")
 	flag_noprog   = flag.Bool("P", false, "Do not show progress bar")
 	flag_oops     = flag.Bool("oops", false, "Delete the last file uploaded")
+	flag_expire   = flag.Duration("expire", 0, "Have the server delete the upload after this long (e.g. 1h)")
+	flag_once     = flag.Bool("once", false, "Have the server delete the upload after it is downloaded once")
+	flag_paste    = flag.Bool("paste", false, "Post stdin as a text paste")
+	flag_lang     = flag.String("lang", "", "With -paste, set the language to highlight the paste as (e.g. go)")
 	dotfilePath   string
 )
 
@@ -65,6 +70,13 @@ func main() {
 
 	configured := config(conf)
 
+	if *flag_paste {
+		u := postPaste(conf)
+		fmt.Println(u)
+		copyLinks([]string{u})
+		return
+	}
+
 	if flag.NArg() == 0 {
 		if configured {
 			return
@@ -135,24 +147,48 @@ func main() {
 		urls = append(urls, u)
 	}
 
-	if !*flag_nocopy {
-		str := strings.Join(urls, "\n")
-		if err := copyString(str); err != nil {
-			if err != errNotCopying {
-				fmt.Fprintf(os.Stderr, "(Error copying to clipboard: %v)\n", err)
-			}
-		} else {
-			fmt.Fprintln(os.Stderr, "(Copied to clipboard)")
+	copyLinks(urls)
+}
+
+// copyLinks puts the given links on the clipboard unless -C is set.
+func copyLinks(urls []string) {
+	if *flag_nocopy {
+		return
+	}
+	str := strings.Join(urls, "\n")
+	if err := copyString(str); err != nil {
+		if err != errNotCopying {
+			fmt.Fprintf(os.Stderr, "(Error copying to clipboard: %v)\n", err)
 		}
+	} else {
+		fmt.Fprintln(os.Stderr, "(Copied to clipboard)")
 	}
 }
 
 type Resp struct {
-	URL string
-	Err string
+	URL         string
+	DeleteToken string
+	DeleteURL   string
+	Stripped    []string
+	Err         string
 }
 
 func (conf *Config) TryRequest(makeReq func() *http.Request, expectCode int) Resp {
+	resp, msg, err := conf.tryRequest(makeReq)
+	if err != nil {
+		fatal("Invalid server response:", err)
+	}
+	if resp.StatusCode != expectCode {
+		fmt.Fprintln(os.Stderr, resp.Status)
+		fatal("Server returned error:", msg.Err)
+	}
+	return msg
+}
+
+// tryRequest makes a request, asking for a new password and retrying for as
+// long as the server rejects the current one. Any other response is returned
+// to the caller along with its decoded body, if it has one.
+func (conf *Config) tryRequest(makeReq func() *http.Request) (*http.Response, Resp, error) {
 	if conf.Host == "" {
 		fmt.Fprintln(os.Stderr, "Host not configured.")
 		flag.Usage()
@@ -171,44 +207,37 @@ func (conf *Config) TryRequest(makeReq func() *http.Request, expectCode int) Res
 			fatal(err)
 		}
 
-		if resp.StatusCode != expectCode {
-			fmt.Fprintln(os.Stderr, resp.Status)
-		}
-
 		var msg Resp
 
-		if resp.StatusCode != http.StatusNoContent {
-			err = json.NewDecoder(resp.Body).Decode(&msg)
-			resp.Body.Close()
-			if err != nil {
-				fatal("Invalid server response:", err)
-			}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			fatal(err)
+		}
+		if len(b) > 0 && resp.StatusCode != http.StatusNoContent {
+			err = json.Unmarshal(b, &msg)
 		}
 
-		switch resp.StatusCode {
-		case expectCode:
-			return msg
-
-		case http.StatusForbidden:
-			if alreadyWrong {
-				fmt.Fprintln(os.Stderr, "Sorry, wrong password.")
-			} else {
-				fmt.Fprintln(os.Stderr, "Server returned error:", msg.Err)
-				fmt.Fprintln(os.Stderr, "You'll need a new password. If the request is successful,")
-				fmt.Fprintf(os.Stderr, "it will be saved in %s.\n", PasswordStorageMechanism)
-				alreadyWrong = true
-			}
-			fmt.Fprint(os.Stderr, "Password: ")
-			pass, err := readPassword()
-			if err != nil {
-				fatal(err)
-			}
-			if err = updatePassword(conf, pass); err != nil {
-				fatal(err)
-			}
+		if resp.StatusCode != http.StatusForbidden {
+			return resp, msg, err
+		}
 
-		default:
-			fatal("Server returned error:", msg.Err)
+		fmt.Fprintln(os.Stderr, resp.Status)
+		if alreadyWrong {
+			fmt.Fprintln(os.Stderr, "Sorry, wrong password.")
+		} else {
+			fmt.Fprintln(os.Stderr, "Server returned error:", msg.Err)
+			fmt.Fprintln(os.Stderr, "You'll need a new password or API key. If the request is successful,")
+			fmt.Fprintf(os.Stderr, "it will be saved in %s.\n", PasswordStorageMechanism)
+			alreadyWrong = true
+		}
+		fmt.Fprint(os.Stderr, "Password: ")
+		pass, err := readPassword()
+		if err != nil {
+			fatal(err)
+		}
+		if err = updatePassword(conf, pass); err != nil {
+			fatal(err)
 		}
 	}
 }
@@ -232,13 +261,25 @@ func oops(conf *Config) {
 	}
 }
 
+// remove deletes an upload given its ID or URL. If it was uploaded from here,
+// its deletion token is used instead of the password.
 func remove(conf *Config, id string) {
-	req, err := http.NewRequest("DELETE", conf.BaseURL("/"+id), nil)
-	if err != nil {
-		fatal(err)
-	}
+	id = uploadID(conf, id)
+	token := deleteToken(conf, id)
 
-	conf.TryRequest(requestMaker(req), http.StatusNoContent)
+	conf.TryRequest(func() *http.Request {
+		req, err := http.NewRequest("DELETE", conf.BaseURL("/"+id), nil)
+		if err != nil {
+			fatal(err)
+		}
+		// if the token is rejected, fall back to asking for the password
+		if token != "" {
+			req.Header.Set("X-Airlift-Delete-Token", token)
+			token = ""
+		}
+		return req
+	}, http.StatusNoContent)
+	forgetUpload(conf, id)
 }
 
 type NotAnError int
@@ -256,13 +297,23 @@ func (c *Config) BaseURL(add string) string {
 	return c.Scheme + "://" + c.Host + ":" + c.Port + add
 }
 
-// attach the password. Only do so if there's a password stored for the given
-// host.
+// tokenPrefix begins every API key issued by the server.
+const tokenPrefix = "alt_"
+
+// attach the password or API key. Only do so if there's one stored for the
+// given host.
 func (c *Config) PrepareRequest(req *http.Request) error {
 	pass, err := getPassword(c)
 	switch err {
 	case nil:
+		if strings.HasPrefix(pass, tokenPrefix) {
+			req.Header.Set("Authorization", "Bearer "+pass)
+			return nil
+		}
 		req.Header.Set("X-Airlift-Password", pass)
+		if c.User != "" {
+			req.Header.Set("X-Airlift-User", c.User)
+		}
 		return nil
 	case errPassNotFound:
 		return nil
@@ -336,6 +387,10 @@ func config(conf *Config) bool {
 			conf.Port = "80"
 		}
 	}
+	if *flag_user != "" {
+		configured = true
+		conf.User = *flag_user
+	}
 	if conf.Scheme == "" {
 		conf.Scheme = "http"
 	}
@@ -355,6 +410,26 @@ type FileUpload struct {
 }
 
 func postFile(conf *Config, upload FileUpload) string {
+	u, token, ok := postResumable(conf, upload)
+	if !ok {
+		u, token = postWhole(conf, upload)
+	}
+	if token != "" {
+		rememberUpload(conf, u, token)
+	}
+
+	if *flag_inclname {
+		u = path.Join(u, upload.Name)
+	} else if *flag_inclext {
+		u += filepath.Ext(upload.Name)
+	}
+	u = conf.Scheme + "://" + u
+	return u
+}
+
+// postWhole uploads a file in a single request, returning its URL and
+// deletion token.
+func postWhole(conf *Config, upload FileUpload) (string, string) {
 	msg := conf.TryRequest(func() *http.Request {
 		file, err := os.Open(upload.Path)
 		if err != nil {
@@ -367,33 +442,79 @@ func postFile(conf *Config, upload FileUpload) string {
 		}
 		file.Seek(0, os.SEEK_SET)
 
-		var body io.ReadCloser
-
-		// only show progress if the size is bigger than some arbitrary amount
-		// (512KiB) and -P isn't set
-		if sz > 512*1024 && !*flag_noprog {
-			r := newProgressReader(file, sz)
-			//go r.Report()
-			body = r
-		} else {
-			body = file
-		}
-
-		req, err := http.NewRequest("POST", conf.BaseURL("/upload/file"), body)
+		req, err := http.NewRequest("POST", conf.BaseURL("/upload/file"), progressBody(file, 0, sz))
 		if err != nil {
 			fatal(err)
 		}
 
 		req.Header.Set("X-Airlift-Filename", url.QueryEscape(upload.Name))
+		setLimits(req)
 		return req
 	}, http.StatusCreated)
 
-	u := msg.URL
-	if *flag_inclname {
-		u = path.Join(u, upload.Name)
-	} else if *flag_inclext {
-		u += filepath.Ext(upload.Name)
+	noteStripped(strings.Join(msg.Stripped, ", "))
+	return msg.URL, msg.DeleteToken
+}
+
+// noteStripped mentions the metadata that the server removed from an image,
+// if it did.
+func noteStripped(kinds string) {
+	if kinds != "" {
+		fmt.Fprintf(os.Stderr, "(Removed from the image: %s)\n", kinds)
 	}
-	u = conf.Scheme + "://" + u
-	return u
+}
+
+// postPaste posts stdin as a text paste and returns its URL.
+func postPaste(conf *Config) string {
+	text, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fatal("Failed to read stdin:", err)
+	}
+	name := *flag_name
+	if name == "" {
+		name = *flag_stdin
+	}
+	form := url.Values{
+		"text": {string(text)},
+		"lang": {*flag_lang},
+		"name": {name},
+	}
+
+	msg := conf.TryRequest(func() *http.Request {
+		req, err := http.NewRequest("POST", conf.BaseURL("/paste"), strings.NewReader(form.Encode()))
+		if err != nil {
+			fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		setLimits(req)
+		return req
+	}, http.StatusCreated)
+
+	if msg.DeleteToken != "" {
+		rememberUpload(conf, msg.URL, msg.DeleteToken)
+	}
+	return conf.Scheme + "://" + msg.URL
+}
+
+// setLimits adds the per-upload expiry headers requested on the command line.
+func setLimits(req *http.Request) {
+	if *flag_expire > 0 {
+		req.Header.Set("X-Airlift-Expires", flag_expire.String())
+	}
+	if *flag_once {
+		req.Header.Set("X-Airlift-Max-Downloads", "1")
+	}
+}
+
+// progressBody wraps a request body so that it shows a progress bar, but only
+// if the size is bigger than some arbitrary amount (512KiB) and -P isn't set.
+// offset is how much of the total has already been sent.
+func progressBody(r io.ReadCloser, offset, total int64) io.ReadCloser {
+	if total > 512*1024 && !*flag_noprog {
+		p := newProgressReader(r, total)
+		p.current = offset
+		//go p.Report()
+		return p
+	}
+	return r
 }
@@ -58,6 +58,7 @@ type Config struct {
 	Scheme string
 	Host   string
 	Port   string
+	User   string `json:",omitempty"`
 }
 
 const (
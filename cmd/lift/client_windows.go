@@ -24,6 +24,7 @@ type Config struct {
 	Scheme string
 	Host   string
 	Port   string
+	User   string `json:",omitempty"`
 	Pass   []byte
 }
 
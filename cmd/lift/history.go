@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// how many past uploads are remembered
+const maxHistory = 1000
+
+// historyPath is the file that remembers the deletion tokens of past uploads
+// so that they can be deleted without the password.
+func historyPath() string {
+	return dotfilePath + ".history"
+}
+
+// HistoryEntry is a past upload.
+type HistoryEntry struct {
+	Server      string // base URL of the server it was uploaded to
+	ID          string
+	URL         string
+	DeleteToken string
+	Uploaded    time.Time
+}
+
+func loadHistory() []HistoryEntry {
+	var h []HistoryEntry
+	b, err := ioutil.ReadFile(historyPath())
+	if err == nil {
+		json.Unmarshal(b, &h)
+	}
+	return h
+}
+
+func saveHistory(h []HistoryEntry) {
+	if len(h) > maxHistory {
+		h = h[len(h)-maxHistory:]
+	}
+	b, err := json.MarshalIndent(h, "", "    ")
+	if err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(historyPath(), b, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, "(Error saving upload history:", err, ")")
+	}
+}
+
+// uploadID returns the ID of an upload given either the ID itself or one of
+// its URLs, which may have the file name or extension after the ID.
+func uploadID(conf *Config, s string) string {
+	if strings.Contains(s, "/") {
+		if !strings.Contains(s, "://") {
+			s = "http://" + s
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return s
+		}
+		s = strings.TrimPrefix(u.Path, "/")
+
+		// the ID comes right after the path the server is at, if any
+		if i := strings.Index(conf.Host, "/"); i >= 0 {
+			if base := strings.Trim(conf.Host[i:], "/"); base != "" {
+				s = strings.TrimPrefix(s, base+"/")
+			}
+		}
+		if i := strings.Index(s, "/"); i >= 0 {
+			s = s[:i]
+		}
+	}
+	if i := strings.Index(s, "."); i > 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// rememberUpload adds an upload to the history.
+func rememberUpload(conf *Config, u, token string) {
+	h := append(loadHistory(), HistoryEntry{
+		Server:      conf.BaseURL(""),
+		ID:          uploadID(conf, u),
+		URL:         u,
+		DeleteToken: token,
+		Uploaded:    time.Now(),
+	})
+	saveHistory(h)
+}
+
+// deleteToken returns the deletion token of an upload made from here, if it
+// is in the history.
+func deleteToken(conf *Config, id string) string {
+	server := conf.BaseURL("")
+	h := loadHistory()
+	for i := len(h) - 1; i >= 0; i-- {
+		if h[i].Server == server && h[i].ID == id {
+			return h[i].DeleteToken
+		}
+	}
+	return ""
+}
+
+// forgetUpload removes a deleted upload from the history.
+func forgetUpload(conf *Config, id string) {
+	server := conf.BaseURL("")
+	h := loadHistory()
+	n := 0
+	for _, e := range h {
+		if e.Server != server || e.ID != id {
+			h[n] = e
+			n++
+		}
+	}
+	if n < len(h) {
+		saveHistory(h[:n])
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	tusVersion = "1.0.0"
+
+	// files smaller than this are sent in one go
+	resumableThreshold = 4 * 1024 * 1024
+
+	// how many times a dropped upload is resumed before giving up
+	maxRetries = 5
+)
+
+// resumePath is the file that remembers unfinished uploads so that they can be
+// resumed by running lift again.
+func resumePath() string {
+	return dotfilePath + ".resume"
+}
+
+// resumeState is what is remembered about an unfinished upload.
+type resumeState struct {
+	Location    string // upload URL on the server
+	DeleteToken string
+}
+
+// loadResume returns the unfinished uploads, keyed by resumeKey.
+func loadResume() map[string]resumeState {
+	state := make(map[string]resumeState)
+	b, err := ioutil.ReadFile(resumePath())
+	if err == nil {
+		if err = json.Unmarshal(b, &state); err != nil {
+			// written by an older version; start over
+			state = make(map[string]resumeState)
+		}
+	}
+	return state
+}
+
+func saveResume(state map[string]resumeState) {
+	if len(state) == 0 {
+		os.Remove(resumePath())
+		return
+	}
+	b, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(resumePath(), b, 0600); err != nil {
+		fmt.Fprintln(os.Stderr, "(Error saving upload state:", err, ")")
+	}
+}
+
+// resumeKey identifies an upload of a particular version of a file to a
+// particular server.
+func resumeKey(conf *Config, upload FileUpload, fi os.FileInfo) string {
+	abs, err := filepath.Abs(upload.Path)
+	if err != nil {
+		abs = upload.Path
+	}
+	return fmt.Sprintf("%s %s %s %d %d", conf.BaseURL(""), abs, upload.Name, fi.Size(), fi.ModTime().UnixNano())
+}
+
+// postResumable uploads a file using the server's resumable upload endpoint,
+// continuing a previous attempt if there was one. Dropped connections are
+// resumed a few times before giving up. It returns the upload's URL and
+// deletion token, or false if the file is too small to bother or the server
+// doesn't support resumable uploads.
+func postResumable(conf *Config, upload FileUpload) (string, string, bool) {
+	file, err := os.Open(upload.Path)
+	if err != nil {
+		fatal(err)
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		fatal(err)
+	}
+	size := fi.Size()
+	if size < resumableThreshold {
+		return "", "", false
+	}
+
+	state := loadResume()
+	key := resumeKey(conf, upload, fi)
+	loc := state[key].Location
+	token := state[key].DeleteToken
+	var offset int64
+
+	if loc != "" {
+		var u string
+		offset, u, err = tusHead(conf, loc)
+		if err == nil && u != "" {
+			delete(state, key)
+			saveResume(state)
+			return u, token, true
+		}
+		if err != nil {
+			loc = ""
+		} else {
+			fmt.Fprintf(os.Stderr, "Resuming upload of %s at %d bytes\n", upload.Name, offset)
+		}
+	}
+
+	if loc == "" {
+		resp, msg, err := conf.tryRequest(func() *http.Request {
+			req, err := http.NewRequest("POST", conf.BaseURL("/upload/tus"), nil)
+			if err != nil {
+				fatal(err)
+			}
+			req.Header.Set("Tus-Resumable", tusVersion)
+			req.Header.Set("Upload-Length", strconv.FormatInt(size, 10))
+			req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte(upload.Name)))
+			setLimits(req)
+			return req
+		})
+		switch resp.StatusCode {
+		case http.StatusCreated:
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusPreconditionFailed:
+			// an older server
+			return "", "", false
+		default:
+			fmt.Fprintln(os.Stderr, resp.Status)
+			if err != nil {
+				fatal("Invalid server response:", err)
+			}
+			fatal("Server returned error:", msg.Err)
+		}
+		token = resp.Header.Get("X-Airlift-Delete-Token")
+		if u := resp.Header.Get("X-Airlift-URL"); u != "" {
+			noteStripped(resp.Header.Get("X-Airlift-Stripped"))
+			return u, token, true
+		}
+		loc = conf.resolve(resp.Header.Get("Location"))
+		state[key] = resumeState{loc, token}
+		saveResume(state)
+	}
+
+	for retries := 0; ; retries++ {
+		var u string
+		u, err = tusPatch(conf, loc, file, offset, size)
+		if err == nil {
+			delete(state, key)
+			saveResume(state)
+			return u, token, true
+		}
+		if retries == maxRetries {
+			break
+		}
+
+		fmt.Fprintf(os.Stderr, "Upload interrupted (%v), retrying...\n", err)
+		time.Sleep(time.Duration(retries+1) * time.Second)
+		if offset, u, err = tusHead(conf, loc); err != nil {
+			continue
+		}
+		if u != "" {
+			delete(state, key)
+			saveResume(state)
+			return u, token, true
+		}
+	}
+
+	fatal("Giving up on upload (run lift again to resume):", err)
+	return "", "", false
+}
+
+// resolve returns the absolute URL of a location returned by the server.
+func (c *Config) resolve(loc string) string {
+	base, err := url.Parse(c.BaseURL("/"))
+	if err != nil {
+		return loc
+	}
+	ref, err := url.Parse(loc)
+	if err != nil {
+		return loc
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func tusRequest(conf *Config, method, loc string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, loc, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Tus-Resumable", tusVersion)
+	if err = conf.PrepareRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// tusHead asks the server how much of an upload it has received. If the
+// upload has already finished, its URL is returned.
+func tusHead(conf *Config, loc string) (int64, string, error) {
+	req, err := tusRequest(conf, "HEAD", loc, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, "", errors.New(resp.Status)
+	}
+	offset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return offset, resp.Header.Get("X-Airlift-URL"), nil
+}
+
+// tusPatch sends the rest of the file starting at offset, returning the URL of
+// the finished upload.
+func tusPatch(conf *Config, loc string, file *os.File, offset, size int64) (string, error) {
+	body := progressBody(ioutil.NopCloser(io.NewSectionReader(file, offset, size-offset)), offset, size)
+	req, err := tusRequest(conf, "PATCH", loc, body)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size - offset
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		var msg Resp
+		json.NewDecoder(resp.Body).Decode(&msg)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusConflict {
+			fmt.Fprintln(os.Stderr, resp.Status)
+			fatal("Server returned error:", msg.Err)
+		}
+		return "", errors.New(resp.Status)
+	}
+
+	u := resp.Header.Get("X-Airlift-URL")
+	if u == "" {
+		return "", errors.New("server did not finish the upload")
+	}
+	noteStripped(resp.Header.Get("X-Airlift-Stripped"))
+	return u, nil
+}
@@ -1,8 +1,8 @@
 package config
 
 import (
-	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -10,7 +10,6 @@ import (
 	"sync/atomic"
 
 	"ktkr.us/pkg/airlift/shorthash"
-	"ktkr.us/pkg/gas/auth"
 )
 
 var OnSave func(*Config)
@@ -38,8 +37,11 @@ func Init(filePath string) error {
 type Config struct {
 	Host              string `form:"host"`
 	Port              int
-	Password          []byte
-	Salt              []byte
+	SSHPort           int    // 0 to disable the SSH server
+	Password          []byte `json:",omitempty"` // only read to migrate to Users
+	Salt              []byte `json:",omitempty"`
+	Users             []*User
+	Storage           Storage
 	Directory         string `form:"directory"`
 	HashLen           int    `form:"id-size"`
 	MaxAgeEnable      bool   `form:"enable-age-prune"`
@@ -47,21 +49,33 @@ type Config struct {
 	MaxSizeEnable     bool   `form:"enable-size-prune"`
 	Size              int64  `form:"max-size"`     // max total size of uploads in MB
 	AppendExt         bool   `form:"append-ext"`   // append extensions to returned file URLs
-	TwitterCardEnable bool   `form:"twitter-card"` // enable Twitter Card preview for embeddable files
+	PreviewEnable     bool   `form:"link-preview"` // serve link previews to chat and social media crawlers
+	TwitterCardEnable bool   `json:",omitempty"`   // only read to migrate to PreviewEnable
 	TwitterHandle     string `form:"twitter-handle"`
 	SyntaxEnable      bool   `form:"syntax-enable"` // enable syntax highlighting for text files
 	SyntaxTheme       string `form:"syntax-theme"`  // Chroma syntax highlight theme
+	TableRows         int    `form:"table-rows"`    // most rows of a CSV file shown in its preview
+	ImageSizes        string `form:"image-sizes"`   // sizes images can be resized to on request, like "640x0 128x128"
+	StripEnable       bool   `form:"strip-meta"`    // remove EXIF and other metadata from uploaded images
+	ThumbSizeEnable   bool   `form:"thumb-prune"`   // evict the least recently used thumbnails
+	ThumbSize         int64  `form:"thumb-size"`    // max total size of thumbnails in MB
+	ThumbWorkers      int    `form:"thumb-jobs"`    // how many thumbnails are made at once
+	ThumbPixels       int64  `form:"thumb-pixels"`  // max megapixels of images that get thumbnails
+	TrustedProxies    string `form:"proxies"`       // addresses and networks whose X-Forwarded-For is believed, like "127.0.0.1 10.0.0.0/8"
 }
 
-// Secrets satisfies gas.User interface.
-func (c Config) Secrets() (pass, salt []byte, err error) {
-	return c.Password, c.Salt, nil
-}
-
-// Username satisfies the gas.User interface. It returns nothing, as Airlift
-// doesn't use usernames.
-func (c Config) Username() string {
-	return ""
+// Storage selects where the contents of uploads are kept. It can only be
+// changed by editing the config file, and takes effect on restart.
+type Storage struct {
+	Backend   string // "local" (the default) or "s3"
+	Endpoint  string // base URL of the S3-compatible service
+	Region    string
+	Bucket    string
+	Prefix    string // prepended to object keys
+	AccessKey string
+	SecretKey string
+	PathStyle bool // address the bucket in the path, as MinIO expects
+	Redirect  bool // send downloads to signed URLs instead of proxying them
 }
 
 // MaxAge satisfies the cache.Config interface.
@@ -80,9 +94,21 @@ func (c Config) MaxSize() int64 {
 	return 0
 }
 
+// MaxThumbSize returns the most megabytes of thumbnails that are kept, or 0
+// if there is no limit.
+func (c Config) MaxThumbSize() int64 {
+	if c.ThumbSizeEnable {
+		return c.ThumbSize
+	}
+	return 0
+}
+
 // MaxCount satisfies the cache.Config interface.
 func (c Config) MaxCount() int { return 0 }
 
+// StripMetadata satisfies the cache.Config interface.
+func (c Config) StripMetadata() bool { return c.StripEnable }
+
 // Refresh satisfies the cache.Config interface.
 func (c *Config) Refresh() {
 	cc := Get()
@@ -93,31 +119,92 @@ func (c *Config) ProcessHash(buf []byte) string {
 	return shorthash.Make(buf, c.HashLen)
 }
 
-// SetPass updates the config with the new password hash, generating a new
-// random salt.
-func (c *Config) SetPass(pass string) {
-	c.Salt = make([]byte, 32)
-	rand.Read(c.Salt)
-	c.Password = auth.Hash([]byte(pass), c.Salt)
+// migrate moves settings from older versions of the config into their
+// current places.
+func (c *Config) migrate() {
+	if len(c.Users) == 0 && c.Password != nil {
+		c.Users = []*User{{
+			Name:     DefaultUsername,
+			Password: c.Password,
+			Salt:     c.Salt,
+			Admin:    true,
+		}}
+	}
+	c.Password = nil
+	c.Salt = nil
+
+	// Twitter Cards grew into previews for every service that unfurls links
+	if c.TwitterCardEnable {
+		c.PreviewEnable = true
+	}
+	c.TwitterCardEnable = false
+
+	// API keys made before scopes existed could do anything
+	for _, u := range c.Users {
+		for _, t := range u.Tokens {
+			if t.Scopes == nil {
+				t.Scopes = Scopes
+			}
+		}
+	}
 }
 
+// Get returns a copy of the current configuration that is safe to modify.
 func Get() *Config {
 	c := &Config{}
 	*c = *sharedConfig.Load().(*Config)
+	users := make([]*User, len(c.Users))
+	for i, u := range c.Users {
+		users[i] = u.clone()
+	}
+	c.Users = users
 	return c
 }
 
+// updateMu is held while the configuration is being replaced, so that
+// changes made at the same time are made one after the other.
+var updateMu sync.Mutex
+
+// errUnchanged is returned by a function given to Update that didn't change
+// anything, so there is nothing to save.
+var errUnchanged = errors.New("config: unchanged")
+
+// Update applies f to a copy of the current configuration and saves it,
+// unless f returns an error. Updates are made one at a time, each seeing the
+// ones before it, so none of them is lost.
+func Update(f func(*Config) error) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	c := Get()
+	if err := f(c); err == errUnchanged {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return set(c)
+}
+
+// Set replaces the whole configuration with c. Changes to the current one
+// should be made with Update instead.
 func Set(c *Config) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	return set(c)
+}
+
+func set(c *Config) error {
 	sharedConfig.Store(c)
 	return Save(c)
 }
 
 func Reload() error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
 	c, err := Load()
 	if err != nil {
 		return err
 	}
-	Set(c)
+	set(c)
 	return nil
 }
 
@@ -149,6 +236,7 @@ func Load() (*Config, error) {
 			mu.RUnlock()
 			return nil, fmt.Errorf("decoding config: %v", err)
 		}
+		conf.migrate()
 		// save any new defaults in case the config structure changed
 		mu.RUnlock()
 		err = Save(&conf)
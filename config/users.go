@@ -0,0 +1,381 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"ktkr.us/pkg/gas/auth"
+)
+
+// DefaultUsername is the name given to the admin account created from the
+// single password used by older versions.
+const DefaultUsername = "admin"
+
+// TokenPrefix begins every API key so that they can be told apart from
+// passwords.
+const TokenPrefix = "alt_"
+
+var (
+	ErrUserExists   = errors.New("user already exists")
+	ErrUserNotFound = errors.New("no such user")
+	ErrBadUsername  = errors.New("usernames may only contain letters, digits, '-', '_' and '.'")
+	ErrNoScopes     = errors.New("API keys need at least one scope")
+	ErrBadScope     = errors.New("unknown API key scope")
+	ErrKeyInUse     = errors.New("SSH key already belongs to another user")
+)
+
+// User is an account that can log in and upload files.
+type User struct {
+	Name     string
+	Password []byte
+	Salt     []byte
+	Admin    bool
+	Quota    int64 // max total size of the user's uploads in MB, 0 for no limit
+	Tokens   []*Token
+	SSHKeys  []string // public keys in authorized_keys format
+}
+
+// API key scopes.
+const (
+	ScopeUpload = "upload"
+	ScopeDelete = "delete"
+	ScopeList   = "list"
+)
+
+// Scopes lists every scope an API key can be given.
+var Scopes = []string{ScopeUpload, ScopeDelete, ScopeList}
+
+// Token is an API key that can be sent instead of a password. It is limited
+// to the actions in Scopes. Only its hash is kept.
+type Token struct {
+	ID       string
+	Name     string
+	Hash     []byte
+	Scopes   []string
+	Created  time.Time
+	Expires  time.Time // zero if the key doesn't expire
+	LastUsed time.Time
+}
+
+// Allows returns true if the key was given the scope.
+func (t *Token) Allows(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired returns true if the key can no longer be used.
+func (t *Token) Expired() bool {
+	return !t.Expires.IsZero() && time.Now().After(t.Expires)
+}
+
+// Secrets satisfies the gas.User interface.
+func (u *User) Secrets() (pass, salt []byte, err error) {
+	return u.Password, u.Salt, nil
+}
+
+// Username satisfies the gas.User interface.
+func (u *User) Username() string {
+	return u.Name
+}
+
+// SetPass updates the user with the new password hash, generating a new random
+// salt.
+func (u *User) SetPass(pass string) {
+	u.Salt = make([]byte, 32)
+	rand.Read(u.Salt)
+	u.Password = auth.Hash([]byte(pass), u.Salt)
+}
+
+// CheckPass returns true if pass is the user's password.
+func (u *User) CheckPass(pass string) bool {
+	return u.Password != nil && auth.VerifyHash([]byte(pass), u.Password, u.Salt)
+}
+
+// MaxSize returns the user's quota in bytes, or 0 if there is none.
+func (u *User) MaxSize() int64 {
+	return u.Quota * 1024 * 1024
+}
+
+// NewToken generates a new API key for the user with the given name, scopes
+// and expiry time (which may be zero). The returned string is the only copy of
+// the key; it can't be recovered later.
+func (u *User) NewToken(name string, scopes []string, expires time.Time) (*Token, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrNoScopes
+	}
+	for _, s := range scopes {
+		if !validScope(s) {
+			return nil, "", ErrBadScope
+		}
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	secret := TokenPrefix + hex.EncodeToString(buf)
+	// the ID is shown and sent around, so it's no part of the secret
+	var id string
+	for id == "" || u.Token(id) != nil {
+		idBuf := make([]byte, 4)
+		if _, err := rand.Read(idBuf); err != nil {
+			return nil, "", err
+		}
+		id = hex.EncodeToString(idBuf)
+	}
+	t := &Token{
+		ID:      id,
+		Name:    name,
+		Hash:    hashToken(secret),
+		Scopes:  scopes,
+		Created: time.Now(),
+		Expires: expires,
+	}
+	u.Tokens = append(u.Tokens, t)
+	return t, secret, nil
+}
+
+// RevokeToken removes the API key with the given ID, returning false if there
+// was no such key.
+func (u *User) RevokeToken(id string) bool {
+	for i, t := range u.Tokens {
+		if t.ID == id {
+			u.Tokens = append(u.Tokens[:i], u.Tokens[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Token returns the user's API key with the given ID, or nil.
+func (u *User) Token(id string) *Token {
+	for _, t := range u.Tokens {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// SetSSHKeys replaces the user's SSH public keys with the ones in text, which
+// is in authorized_keys format. Blank lines and comments are skipped.
+func (u *User) SetSSHKeys(text string) error {
+	var keys []string
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return fmt.Errorf("SSH key on line %d: %v", i+1, err)
+		}
+		line = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+		if comment != "" {
+			line += " " + comment
+		}
+		keys = append(keys, line)
+	}
+	u.SSHKeys = keys
+	return nil
+}
+
+// HasSSHKey returns true if key is one of the user's SSH public keys.
+func (u *User) HasSSHKey(key ssh.PublicKey) bool {
+	want := key.Marshal()
+	for _, line := range u.SSHKeys {
+		k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err == nil && bytes.Equal(k.Marshal(), want) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) clone() *User {
+	uu := *u
+	uu.SSHKeys = append([]string(nil), u.SSHKeys...)
+	uu.Tokens = make([]*Token, len(u.Tokens))
+	for i, t := range u.Tokens {
+		tt := *t
+		tt.Scopes = append([]string(nil), t.Scopes...)
+		uu.Tokens[i] = &tt
+	}
+	return &uu
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func hashToken(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// ValidUsername returns an error if name can't be used as a username.
+func ValidUsername(name string) error {
+	if name == "" || len(name) > 64 {
+		return ErrBadUsername
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.", r)) {
+			return ErrBadUsername
+		}
+	}
+	return nil
+}
+
+// NeedsSetup returns true if no account has a password yet.
+func (c *Config) NeedsSetup() bool {
+	for _, u := range c.Users {
+		if u.Password != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// User returns the account with the given name, or nil.
+func (c *Config) User(name string) *User {
+	for _, u := range c.Users {
+		if u.Name == name {
+			return u
+		}
+	}
+	return nil
+}
+
+// UserByToken returns the API key matching secret and the account that owns
+// it. Nothing is returned if there is no such key or it has expired.
+func (c *Config) UserByToken(secret string) (*User, *Token) {
+	if !strings.HasPrefix(secret, TokenPrefix) {
+		return nil, nil
+	}
+	hash := hashToken(secret)
+	for _, u := range c.Users {
+		for _, t := range u.Tokens {
+			if subtle.ConstantTimeCompare(t.Hash, hash) == 1 {
+				if t.Expired() {
+					return nil, nil
+				}
+				return u, t
+			}
+		}
+	}
+	return nil, nil
+}
+
+// UserBySSHKey returns the account that the SSH public key belongs to, or nil.
+func (c *Config) UserBySSHKey(key ssh.PublicKey) *User {
+	for _, u := range c.Users {
+		if u.HasSSHKey(key) {
+			return u
+		}
+	}
+	return nil
+}
+
+// SetSSHKeys replaces the named user's SSH public keys with the ones in text,
+// which is in authorized_keys format. A key can only belong to one user.
+func (c *Config) SetSSHKeys(name, text string) error {
+	u := c.User(name)
+	if u == nil {
+		return ErrUserNotFound
+	}
+	nu := u.clone()
+	if err := nu.SetSSHKeys(text); err != nil {
+		return err
+	}
+	for _, other := range c.Users {
+		if other == u {
+			continue
+		}
+		for _, line := range nu.SSHKeys {
+			key, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(line))
+			if other.HasSSHKey(key) {
+				return ErrKeyInUse
+			}
+		}
+	}
+	u.SSHKeys = nu.SSHKeys
+	return nil
+}
+
+// tokenTouchInterval is how often the last use of an API key is written to
+// disk, so that busy keys don't cause a config write on every request.
+const tokenTouchInterval = time.Minute
+
+// TouchToken records that the named user's API key with the given ID was just
+// used.
+func TouchToken(name, id string) error {
+	return Update(func(c *Config) error {
+		u := c.User(name)
+		if u == nil {
+			return ErrUserNotFound
+		}
+		// a key revoked in the meantime stays revoked
+		t := u.Token(id)
+		if t == nil {
+			return errUnchanged
+		}
+		now := time.Now()
+		if now.Sub(t.LastUsed) < tokenTouchInterval {
+			return errUnchanged
+		}
+		t.LastUsed = now
+		return nil
+	})
+}
+
+// AddUser creates a new account.
+func (c *Config) AddUser(name, pass string, admin bool) (*User, error) {
+	if err := ValidUsername(name); err != nil {
+		return nil, err
+	}
+	if c.User(name) != nil {
+		return nil, ErrUserExists
+	}
+	u := &User{Name: name, Admin: admin}
+	u.SetPass(pass)
+	c.Users = append(c.Users, u)
+	return u, nil
+}
+
+// RemoveUser deletes the account with the given name.
+func (c *Config) RemoveUser(name string) error {
+	for i, u := range c.Users {
+		if u.Name == name {
+			c.Users = append(c.Users[:i], c.Users[i+1:]...)
+			return nil
+		}
+	}
+	return ErrUserNotFound
+}
+
+// Admins returns the number of accounts with the admin role.
+func (c *Config) Admins() int {
+	n := 0
+	for _, u := range c.Users {
+		if u.Admin {
+			n++
+		}
+	}
+	return n
+}
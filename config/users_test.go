@@ -0,0 +1,23 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTokenID(t *testing.T) {
+	u := &User{Name: "someone"}
+	for i := 0; i < 50; i++ {
+		tok, secret, err := u.NewToken("key", []string{ScopeUpload}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(secret, tok.ID) {
+			t.Errorf("ID %s is part of the secret %s", tok.ID, secret)
+		}
+		if u.Token(tok.ID) != tok {
+			t.Errorf("ID %s isn't unique", tok.ID)
+		}
+	}
+}
@@ -26,6 +26,12 @@ func makeHeader(filename string) string {
 	return disposition
 }
 
+// Filename returns the value of a Content-Disposition header that sets an
+// alternate filename, for when the header is sent by someone else.
+func Filename(filename string) string {
+	return makeHeader(filename)
+}
+
 // SetFilename adds a Content-Disposition header to the response to set an
 // alternate filename. It probably has proper formatting and non-ASCII
 // character support, as described by RFC 2616 and RFC 5987.
@@ -6,7 +6,7 @@ require (
 	github.com/alecthomas/chroma v0.8.2
 	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/pkg/errors v0.9.1
-	github.com/russross/blackfriday/v2 v2.1.0 // indirect
+	github.com/russross/blackfriday/v2 v2.1.0
 	golang.org/x/crypto v0.0.0-20210317152858-513c2a44f670
 	golang.org/x/image v0.0.0-20210220032944-ac19c3e999fb
 	golang.org/x/sys v0.0.0-20210320140829-1e4c9ba3b0c4 // indirect
@@ -0,0 +1,364 @@
+// Package imgmeta reads and removes the metadata that cameras and image
+// editors embed in JPEG, PNG and WebP files, without decoding the images
+// themselves.
+package imgmeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+// ErrUnsupported is returned for files that aren't JPEG, PNG or WebP images.
+var ErrUnsupported = errors.New("imgmeta: unsupported image format")
+
+var errFormat = errors.New("imgmeta: malformed image")
+
+// The kinds of metadata that Strip reports removing.
+const (
+	EXIF    = "EXIF"
+	GPS     = "GPS" // location, found in EXIF
+	XMP     = "XMP"
+	IPTC    = "IPTC"
+	Comment = "comment"
+	Text    = "text" // PNG text chunks
+)
+
+var (
+	jpegMagic = []byte{0xff, 0xd8, 0xff}
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+
+	exifHeader   = []byte("Exif\x00\x00")
+	xmpHeader    = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpExtHeader = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	iptcHeader   = []byte("Photoshop 3.0\x00")
+	xmpKeyword   = []byte("XML:com.adobe.xmp\x00")
+)
+
+// report is the list of kinds of metadata found, in the order they were.
+type report []string
+
+func (r *report) add(kind string) {
+	for _, k := range *r {
+		if k == kind {
+			return
+		}
+	}
+	*r = append(*r, kind)
+}
+
+// Strip copies the image read from r to w without its metadata, and returns
+// the kinds that were removed. The orientation of a photo is the only EXIF
+// tag that is kept, since without it the photo would be shown the wrong way
+// around. ErrUnsupported is returned without writing anything if the image
+// isn't one that can be stripped.
+func Strip(w io.Writer, r io.Reader) ([]string, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(12)
+	switch {
+	case bytes.HasPrefix(head, jpegMagic):
+		return stripJPEG(w, br)
+	case bytes.HasPrefix(head, pngMagic):
+		return stripPNG(w, br)
+	case len(head) == 12 && string(head[:4]) == "RIFF" && string(head[8:]) == "WEBP":
+		return stripWebP(w, br)
+	}
+	return nil, ErrUnsupported
+}
+
+// exifInfo is what matters about a block of EXIF data.
+type exifInfo struct {
+	orientation int // 1 to 8, or 0 if it isn't given
+	gps         bool
+}
+
+// parseTIFF reads the first IFD of EXIF data, which is stored in the layout
+// of a TIFF file.
+func parseTIFF(r io.ReaderAt) (info exifInfo, ok bool) {
+	var head [8]byte
+	if _, err := r.ReadAt(head[:], 0); err != nil {
+		return info, false
+	}
+	var order binary.ByteOrder
+	switch string(head[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return info, false
+	}
+	if order.Uint16(head[2:]) != 42 {
+		return info, false
+	}
+	off := int64(order.Uint32(head[4:]))
+	var count [2]byte
+	if _, err := r.ReadAt(count[:], off); err != nil {
+		return info, false
+	}
+	// a truncated IFD is read as far as it goes
+	b := make([]byte, 12*int(order.Uint16(count[:])))
+	n, _ := r.ReadAt(b, off+2)
+	for e := 0; e+12 <= n; e += 12 {
+		switch order.Uint16(b[e:]) {
+		case 0x0112: // Orientation
+			if order.Uint16(b[e+2:]) == 3 { // SHORT
+				if o := int(order.Uint16(b[e+8:])); o >= 1 && o <= 8 {
+					info.orientation = o
+				}
+			}
+		case 0x8825: // GPSInfo
+			info.gps = true
+		}
+	}
+	return info, true
+}
+
+// orientationTIFF returns EXIF data with nothing in it but an orientation.
+func orientationTIFF(o int) []byte {
+	return []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // header, IFD at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(o), 0, 0, // Orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+}
+
+// strippedEXIF records what was in a block of EXIF data and returns what
+// should be kept of it, which is nil if the orientation doesn't need keeping.
+func strippedEXIF(rep *report, tiff []byte) []byte {
+	rep.add(EXIF)
+	info, _ := parseTIFF(bytes.NewReader(tiff))
+	if info.gps {
+		rep.add(GPS)
+	}
+	if info.orientation > 1 {
+		return orientationTIFF(info.orientation)
+	}
+	return nil
+}
+
+func stripJPEG(w io.Writer, r *bufio.Reader) ([]string, error) {
+	var rep report
+	soi := make([]byte, 2)
+	if _, err := io.ReadFull(r, soi); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(soi); err != nil {
+		return nil, err
+	}
+
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if c != 0xff {
+			return nil, errFormat
+		}
+		marker, err := r.ReadByte()
+		for err == nil && marker == 0xff { // fill bytes
+			marker, err = r.ReadByte()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// everything from the start of the image data on is left as it is
+		if marker == 0xda || marker == 0xd9 {
+			if _, err := w.Write([]byte{0xff, marker}); err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(w, r); err != nil {
+				return nil, err
+			}
+			return rep, nil
+		}
+
+		var n [2]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(n[:]))
+		if length < 2 {
+			return nil, errFormat
+		}
+		data := make([]byte, length-2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		keep := true
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(data, exifHeader):
+			keep = false
+			if tiff := strippedEXIF(&rep, data[len(exifHeader):]); tiff != nil {
+				data = append(append([]byte{}, exifHeader...), tiff...)
+				keep = true
+			}
+		case marker == 0xe1 && (bytes.HasPrefix(data, xmpHeader) || bytes.HasPrefix(data, xmpExtHeader)):
+			rep.add(XMP)
+			keep = false
+		case marker == 0xed && bytes.HasPrefix(data, iptcHeader):
+			rep.add(IPTC)
+			keep = false
+		case marker == 0xfe:
+			rep.add(Comment)
+			keep = false
+		}
+		if !keep {
+			continue
+		}
+
+		seg := make([]byte, 4, 4+len(data))
+		seg[0], seg[1] = 0xff, marker
+		binary.BigEndian.PutUint16(seg[2:], uint16(len(data)+2))
+		if _, err := w.Write(append(seg, data...)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writePNGChunk writes a PNG chunk with its length and checksum.
+func writePNGChunk(w io.Writer, typ string, data []byte) error {
+	b := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(b, uint32(len(data)))
+	copy(b[4:], typ)
+	b = append(b, data...)
+	var crc [4]byte
+	binary.BigEndian.PutUint32(crc[:], crc32.ChecksumIEEE(b[4:]))
+	_, err := w.Write(append(b, crc[:]...))
+	return err
+}
+
+func stripPNG(w io.Writer, r *bufio.Reader) ([]string, error) {
+	var rep report
+	sig := make([]byte, len(pngMagic))
+	if _, err := io.ReadFull(r, sig); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(sig); err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		length := int64(binary.BigEndian.Uint32(head))
+		typ := string(head[4:])
+
+		switch typ {
+		case "tEXt", "zTXt", "iTXt", "eXIf":
+			// metadata chunks are small enough to hold on to
+			if length > 1<<24 {
+				return nil, errFormat
+			}
+			data := make([]byte, length+4) // and the checksum
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			data = data[:length]
+			switch {
+			case typ == "eXIf":
+				if tiff := strippedEXIF(&rep, data); tiff != nil {
+					if err := writePNGChunk(w, typ, tiff); err != nil {
+						return nil, err
+					}
+				}
+			case typ == "iTXt" && bytes.HasPrefix(data, xmpKeyword):
+				rep.add(XMP)
+			default:
+				rep.add(Text)
+			}
+			continue
+		}
+
+		if _, err := w.Write(head); err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(w, r, length+4); err != nil {
+			return nil, err
+		}
+		// anything after the end is dropped, since it could be anything
+		if typ == "IEND" {
+			return rep, nil
+		}
+	}
+}
+
+// The VP8X chunk's flags for the metadata chunks in a WebP file.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// stripWebP reads the whole file, since the size at its start has to change
+// when chunks are taken out.
+func stripWebP(w io.Writer, r io.Reader) ([]string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(binary.LittleEndian.Uint32(b[4:])) + 8
+	if size > int64(len(b)) {
+		return nil, errFormat
+	}
+	b = b[:size]
+
+	var (
+		rep   report
+		out   = append([]byte{}, b[:12]...)
+		vp8x  = -1 // offset of the VP8X chunk's flags in out
+		flags byte
+	)
+	for p := 12; p < len(b); {
+		if p+8 > len(b) {
+			return nil, errFormat
+		}
+		typ := string(b[p : p+4])
+		length := int(binary.LittleEndian.Uint32(b[p+4:]))
+		end := p + 8 + length + length&1 // chunks are padded to an even size
+		if end > len(b) || end < p {
+			return nil, errFormat
+		}
+		chunk := b[p:end]
+		data := b[p+8 : p+8+length]
+		p = end
+
+		switch typ {
+		case "EXIF":
+			tiff := strippedEXIF(&rep, bytes.TrimPrefix(data, exifHeader))
+			if tiff == nil {
+				continue
+			}
+			flags |= webpFlagEXIF
+			chunk = make([]byte, 8, 8+len(tiff))
+			copy(chunk, "EXIF")
+			binary.LittleEndian.PutUint32(chunk[4:], uint32(len(tiff)))
+			chunk = append(chunk, tiff...)
+		case "XMP ":
+			rep.add(XMP)
+			continue
+		case "VP8X":
+			if length > 0 {
+				vp8x = len(out) + 8
+			}
+		}
+		out = append(out, chunk...)
+	}
+
+	if vp8x >= 0 {
+		out[vp8x] = out[vp8x]&^(webpFlagEXIF|webpFlagXMP) | flags
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	if _, err := w.Write(out); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
@@ -0,0 +1,179 @@
+package imgmeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// secret is put in every kind of metadata, to check none of it is left.
+const secret = "52.3676 N, 4.9041 E"
+
+func TestStrip(t *testing.T) {
+	tiff := exifTIFF(binary.LittleEndian, 6, true)
+	tiff = append(tiff, secret...)
+	files := []struct {
+		format string
+		b      []byte
+		decode func(io.Reader) (image.Image, error)
+		want   []string
+	}{
+		{
+			"jpeg",
+			sampleJPEG(t,
+				jpegSegment(0xe1, append(exifHeader, tiff...)),
+				jpegSegment(0xe1, append(xmpHeader, secret...)),
+				jpegSegment(0xed, append(iptcHeader, secret...)),
+				jpegSegment(0xfe, []byte(secret)),
+			),
+			jpeg.Decode,
+			[]string{EXIF, GPS, XMP, IPTC, Comment},
+		},
+		{
+			"png",
+			samplePNG(t,
+				pngChunk(t, "eXIf", tiff),
+				pngChunk(t, "iTXt", append(xmpKeyword, secret...)),
+				pngChunk(t, "tEXt", []byte("Comment\x00"+secret)),
+			),
+			png.Decode,
+			[]string{EXIF, GPS, XMP, Text},
+		},
+		{
+			"webp",
+			sampleWebP(webpFlagEXIF|webpFlagXMP,
+				webpChunk("EXIF", tiff),
+				webpChunk("XMP ", []byte(secret)),
+			),
+			webp.Decode,
+			[]string{EXIF, GPS, XMP},
+		},
+	}
+	for _, f := range files {
+		var out bytes.Buffer
+		rep, err := Strip(&out, bytes.NewReader(f.b))
+		if err != nil {
+			t.Errorf("%s: %v", f.format, err)
+			continue
+		}
+		if !equal(rep, f.want) {
+			t.Errorf("%s: removed %q, want %q", f.format, rep, f.want)
+		}
+		b := out.Bytes()
+		if _, err := f.decode(bytes.NewReader(b)); err != nil {
+			t.Errorf("%s: decoding the stripped image: %v", f.format, err)
+		}
+		if bytes.Contains(b, []byte(secret)) {
+			t.Errorf("%s: metadata is left in the stripped image", f.format)
+		}
+		if o, err := Orientation(bytes.NewReader(b)); err != nil || o != 6 {
+			t.Errorf("%s: stripped image has orientation %d (%v), want 6", f.format, o, err)
+		}
+
+		// all that's left is the orientation, and it's kept as it is
+		var again bytes.Buffer
+		rep, err = Strip(&again, bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%s: stripping again: %v", f.format, err)
+			continue
+		}
+		if !equal(rep, []string{EXIF}) {
+			t.Errorf("%s: stripping again removed %q, want only EXIF", f.format, rep)
+		}
+		if !bytes.Equal(again.Bytes(), b) {
+			t.Errorf("%s: stripping again changed the image", f.format)
+		}
+		if !bytes.Contains(b, orientationTIFF(6)) {
+			t.Errorf("%s: stripped image doesn't have the orientation on its own", f.format)
+		}
+	}
+}
+
+func TestStripUpright(t *testing.T) {
+	// EXIF with nothing that needs keeping is dropped altogether
+	tiff := exifTIFF(binary.BigEndian, 1, false)
+	files := []struct {
+		format string
+		b      []byte
+		want   []byte
+	}{
+		{"jpeg", sampleJPEG(t, jpegSegment(0xe1, append(exifHeader, tiff...))), sampleJPEG(t)},
+		{"png", samplePNG(t, pngChunk(t, "eXIf", tiff)), samplePNG(t)},
+		{"webp", sampleWebP(webpFlagEXIF, webpChunk("EXIF", tiff)), sampleWebP(0)},
+	}
+	for _, f := range files {
+		var out bytes.Buffer
+		rep, err := Strip(&out, bytes.NewReader(f.b))
+		if err != nil {
+			t.Errorf("%s: %v", f.format, err)
+			continue
+		}
+		if !equal(rep, []string{EXIF}) {
+			t.Errorf("%s: removed %q, want only EXIF", f.format, rep)
+		}
+		if !bytes.Equal(out.Bytes(), f.want) {
+			t.Errorf("%s: stripped image isn't the one without EXIF", f.format)
+		}
+	}
+}
+
+func TestStripNothing(t *testing.T) {
+	files := []struct {
+		format string
+		b      []byte
+	}{
+		{"jpeg", sampleJPEG(t)},
+		{"png", samplePNG(t)},
+		{"webp", sampleWebP(0)},
+	}
+	for _, f := range files {
+		var out bytes.Buffer
+		rep, err := Strip(&out, bytes.NewReader(f.b))
+		if err != nil {
+			t.Errorf("%s: %v", f.format, err)
+			continue
+		}
+		if len(rep) != 0 {
+			t.Errorf("%s: removed %q from an image without metadata", f.format, rep)
+		}
+		if !bytes.Equal(out.Bytes(), f.b) {
+			t.Errorf("%s: image without metadata was changed", f.format)
+		}
+	}
+}
+
+func TestStripTruncated(t *testing.T) {
+	// each is cut off partway through its EXIF data
+	tiff := exifTIFF(binary.LittleEndian, 6, true)
+	files := []struct {
+		format string
+		b      []byte
+	}{
+		{"jpeg", sampleJPEG(t, jpegSegment(0xe1, append(exifHeader, tiff...)))[:2+20]},
+		{"png", samplePNG(t, pngChunk(t, "eXIf", tiff))[:len(pngMagic)+25+20]},
+		{"webp", sampleWebP(webpFlagEXIF, webpChunk("EXIF", tiff))[:len(sampleWebP(0))+20]},
+	}
+	for _, f := range files {
+		if _, err := Strip(io.Discard, bytes.NewReader(f.b)); err == nil {
+			t.Errorf("%s: no error for a truncated image", f.format)
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
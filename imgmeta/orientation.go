@@ -0,0 +1,171 @@
+package imgmeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+var tiffMagic = [][]byte{
+	[]byte("II\x2a\x00"),
+	[]byte("MM\x00\x2a"),
+}
+
+// Orientation returns the EXIF orientation of the JPEG, PNG, TIFF or WebP
+// image read from r, which is 1 if it doesn't have one. The values 2 to 8
+// mean the image has to be flipped or rotated to be shown the right way up,
+// as the EXIF standard lays out. ErrUnsupported is returned for other
+// formats.
+func Orientation(r io.ReadSeeker) (int, error) {
+	var head [12]byte
+	n, err := io.ReadFull(r, head[:])
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var info exifInfo
+	switch b := head[:n]; {
+	case bytes.HasPrefix(b, jpegMagic):
+		info, err = jpegEXIF(r)
+	case bytes.HasPrefix(b, pngMagic):
+		info, err = pngEXIF(r)
+	case bytes.HasPrefix(b, tiffMagic[0]) || bytes.HasPrefix(b, tiffMagic[1]):
+		// a TIFF file is its own EXIF data
+		info, _ = parseTIFF(seekReaderAt{r})
+	case n == 12 && string(b[:4]) == "RIFF" && string(b[8:]) == "WEBP":
+		info, err = webpEXIF(r)
+	default:
+		return 0, ErrUnsupported
+	}
+	if err != nil {
+		return 0, err
+	}
+	if info.orientation == 0 {
+		return 1, nil
+	}
+	return info.orientation, nil
+}
+
+// seekReaderAt reads at an offset by seeking to it first.
+type seekReaderAt struct{ io.ReadSeeker }
+
+func (r seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.ReadSeeker, p)
+}
+
+// skip moves r past n bytes.
+func skip(r io.ReadSeeker, n int64) error {
+	_, err := r.Seek(n, io.SeekCurrent)
+	return err
+}
+
+// jpegEXIF reads the segments before the image data for an EXIF one.
+func jpegEXIF(r io.ReadSeeker) (exifInfo, error) {
+	var info exifInfo
+	if err := skip(r, 2); err != nil { // SOI
+		return info, err
+	}
+	var head [4]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return info, err
+		}
+		if head[0] != 0xff {
+			return info, errFormat
+		}
+		marker := head[1]
+		if marker == 0xda || marker == 0xd9 {
+			return info, nil
+		}
+		length := int64(binary.BigEndian.Uint16(head[2:]))
+		if length < 2 {
+			return info, errFormat
+		}
+		length -= 2
+
+		if marker != 0xe1 || length < int64(len(exifHeader)) {
+			if err := skip(r, length); err != nil {
+				return info, err
+			}
+			continue
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return info, err
+		}
+		if bytes.HasPrefix(data, exifHeader) {
+			info, _ = parseTIFF(bytes.NewReader(data[len(exifHeader):]))
+			return info, nil
+		}
+	}
+}
+
+// pngEXIF reads the chunks before the image data for an eXIf one.
+func pngEXIF(r io.ReadSeeker) (exifInfo, error) {
+	var info exifInfo
+	if err := skip(r, int64(len(pngMagic))); err != nil {
+		return info, err
+	}
+	var head [8]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return info, err
+		}
+		length := int64(binary.BigEndian.Uint32(head[:]))
+		switch string(head[4:]) {
+		case "IDAT", "IEND":
+			return info, nil
+		case "eXIf":
+			if length > 1<<24 {
+				return info, errFormat
+			}
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return info, err
+			}
+			info, _ = parseTIFF(bytes.NewReader(data))
+			return info, nil
+		}
+		if err := skip(r, length+4); err != nil { // and the checksum
+			return info, err
+		}
+	}
+}
+
+// webpEXIF reads the chunks of a WebP file for an EXIF one.
+func webpEXIF(r io.ReadSeeker) (exifInfo, error) {
+	var info exifInfo
+	if err := skip(r, 12); err != nil {
+		return info, err
+	}
+	var head [8]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return info, err
+		}
+		length := int64(binary.LittleEndian.Uint32(head[4:]))
+		if string(head[:4]) == "EXIF" {
+			if length > 1<<24 {
+				return info, errFormat
+			}
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return info, err
+			}
+			info, _ = parseTIFF(bytes.NewReader(bytes.TrimPrefix(data, exifHeader)))
+			return info, nil
+		}
+		if err := skip(r, length+length&1); err != nil {
+			return info, err
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package imgmeta
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// exifTIFF makes EXIF data in the given byte order with an orientation, if
+// it isn't 0, and a GPS tag if gps is set.
+func exifTIFF(order binary.ByteOrder, o int, gps bool) []byte {
+	b := []byte("II*\x00\x00\x00\x00\x00")
+	if order == binary.BigEndian {
+		b = []byte("MM\x00*\x00\x00\x00\x00")
+	}
+	order.PutUint32(b[4:], 8)
+
+	type entry struct{ tag, typ uint16 }
+	var entries []entry
+	if o != 0 {
+		entries = append(entries, entry{0x0112, 3})
+	}
+	if gps {
+		entries = append(entries, entry{0x8825, 4})
+	}
+	b = append(b, 0, 0)
+	order.PutUint16(b[8:], uint16(len(entries)))
+	for _, e := range entries {
+		var field [12]byte
+		order.PutUint16(field[0:], e.tag)
+		order.PutUint16(field[2:], e.typ)
+		order.PutUint32(field[4:], 1)
+		if e.tag == 0x0112 {
+			order.PutUint16(field[8:], uint16(o))
+		}
+		b = append(b, field[:]...)
+	}
+	return append(b, 0, 0, 0, 0) // no next IFD
+}
+
+// sampleImage is a small image with something in it to check it decodes.
+func sampleImage() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+	return img
+}
+
+// jpegSegment makes a JPEG marker segment.
+func jpegSegment(marker byte, data []byte) []byte {
+	seg := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(data)+2))
+	return append(seg, data...)
+}
+
+// sampleJPEG makes a JPEG file with the given segments after its SOI.
+func sampleJPEG(t *testing.T, segs ...[]byte) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sampleImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	out := append([]byte{}, b[:2]...)
+	for _, s := range segs {
+		out = append(out, s...)
+	}
+	return append(out, b[2:]...)
+}
+
+// pngChunk makes a PNG chunk with its length and checksum.
+func pngChunk(t *testing.T, typ string, data []byte) []byte {
+	var buf bytes.Buffer
+	if err := writePNGChunk(&buf, typ, data); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// samplePNG makes a PNG file with the given chunks after its IHDR.
+func samplePNG(t *testing.T, chunks ...[]byte) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sampleImage()); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	ihdrEnd := len(pngMagic) + 8 + 13 + 4
+	out := append([]byte{}, b[:ihdrEnd]...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return append(out, b[ihdrEnd:]...)
+}
+
+// lossless1x1 is the VP8L chunk of a 1×1 lossless WebP image.
+var lossless1x1, _ = base64.StdEncoding.DecodeString("VlA4TA0AAAAvAAAAEAcQERGIiP4HAA==")
+
+// webpChunk makes a WebP chunk, padded to an even size.
+func webpChunk(typ string, data []byte) []byte {
+	c := make([]byte, 8, 9+len(data))
+	copy(c, typ)
+	binary.LittleEndian.PutUint32(c[4:], uint32(len(data)))
+	c = append(c, data...)
+	if len(data)&1 != 0 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+// sampleWebP makes an extended WebP file of a 1×1 image followed by the
+// given chunks, with flags in its VP8X chunk.
+func sampleWebP(flags byte, chunks ...[]byte) []byte {
+	vp8x := make([]byte, 10) // a canvas of 1×1 is stored as 0×0
+	vp8x[0] = flags
+	b := []byte("RIFF\x00\x00\x00\x00WEBP")
+	b = append(b, webpChunk("VP8X", vp8x)...)
+	b = append(b, lossless1x1...)
+	for _, c := range chunks {
+		b = append(b, c...)
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	return b
+}
+
+func TestOrientation(t *testing.T) {
+	orders := []struct {
+		name  string
+		order binary.ByteOrder
+	}{
+		{"II", binary.LittleEndian},
+		{"MM", binary.BigEndian},
+	}
+	for _, bo := range orders {
+		for o := 1; o <= 8; o++ {
+			tiff := exifTIFF(bo.order, o, false)
+			files := []struct {
+				format string
+				b      []byte
+			}{
+				{"jpeg", sampleJPEG(t, jpegSegment(0xe1, append(exifHeader, tiff...)))},
+				{"png", samplePNG(t, pngChunk(t, "eXIf", tiff))},
+				{"webp", sampleWebP(webpFlagEXIF, webpChunk("EXIF", tiff))},
+				{"webp with header", sampleWebP(webpFlagEXIF, webpChunk("EXIF", append(exifHeader, tiff...)))},
+				{"tiff", tiff},
+			}
+			for _, f := range files {
+				got, err := Orientation(bytes.NewReader(f.b))
+				if err != nil {
+					t.Errorf("%s %s %d: %v", f.format, bo.name, o, err)
+				} else if got != o {
+					t.Errorf("%s %s: got orientation %d, want %d", f.format, bo.name, got, o)
+				}
+			}
+		}
+	}
+}
+
+func TestOrientationMissing(t *testing.T) {
+	tiff := exifTIFF(binary.BigEndian, 0, true)
+	files := []struct {
+		format string
+		b      []byte
+	}{
+		{"jpeg", sampleJPEG(t)},
+		{"jpeg without orientation", sampleJPEG(t, jpegSegment(0xe1, append(exifHeader, tiff...)))},
+		{"png", samplePNG(t)},
+		{"png without orientation", samplePNG(t, pngChunk(t, "eXIf", tiff))},
+		{"webp", sampleWebP(0)},
+		{"tiff without orientation", tiff},
+	}
+	for _, f := range files {
+		got, err := Orientation(bytes.NewReader(f.b))
+		if err != nil {
+			t.Errorf("%s: %v", f.format, err)
+		} else if got != 1 {
+			t.Errorf("%s: got orientation %d, want 1", f.format, got)
+		}
+	}
+}
+
+func TestOrientationUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	img := image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black})
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Orientation(bytes.NewReader(buf.Bytes())); err != ErrUnsupported {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,286 @@
+package thumb
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/gif"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// AnimationEncoder is an Encoder that can also encode every frame of an
+// animated GIF. Thumbnails encoded by one keep the animation of the GIF or
+// WebP image they're made from.
+type AnimationEncoder interface {
+	Encoder
+	EncodeAll(dst io.Writer, thumb *gif.GIF) error
+}
+
+// GIFEncoder is an AnimationEncoder that encodes GIF files.
+type GIFEncoder struct{ *gif.Options }
+
+func (GIFEncoder) Extension() string { return ".gif" }
+func (e GIFEncoder) Encode(dst io.Writer, thumb image.Image) error {
+	opt := e.Options
+	if opt == nil {
+		opt = &gif.Options{NumColors: 256, Drawer: draw.FloydSteinberg}
+	}
+	return gif.Encode(dst, thumb, opt)
+}
+func (GIFEncoder) EncodeAll(dst io.Writer, thumb *gif.GIF) error {
+	return gif.EncodeAll(dst, thumb)
+}
+
+// The default limits on the GIFs that animated thumbnails are made from.
+const (
+	defaultMaxFrames = 300
+	defaultMaxPixels = 100 << 20
+)
+
+// LimitAnimations sets the most frames, and pixels in all of the frames
+// together, that an animated image can have for its thumbnails to be
+// animated. Bigger ones get a still thumbnail of their first frame. It must be
+// called before Serve.
+func (c *Cache) LimitAnimations(frames int, pixels int64) {
+	c.maxFrames, c.maxPixels = frames, pixels
+}
+
+var errGIF = errors.New("thumb: malformed gif")
+
+// gifFrames counts the frames in a GIF without decoding them.
+func gifFrames(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+	var head [13]byte // header and logical screen descriptor
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		return 0, err
+	}
+	if err := skipColorTable(br, head[10]); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case 0x21: // extension
+			if _, err := br.ReadByte(); err != nil { // label
+				return 0, err
+			}
+		case 0x2c: // image descriptor
+			var desc [9]byte
+			if _, err := io.ReadFull(br, desc[:]); err != nil {
+				return 0, err
+			}
+			if err := skipColorTable(br, desc[8]); err != nil {
+				return 0, err
+			}
+			if _, err := br.ReadByte(); err != nil { // LZW code size
+				return 0, err
+			}
+			n++
+		case 0x3b: // trailer
+			return n, nil
+		default:
+			return 0, errGIF
+		}
+		if err := skipSubBlocks(br); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func skipColorTable(r *bufio.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	_, err := r.Discard(3 << (flags&7 + 1))
+	return err
+}
+
+func skipSubBlocks(r *bufio.Reader) error {
+	for {
+		n, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if _, err := r.Discard(int(n)); err != nil {
+			return err
+		}
+	}
+}
+
+// Animated returns true if the image with the given id has more than one
+// frame, so that its thumbnails move if they're encoded by an
+// AnimationEncoder. The answer is remembered until it's removed.
+func (c *Cache) Animated(id string) bool {
+	if v, ok := c.animated.Load(id); ok {
+		return v.(bool)
+	}
+	f, err := c.store.Open(id)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var a bool
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(f, head)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	switch Sniff(head[:n]) {
+	case "image/gif":
+		frames, err := gifFrames(f)
+		a = err == nil && frames > 1
+	case "image/webp":
+		anim, err := readWebPAnimation(f, 1)
+		a = err == nil && anim != nil && len(anim.frames) > 1
+	}
+	c.animated.Store(id, a)
+	return a
+}
+
+// decodeAnimation decodes every frame of the GIF or WebP image in f, if its
+// thumbnail of size s should be animated. Otherwise it returns nil. Either
+// way, f is left at its start.
+func (c *Cache) decodeAnimation(f io.ReadSeeker, s size) (*gif.GIF, error) {
+	if _, ok := c.encoders[s.ext].(AnimationEncoder); !ok || s.cover {
+		return nil, nil
+	}
+
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(f, head)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	switch Sniff(head[:n]) {
+	case "image/gif":
+		return c.decodeGIF(f)
+	case "image/webp":
+		return c.decodeWebP(f)
+	}
+	return nil, nil
+}
+
+// decodeGIF decodes every frame of the GIF in f, if it's within the limits.
+func (c *Cache) decodeGIF(f io.ReadSeeker) (*gif.GIF, error) {
+	frames, err := gifFrames(f)
+	if _, serr := f.Seek(0, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+	if err != nil || frames < 2 || frames > c.maxFrames {
+		return nil, nil
+	}
+	conf, err := gif.DecodeConfig(f)
+	if _, serr := f.Seek(0, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+	if err != nil || int64(frames)*int64(conf.Width)*int64(conf.Height) > c.maxPixels {
+		return nil, nil
+	}
+
+	anim, err := gif.DecodeAll(f)
+	if _, serr := f.Seek(0, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+	if err != nil {
+		// the first frame might still be fine
+		return nil, nil
+	}
+	return anim, nil
+}
+
+// decodeWebP decodes every frame of the animated WebP image in f, if it's
+// within the limits.
+func (c *Cache) decodeWebP(f io.ReadSeeker) (*gif.GIF, error) {
+	anim, err := readWebPAnimation(f, c.maxFrames)
+	if _, serr := f.Seek(0, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+	if err != nil || anim == nil || len(anim.frames) < 2 || len(anim.frames) > c.maxFrames {
+		return nil, nil
+	}
+	if int64(len(anim.frames))*int64(anim.canvas.Dx())*int64(anim.canvas.Dy()) > c.maxPixels {
+		return nil, nil
+	}
+
+	out, err := decodeWebPAnimation(f, anim)
+	if _, serr := f.Seek(0, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+	if err != nil {
+		// the first frame might still be fine
+		return nil, nil
+	}
+	return out, nil
+}
+
+// produceAnimation scales every frame of anim to fit inside s, keeping their
+// delays and the way they're disposed of.
+func (c *Cache) produceAnimation(anim *gif.GIF, s size, dst io.Writer) error {
+	enc := c.encoders[s.ext].(AnimationEncoder)
+
+	src := image.Rect(0, 0, anim.Config.Width, anim.Config.Height)
+	if src.Empty() {
+		src = anim.Image[0].Bounds()
+	}
+	bounds := image.Rect(0, 0, s.w, s.h)
+	if s.w == 0 {
+		bounds.Max.X = src.Max.X
+	}
+	if s.h == 0 {
+		bounds.Max.Y = src.Max.Y
+	}
+	if src.In(bounds) {
+		return enc.EncodeAll(dst, anim)
+	}
+	dim := image.Rect(0, 0, s.w, s.h)
+	thumbDimensions(&dim, src)
+
+	// a point on the source's canvas, rounded down or up on the thumbnail's
+	scale := func(p image.Point, up bool) image.Point {
+		x, y := p.X*dim.Dx(), p.Y*dim.Dy()
+		if up {
+			x += src.Dx() - 1
+			y += src.Dy() - 1
+		}
+		return image.Pt(x/src.Dx(), y/src.Dy())
+	}
+
+	out := &gif.GIF{
+		Image:           make([]*image.Paletted, len(anim.Image)),
+		Delay:           anim.Delay,
+		Disposal:        anim.Disposal,
+		LoopCount:       anim.LoopCount,
+		BackgroundIndex: anim.BackgroundIndex,
+		Config:          anim.Config,
+	}
+	out.Config.Width, out.Config.Height = dim.Dx(), dim.Dy()
+
+	for i, frame := range anim.Image {
+		fr := frame.Bounds()
+		r := image.Rectangle{scale(fr.Min, false), scale(fr.Max, true)}
+		if r.Dx() == 0 {
+			r.Max.X++
+		}
+		if r.Dy() == 0 {
+			r.Max.Y++
+		}
+		// scaled through RGBA, so that the colors are blended before
+		// they're matched to the frame's palette again
+		rgba := image.NewRGBA(r)
+		c.scaler.Scale(rgba, r, frame, fr, draw.Src, nil)
+		p := image.NewPaletted(r, frame.Palette)
+		draw.Draw(p, r, rgba, r.Min, draw.Src)
+		out.Image[i] = p
+	}
+	return enc.EncodeAll(dst, out)
+}
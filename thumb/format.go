@@ -0,0 +1,187 @@
+package thumb
+
+import (
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// format is an image format that thumbnails can be made from.
+type format struct {
+	mime   string
+	magic  string // the start of every file, with ? matching any byte
+	exts   []string
+	decode func(io.Reader) (image.Image, error)
+	config func(io.Reader) (image.Config, error)
+}
+
+// formats are sniffed in order, with the same magic strings as the image
+// packages register with image.RegisterFormat.
+var formats = []format{
+	{"image/jpeg", "\xff\xd8", []string{".jpg", ".jpeg"}, jpeg.Decode, jpeg.DecodeConfig},
+	{"image/png", "\x89PNG\r\n\x1a\n", []string{".png"}, png.Decode, png.DecodeConfig},
+	{"image/gif", "GIF8?a", []string{".gif"}, gif.Decode, gif.DecodeConfig},
+	{"image/tiff", "II\x2a\x00", []string{".tif", ".tiff"}, tiff.Decode, tiff.DecodeConfig},
+	{"image/tiff", "MM\x00\x2a", []string{".tif", ".tiff"}, tiff.Decode, tiff.DecodeConfig},
+	{"image/webp", "RIFF????WEBPVP8", []string{".webp"}, decodeWebP, webp.DecodeConfig},
+	{"image/bmp", "BM????\x00\x00\x00\x00", []string{".bmp"}, bmp.Decode, bmp.DecodeConfig},
+}
+
+// sniffLen is how much of the start of a file Sniff sees.
+const sniffLen = 64
+
+// RegisterFormat lets thumbnails be made from images of another format, with
+// the given MIME type and file extensions. Files are recognized as being in
+// it if they start with magic, in which ? matches any byte. Formats that are
+// registered later than another with a matching magic string are never
+// picked. decodeConfig reads the dimensions of an image without decoding it,
+// so that ones over the pixel limit can be turned away. It should be called
+// before any thumbnails are made.
+func RegisterFormat(contentType, magic string, exts []string, decode func(io.Reader) (image.Image, error), decodeConfig func(io.Reader) (image.Config, error)) {
+	if len(magic) > sniffLen {
+		panic("thumb: magic string of " + contentType + " is too long")
+	}
+	if decode == nil || decodeConfig == nil {
+		panic("thumb: format " + contentType + " can't be decoded")
+	}
+	lower := make([]string, len(exts))
+	for i, ext := range exts {
+		lower[i] = strings.ToLower(ext)
+	}
+	formats = append(formats, format{contentType, magic, lower, decode, decodeConfig})
+}
+
+func (f *format) match(head []byte) bool {
+	if len(head) < len(f.magic) {
+		return false
+	}
+	for i := 0; i < len(f.magic); i++ {
+		if f.magic[i] != '?' && f.magic[i] != head[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Sniff returns the MIME type of the image that a file starting with head
+// contains, or an empty string if it's not one that can be thumbnailed.
+func Sniff(head []byte) string {
+	if f := sniffFormat(head); f != nil {
+		return f.mime
+	}
+	return ""
+}
+
+func sniffFormat(head []byte) *format {
+	for i := range formats {
+		if formats[i].match(head) {
+			return &formats[i]
+		}
+	}
+	return nil
+}
+
+// mediaType returns the MIME type without any parameters.
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.TrimSpace(contentType)
+}
+
+// DecodeFunc returns a func that can be used to decode an image of the given
+// MIME type, or nil if it's not supported. The type should be the one the
+// contents were sniffed as, so the file's name is only used to tell its
+// format when the type is empty or the generic application/octet-stream.
+func DecodeFunc(contentType, name string) func(io.Reader) (image.Image, error) {
+	switch contentType = mediaType(contentType); contentType {
+	case "", "application/octet-stream":
+		if f := formatByExt(filepath.Ext(name)); f != nil {
+			return f.decode
+		}
+	default:
+		for _, f := range formats {
+			if f.mime == contentType {
+				return f.decode
+			}
+		}
+	}
+	return nil
+}
+
+// FormatSupported returns true if the given file extension belongs to an image
+// format that can be thumbnailed by this package.
+func FormatSupported(ext string) bool {
+	return formatByExt(ext) != nil
+}
+
+func formatByExt(ext string) *format {
+	ext = strings.ToLower(ext)
+	for i := range formats {
+		for _, e := range formats[i].exts {
+			if e == ext {
+				return &formats[i]
+			}
+		}
+	}
+	return nil
+}
+
+// A Generator makes an image to show as the thumbnail of a file that isn't
+// an image itself, such as the cover art of a song or the first page of a
+// document. It is given the contents of the file and what the FileStore
+// says its name and MIME type are. ErrNoPreview should be returned if the
+// file has nothing to show, so that the next Generator is tried, and
+// ErrTooLarge if the image in it is over the limit that Cache.CheckPixels
+// checks.
+type Generator func(r io.ReadSeeker, name, contentType string) (image.Image, error)
+
+// ErrNoPreview is returned by a Generator that can't make an image for a file.
+var ErrNoPreview = errors.New("thumb: no preview for file")
+
+type generator struct {
+	contentType string
+	gen         Generator
+}
+
+var generators []generator
+
+// RegisterGenerator adds a Generator for the files with the given MIME type.
+// A type that ends in a slash, such as "text/", matches every subtype, and an
+// empty one matches every file. For files that aren't images, the generators
+// are tried in the order they were registered until one makes an image. It
+// should be called before any thumbnails are made.
+func RegisterGenerator(contentType string, gen Generator) {
+	generators = append(generators, generator{contentType, gen})
+}
+
+func (g *generator) match(contentType string) bool {
+	if strings.HasSuffix(g.contentType, "/") {
+		return strings.HasPrefix(contentType, g.contentType)
+	}
+	return g.contentType == "" || g.contentType == contentType
+}
+
+// Supported returns true if a thumbnail can be made for a file with the given
+// MIME type and name, either by decoding it or with a Generator.
+func Supported(contentType, name string) bool {
+	if DecodeFunc(contentType, name) != nil {
+		return true
+	}
+	contentType = mediaType(contentType)
+	for i := range generators {
+		if generators[i].match(contentType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package thumb
+
+import (
+	"container/list"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// touchInterval is how long a thumbnail goes between having its modification
+// time updated when it's used. The times order thumbnails by when they were
+// last used across restarts.
+const touchInterval = time.Hour
+
+// entry is a thumbnail on disk. The Cache keeps them in a list, most recently
+// used first.
+type entry struct {
+	thumbID
+	bytes int64
+	used  time.Time
+}
+
+// addEntry records a thumbnail on disk as the most recently used one, or
+// updates it if it's already there.
+func (c *Cache) addEntry(th thumbID, bytes int64, used time.Time) {
+	set, ok := c.files[th.id]
+	if !ok {
+		set = make(map[size]*list.Element)
+		c.files[th.id] = set
+	}
+	if el, ok := set[th.size]; ok {
+		e := el.Value.(*entry)
+		atomic.AddInt64(&c.size, bytes-e.bytes)
+		e.bytes, e.used = bytes, used
+		c.lru.MoveToFront(el)
+		return
+	}
+	set[th.size] = c.lru.PushFront(&entry{th, bytes, used})
+	atomic.AddInt64(&c.size, bytes)
+	atomic.AddInt64(&c.count, 1)
+}
+
+// touch marks a thumbnail as just used.
+func (c *Cache) touch(el *list.Element) {
+	c.lru.MoveToFront(el)
+	e := el.Value.(*entry)
+	now := time.Now()
+	if now.Sub(e.used) < touchInterval {
+		return
+	}
+	e.used = now
+	path := c.thumbPath(e.thumbID)
+	go os.Chtimes(path, now, now)
+}
+
+// removeEntry deletes a thumbnail from disk and forgets it.
+func (c *Cache) removeEntry(el *list.Element) error {
+	e := el.Value.(*entry)
+	if err := os.Remove(c.thumbPath(e.thumbID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	c.lru.Remove(el)
+	set := c.files[e.id]
+	delete(set, e.size)
+	if len(set) == 0 {
+		delete(c.files, e.id)
+	}
+	atomic.AddInt64(&c.size, -e.bytes)
+	atomic.AddInt64(&c.count, -1)
+	return nil
+}
+
+// evictToLimit removes the least recently used thumbnails until they fit in
+// the size limit. The most recently used one is always kept.
+func (c *Cache) evictToLimit() {
+	max := atomic.LoadInt64(&c.maxSize)
+	if max <= 0 {
+		return
+	}
+	for atomic.LoadInt64(&c.size) > max && c.lru.Len() > 1 {
+		if err := c.removeEntry(c.lru.Back()); err != nil {
+			log.Print("thumb: evicting: ", err)
+			return
+		}
+		atomic.AddInt64(&c.evicted, 1)
+	}
+}
+
+// SetMaxSize limits the total size of the thumbnails to n bytes, evicting the
+// least recently used ones to make room for new ones. Zero means no limit.
+func (c *Cache) SetMaxSize(n int64) {
+	atomic.StoreInt64(&c.maxSize, n)
+	c.limitsChanged()
+}
+
+// Size returns the total size of all of the thumbnails contained in c in bytes.
+func (c *Cache) Size() int64 {
+	return atomic.LoadInt64(&c.size)
+}
+
+// MaxSize returns the limit on the total size of the thumbnails set by
+// SetMaxSize.
+func (c *Cache) MaxSize() int64 {
+	return atomic.LoadInt64(&c.maxSize)
+}
+
+// Len returns the number of thumbnails contained in c.
+func (c *Cache) Len() int {
+	return int(atomic.LoadInt64(&c.count))
+}
+
+// Evicted returns the number of thumbnails that have been removed to keep
+// under the size limit since c was made.
+func (c *Cache) Evicted() int64 {
+	return atomic.LoadInt64(&c.evicted)
+}
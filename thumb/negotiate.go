@@ -0,0 +1,208 @@
+package thumb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// AlphaEncoder is an Encoder that keeps the transparent parts of images.
+// Thumbnails of images with transparency are encoded by one when their
+// format isn't asked for.
+type AlphaEncoder interface {
+	Encoder
+	KeepsAlpha() bool
+}
+
+// format returns the extension of the encoder that a thumbnail of id is made
+// with: the one opt asks for, or else the first of the Cache's encoders that
+// opt.Accept allows, starting with the ones that keep transparency if the
+// image has any, and then the default one.
+func (c *Cache) format(id string, opt Options) string {
+	if opt.Format != "" {
+		return opt.Format
+	}
+	var prefs []string
+	if c.transparent(id) {
+		for _, ext := range c.exts {
+			if enc, ok := c.encoders[ext].(AlphaEncoder); ok && enc.KeepsAlpha() {
+				prefs = append(prefs, ext)
+			}
+		}
+	}
+	prefs = append(prefs, c.enc.Extension())
+	if opt.Accept == "" {
+		return prefs[0]
+	}
+	for _, ext := range append(prefs, c.exts...) {
+		if accepts(opt.Accept, extType(ext)) {
+			return ext
+		}
+	}
+	// an image the client didn't ask for is better than none
+	return prefs[0]
+}
+
+// transparent returns true if the image with the given id might have
+// transparent parts. The answer is remembered until it's removed.
+func (c *Cache) transparent(id string) bool {
+	if v, ok := c.alpha.Load(id); ok {
+		return v.(bool)
+	}
+	f, err := c.store.Open(id)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	a := hasAlpha(f)
+	c.alpha.Store(id, a)
+	return a
+}
+
+// extType returns the MIME type of the files with an extension.
+func extType(ext string) string {
+	if f := formatByExt(ext); f != nil {
+		return f.mime
+	}
+	return mediaType(mime.TypeByExtension(ext))
+}
+
+// accepts returns true if the Accept header of a request allows a response
+// of the given MIME type.
+func accepts(accept, contentType string) bool {
+	slash := strings.IndexByte(contentType, '/')
+	if slash < 0 {
+		return false
+	}
+	// the most specific media range that matches decides
+	best, q := -1, 0.0
+	for _, r := range strings.Split(accept, ",") {
+		params := strings.Split(r, ";")
+		t := strings.ToLower(strings.TrimSpace(params[0]))
+		var spec int
+		switch {
+		case t == contentType:
+			spec = 2
+		case t == contentType[:slash+1]+"*":
+			spec = 1
+		case t == "*/*":
+			spec = 0
+		default:
+			continue
+		}
+		if spec <= best {
+			continue
+		}
+		best, q = spec, 1
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+	}
+	return q > 0
+}
+
+// hasAlpha reads enough of the header of the image in r to tell whether it
+// might have transparent parts.
+func hasAlpha(r io.Reader) bool {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(sniffLen)
+	switch Sniff(head) {
+	case "image/png":
+		return pngAlpha(br)
+	case "image/gif":
+		return gifAlpha(br)
+	case "image/webp":
+		return webpAlpha(head)
+	}
+	return false
+}
+
+// pngAlpha looks for an alpha channel or a transparency chunk.
+func pngAlpha(r *bufio.Reader) bool {
+	if _, err := r.Discard(8); err != nil {
+		return false
+	}
+	var head [8]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return false
+		}
+		length := int(binary.BigEndian.Uint32(head[:]))
+		switch string(head[4:]) {
+		case "IHDR":
+			var ihdr [13]byte
+			if _, err := io.ReadFull(r, ihdr[:]); err != nil {
+				return false
+			}
+			if t := ihdr[9]; t == 4 || t == 6 { // gray or color with alpha
+				return true
+			}
+			length -= len(ihdr)
+		case "tRNS":
+			return true
+		case "IDAT", "IEND":
+			return false
+		}
+		if length < 0 {
+			return false
+		}
+		if _, err := r.Discard(length + 4); err != nil {
+			return false
+		}
+	}
+}
+
+// gifAlpha looks for a transparent color for the first frame.
+func gifAlpha(r *bufio.Reader) bool {
+	var head [13]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return false
+	}
+	if err := skipColorTable(r, head[10]); err != nil {
+		return false
+	}
+	for {
+		c, err := r.ReadByte()
+		if err != nil || c != 0x21 { // anything but an extension
+			return false
+		}
+		label, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		if label == 0xf9 { // graphic control
+			gce, err := r.Peek(2)
+			if err != nil {
+				return false
+			}
+			if gce[0] >= 1 && gce[1]&1 != 0 {
+				return true
+			}
+		}
+		if err := skipSubBlocks(r); err != nil {
+			return false
+		}
+	}
+}
+
+// webpAlpha reads the alpha flag of an extended or lossless WebP file.
+func webpAlpha(head []byte) bool {
+	if len(head) < 25 {
+		return false
+	}
+	switch string(head[12:16]) {
+	case "VP8X":
+		return head[20]&0x10 != 0
+	case "VP8L":
+		return binary.LittleEndian.Uint32(head[21:])>>28&1 != 0
+	}
+	return false
+}
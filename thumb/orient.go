@@ -0,0 +1,72 @@
+package thumb
+
+import (
+	"image"
+	"io"
+
+	"golang.org/x/image/draw"
+
+	"ktkr.us/pkg/airlift/imgmeta"
+)
+
+// orientation returns the EXIF orientation of the image in f, or 1 if it
+// doesn't have one, and leaves f at its start.
+func orientation(f io.ReadSeeker) int {
+	o, err := imgmeta.Orientation(f)
+	if _, serr := f.Seek(0, io.SeekStart); err == nil {
+		err = serr
+	}
+	if err != nil {
+		return 1
+	}
+	return o
+}
+
+// orient flips and rotates img the way EXIF orientation o says it has to be
+// to be shown the right way up.
+func orient(img image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src, ok := img.(*image.NRGBA)
+	if !ok {
+		src = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(src, src.Rect, img, b.Min, draw.Src)
+	}
+	w, h := b.Dx(), b.Dy()
+
+	// orientations 5 to 8 swap the sides
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch o {
+			case 2: // flipped horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // flipped vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // rotated 90° clockwise to be shown
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotated 90° counterclockwise to be shown
+				sx, sy = w-1-y, x
+			}
+			si := src.PixOffset(src.Rect.Min.X+sx, src.Rect.Min.Y+sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
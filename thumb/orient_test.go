@@ -0,0 +1,85 @@
+package thumb
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// layout draws img as rows of letters, one for each pixel, by its red value.
+func layout(img image.Image) []string {
+	b := img.Bounds()
+	var rows []string
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := ""
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			row += string(rune('A' + r>>8))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestOrient(t *testing.T) {
+	// ABC
+	// DEF
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.SetNRGBA(i%3, i/3, color.NRGBA{uint8(i), 0, 0, 255})
+	}
+
+	tests := []struct {
+		o    int
+		want []string
+	}{
+		{0, []string{"ABC", "DEF"}},
+		{1, []string{"ABC", "DEF"}},
+		{2, []string{"CBA", "FED"}},
+		{3, []string{"FED", "CBA"}},
+		{4, []string{"DEF", "ABC"}},
+		{5, []string{"AD", "BE", "CF"}},
+		{6, []string{"DA", "EB", "FC"}},
+		{7, []string{"FC", "EB", "DA"}},
+		{8, []string{"CF", "BE", "AD"}},
+		{9, []string{"ABC", "DEF"}},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.o)
+		w, h := 3, 2
+		if tt.o >= 5 && tt.o <= 8 {
+			w, h = 2, 3
+		}
+		if b := got.Bounds(); b.Dx() != w || b.Dy() != h {
+			t.Errorf("orientation %d: bounds are %v, want %dx%d", tt.o, b, w, h)
+			continue
+		}
+		if l := layout(got); !equal(l, tt.want) {
+			t.Errorf("orientation %d: got %q, want %q", tt.o, l, tt.want)
+		}
+	}
+}
+
+func TestOrientConverts(t *testing.T) {
+	// images that aren't NRGBA, or don't start at the origin, are copied first
+	src := image.NewGray(image.Rect(10, 20, 13, 22))
+	for i := 0; i < 6; i++ {
+		src.SetGray(10+i%3, 20+i/3, color.Gray{uint8(i)})
+	}
+	got := orient(src, 6)
+	if l, want := layout(got), []string{"DA", "EB", "FC"}; !equal(l, want) {
+		t.Errorf("got %q, want %q", l, want)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
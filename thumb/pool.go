@@ -0,0 +1,109 @@
+package thumb
+
+import (
+	"io"
+	"runtime"
+	"sync/atomic"
+)
+
+// defaultMaxDecode is the most pixels in an image that is decoded, unless
+// SetMaxDecode changes it. Decoded, that many take up 200 MB.
+const defaultMaxDecode = 50 * 1000 * 1000
+
+// SetWorkers sets how many thumbnails are made at once. The rest wait their
+// turn. Less than one means one for each CPU.
+func (c *Cache) SetWorkers(n int) {
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	atomic.StoreInt64(&c.workers, int64(n))
+	c.limitsChanged()
+}
+
+// SetMaxDecode sets the most pixels that an image can have for a thumbnail
+// to be made of it. Thumbnails of bigger images fail with ErrTooLarge, which
+// is found out from their headers without decoding them. Zero means no
+// limit.
+func (c *Cache) SetMaxDecode(pixels int64) {
+	atomic.StoreInt64(&c.pixels, pixels)
+}
+
+// Working returns the number of thumbnails being made.
+func (c *Cache) Working() int {
+	return int(atomic.LoadInt64(&c.working))
+}
+
+// Queued returns the number of thumbnails waiting for a worker to make them.
+func (c *Cache) Queued() int {
+	return int(atomic.LoadInt64(&c.queued))
+}
+
+// limitsChanged tells Serve to apply the limits again.
+func (c *Cache) limitsChanged() {
+	select {
+	case c.limits <- struct{}{}:
+	default:
+	}
+}
+
+// start makes a thumbnail if there's a free worker, or else queues it.
+func (c *Cache) start(th thumbID) {
+	if atomic.LoadInt64(&c.working) < atomic.LoadInt64(&c.workers) {
+		atomic.AddInt64(&c.working, 1)
+		go c.getThumb(th)
+		return
+	}
+	c.pending = append(c.pending, th)
+	atomic.StoreInt64(&c.queued, int64(len(c.pending)))
+}
+
+// startQueued starts making the thumbnails that have waited longest, while
+// there are free workers. The ones nobody is waiting for anymore are
+// dropped.
+func (c *Cache) startQueued() {
+	for len(c.pending) > 0 && atomic.LoadInt64(&c.working) < atomic.LoadInt64(&c.workers) {
+		th := c.pending[0]
+		c.pending[0] = thumbID{}
+		c.pending = c.pending[1:]
+		if len(c.inflight[th]) == 0 {
+			delete(c.inflight, th)
+			continue
+		}
+		atomic.AddInt64(&c.working, 1)
+		go c.getThumb(th)
+	}
+	atomic.StoreInt64(&c.queued, int64(len(c.pending)))
+}
+
+// CheckPixels returns ErrTooLarge if the image in f has more pixels than the
+// limit set by SetMaxDecode, reading only its header. Files that aren't in a
+// format Sniff knows pass, for the generators to deal with, but an error is
+// returned for ones that are whose header can't be read. Either way, f is
+// left at its start. Generators that decode images found inside files should
+// check them with it first.
+func (c *Cache) CheckPixels(f io.ReadSeeker) error {
+	max := atomic.LoadInt64(&c.pixels)
+	if max <= 0 {
+		return nil
+	}
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(f, head)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	format := sniffFormat(head[:n])
+	if format == nil {
+		return nil
+	}
+	cfg, err := format.config(f)
+	if _, serr := f.Seek(0, io.SeekStart); serr != nil {
+		return serr
+	}
+	if err != nil {
+		return err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > max {
+		return ErrTooLarge
+	}
+	return nil
+}
@@ -1,27 +1,30 @@
 // Package thumb implements a lazy image thumbnail cache. Supported input image
 // formats are any format Go can decode natively from the standard library and
-// subrepo golang.org/x/image.
+// subrepo golang.org/x/image, which are told apart by their contents.
 package thumb
 
 import (
+	"container/list"
+	"context"
 	"errors"
 	"fmt"
 	"image"
-	"image/gif"
+	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"golang.org/x/image/bmp"
 	"golang.org/x/image/draw"
-	"golang.org/x/image/tiff"
-	"golang.org/x/image/webp"
 )
 
 // Encoder describes a way to encode a thumbnail image.
@@ -30,57 +33,201 @@ type Encoder interface {
 	Encode(dst io.Writer, thumb image.Image) error
 }
 
-// JPEGEncoder is an Encoder that encodes JPEG files.
+// JPEGEncoder is an Encoder that encodes JPEG files. Transparent parts of
+// images are made white, since JPEG can't keep them.
 type JPEGEncoder struct{ *jpeg.Options }
 
 func (JPEGEncoder) Extension() string { return ".jpg" }
 func (e JPEGEncoder) Encode(dst io.Writer, thumb image.Image) error {
+	if o, ok := thumb.(interface{ Opaque() bool }); !ok || !o.Opaque() {
+		b := thumb.Bounds()
+		flat := image.NewRGBA(b)
+		draw.Draw(flat, b, image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, b, thumb, b.Min, draw.Over)
+		thumb = flat
+	}
 	return jpeg.Encode(dst, thumb, e.Options)
 }
 
-// FileStore is a source of files that Cache will reference.
+// PNGEncoder is an AlphaEncoder that encodes PNG files.
+type PNGEncoder struct {
+	*png.Encoder
+	// Paletted encodes images that have no more than 256 colors with a
+	// palette, which makes smaller files without losing anything.
+	Paletted bool
+}
+
+func (PNGEncoder) Extension() string { return ".png" }
+func (PNGEncoder) KeepsAlpha() bool  { return true }
+func (e PNGEncoder) Encode(dst io.Writer, thumb image.Image) error {
+	if e.Paletted {
+		if p := paletted(thumb); p != nil {
+			thumb = p
+		}
+	}
+	if e.Encoder == nil {
+		return png.Encode(dst, thumb)
+	}
+	return e.Encoder.Encode(dst, thumb)
+}
+
+// paletted returns img with a palette of the colors in it, or nil if it has
+// more than 256 of them.
+func paletted(img image.Image) *image.Paletted {
+	if p, ok := img.(*image.Paletted); ok {
+		return p
+	}
+	b := img.Bounds()
+	p := image.NewPaletted(b, nil)
+	index := make(map[color.NRGBA]uint8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i, ok := index[c]
+			if !ok {
+				if len(p.Palette) == 256 {
+					return nil
+				}
+				i = uint8(len(p.Palette))
+				index[c] = i
+				p.Palette = append(p.Palette, c)
+			}
+			p.SetColorIndex(x, y, i)
+		}
+	}
+	return p
+}
+
+// FileStore is a source of files that Cache will reference. The contents of a
+// file must never change once stored, since thumbnails are never regenerated
+// for the same ID. An ID may contain slashes to group files under another ID,
+// which are removed along with it.
 type FileStore interface {
-	// Get should return the path to the file on disk, or the empty string if
-	// not found.
-	Get(id string) string
+	// Open should open the contents of the file for reading. If there is no
+	// file with the id, the error should be ErrSourceMissing or one that
+	// os.IsNotExist reports.
+	Open(id string) (io.ReadSeekCloser, error)
+	// Name should return the original name of the file, which tells its
+	// format apart if its contents don't.
+	Name(id string) string
+	// ContentType should return the MIME type of the file, or an empty
+	// string if it isn't known. It picks the Generator for files that
+	// aren't images.
+	ContentType(id string) string
 }
 
+// size is a variant of a thumbnail: its bounds, how the image is fit into
+// them, and the extension of the encoder it is stored with.
 type size struct {
-	w, h int
+	w, h  int
+	cover bool
+	ext   string
 }
 
 func (s size) String() string {
-	return fmt.Sprintf("%dx%d", s.w, s.h)
+	if s.cover {
+		return fmt.Sprintf("%dx%d cover %s", s.w, s.h, s.ext)
+	}
+	return fmt.Sprintf("%dx%d %s", s.w, s.h, s.ext)
+}
+
+// Options are ways of making a thumbnail other than the default.
+type Options struct {
+	// Cover scales and crops the image to fill the whole size, instead of
+	// fitting it inside.
+	Cover bool
+	// Format is the extension of the encoder to use, which must have been
+	// added with AddEncoder. Empty means the one the Cache was made with, or
+	// an AlphaEncoder for images with transparency.
+	Format string
+	// Accept is the Accept header of the request the thumbnail is for. If
+	// Format is empty, it rules out the encoders that the client can't
+	// show.
+	Accept string
 }
 
-type set map[size]struct{}
+type set map[size]*list.Element
 
 type thumbID struct {
 	id string
 	size
 }
 
+var (
+	// ErrUnsupported is returned for files that no thumbnail can be made of.
+	ErrUnsupported = errors.New("thumb: unsupported format")
+	// ErrSourceMissing is returned when the FileStore has no file with the
+	// id that a thumbnail was asked for.
+	ErrSourceMissing = errors.New("thumb: source file is missing")
+	// ErrBadOptions is returned for sizes that can't be made, and formats
+	// that have no encoder.
+	ErrBadOptions = errors.New("thumb: invalid size or format")
+	// ErrTooLarge is returned for images with more pixels than the limit
+	// set by SetMaxDecode, which aren't decoded.
+	ErrTooLarge = errors.New("thumb: image has too many pixels")
+)
+
+// DecodeError is returned when a file is in a supported format but its
+// thumbnail couldn't be made, such as when it's corrupt.
+type DecodeError struct {
+	ID  string
+	Err error
+}
+
+func (e *DecodeError) Error() string { return "thumb: decoding " + e.ID + ": " + e.Err.Error() }
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+type result struct {
+	path string
+	err  error
+}
+
 type request struct {
 	thumbID
-	ch chan string
+	ch chan result // buffered, so sending never waits for the requester
+}
+
+type finished struct {
+	thumbID
+	result
+	bytes int64
 }
 
 // Cache is a lazy, concurrent thumbnail cache for airlift-server with request
 // batching for on-the-fly thumbnail generation. Only file paths are cached in
 // memory.
 type Cache struct {
-	size     int64  // the total size of the thumbnails
+	// accessed atomically, and first so they're aligned on 32-bit platforms
+	size    int64 // the total size of the thumbnails
+	maxSize int64 // the most size before thumbnails are evicted, or 0
+	count   int64 // the number of thumbnails
+	evicted int64 // the number of thumbnails evicted
+	workers int64 // the most thumbnails made at once
+	working int64 // the number of thumbnails being made
+	queued  int64 // the number of thumbnails waiting for a worker
+	pixels  int64 // the most pixels in an image that is decoded, or 0
+
 	dir      string // path of directory where thumbnails are stored
 	enc      Encoder
+	encoders map[string]Encoder // by extension
+	exts     []string           // of the encoders, in the order they were added
+	alpha    sync.Map           // ID → whether the image has transparency
+	animated sync.Map           // ID → whether the image has several frames
 	store    FileStore
 	files    map[string]set
-	req      chan *request    // ID
+	lru      *list.List // of *entry, most recently used first
+	req      chan *request
+	detach   chan *request    // the requester stopped waiting
 	remove   chan string      // send ID, or empty string to purge all
-	resp     chan interface{} // file path
-	add      chan thumbID
-	inflight map[thumbID][]chan string
-	done     chan thumbID
+	resp     chan interface{} // error
+	limits   chan struct{}    // a limit changed
+	inflight map[thumbID][]*request
+	pending  []thumbID // waiting for a worker, oldest first
+	done     chan finished
 	scaler   draw.Scaler
+
+	maxFrames int   // most frames of an animated thumbnail
+	maxPixels int64 // most pixels in all the frames of its GIF
 }
 
 // NewCache initializes a new thumbnail generator that stores files encoded
@@ -89,32 +236,45 @@ func NewCache(dirPath string, enc Encoder, store FileStore, scaler draw.Scaler)
 	c := &Cache{
 		dir:      dirPath,
 		enc:      enc,
+		encoders: map[string]Encoder{enc.Extension(): enc},
+		exts:     []string{enc.Extension()},
 		store:    store,
 		files:    make(map[string]set),
+		lru:      list.New(),
 		req:      make(chan *request, 5),
+		detach:   make(chan *request),
 		remove:   make(chan string),
 		resp:     make(chan interface{}),
-		add:      make(chan thumbID, 5),
-		inflight: make(map[thumbID][]chan string),
-		done:     make(chan thumbID, 5),
+		limits:   make(chan struct{}, 1),
+		inflight: make(map[thumbID][]*request),
+		done:     make(chan finished, 5),
 		scaler:   scaler,
+
+		maxFrames: defaultMaxFrames,
+		maxPixels: defaultMaxPixels,
+
+		workers: int64(runtime.NumCPU()),
+		pixels:  defaultMaxDecode,
 	}
 
 	os.MkdirAll(dirPath, 0755)
 
 	log.Print("thumb: loading cached thumbs...")
-	i := 0
+	var found []entry
 	filepath.Walk(dirPath, func(path string, fi os.FileInfo, err error) error {
-		if fi.IsDir() {
+		if err != nil || fi.IsDir() {
 			return nil
 		}
-		c.size += fi.Size()
 
-		// format of filename: <path>_<width>_<height>.<ext>
+		// format of filename: <path>_<width>_<height>[_c].<ext>
 		// chop off common prefix
 		relpath, _ := filepath.Rel(dirPath, path)
-		//
-		relpathMinusExt := relpath[:len(relpath)-len(filepath.Ext(relpath))]
+		ext := filepath.Ext(relpath)
+		relpathMinusExt := relpath[:len(relpath)-len(ext)]
+		cover := strings.HasSuffix(relpathMinusExt, "_c")
+		if cover {
+			relpathMinusExt = strings.TrimSuffix(relpathMinusExt, "_c")
+		}
 		j := 0
 
 		// locate sizes by second to last
@@ -140,14 +300,23 @@ func NewCache(dirPath string, enc Encoder, store FileStore, scaler draw.Scaler)
 			os.Remove(path)
 			return nil
 		}
+		s.cover = cover
+		s.ext = ext
 
 		id := relpathMinusExt[:sizesPos]
-		c.addSize(id, s)
-		i++
+		found = append(found, entry{thumbID{id, s}, fi.Size(), fi.ModTime()})
 		return nil
 	})
 
-	log.Printf("thumb: loaded %d cached thumbnails", i)
+	// the modification times are when the thumbnails were last used
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].used.Before(found[j].used)
+	})
+	for _, e := range found {
+		c.addEntry(e.thumbID, e.bytes, e.used)
+	}
+
+	log.Printf("thumb: loaded %d cached thumbnails", len(found))
 
 	return c, nil
 }
@@ -165,16 +334,7 @@ func parseSize(s string) (size, error) {
 	if err != nil {
 		return size{}, err
 	}
-	return size{w, h}, nil
-}
-
-func (c *Cache) addSize(id string, s size) {
-	set, ok := c.files[id]
-	if !ok {
-		set = make(map[size]struct{})
-	}
-	set[s] = struct{}{}
-	c.files[id] = set
+	return size{w: w, h: h}, nil
 }
 
 // Serve starts the cache request server, blocking forever. It should be
@@ -185,140 +345,230 @@ func (c *Cache) Serve() {
 		case req := <-c.req:
 			// check if a thumb of the requested size is already there
 			if dims, ok := c.files[req.id]; ok {
-				if _, ok := dims[req.size]; ok {
-					// freshen thumb on request if original file changed
-					origPath := c.store.Get(req.id)
-					thumbPath := c.thumbPath(req.thumbID)
-
+				if el, ok := dims[req.size]; ok {
 					// does thumb file exist
-					thumbFi, err := os.Stat(thumbPath)
-					if err != nil {
+					thumbPath := c.thumbPath(req.thumbID)
+					if _, err := os.Stat(thumbPath); err != nil {
 						log.Print(err)
 					} else {
-						// does the original file exist
-						origFi, err := os.Stat(origPath)
-						if err != nil {
-							log.Print(err)
-						} else {
-							// is the original file newer than the thumb file
-							if origFi.ModTime().After(thumbFi.ModTime()) {
-								log.Printf("thumb: original file %q is fresher than thumb at %s", origPath, req.size)
-							} else {
-								// serve existing thumb if already fresh
-								//*path = c.thumbPath(req)
-								req.ch <- c.thumbPath(req.thumbID)
-								break
-							}
-						}
+						c.touch(el)
+						req.ch <- result{path: thumbPath}
+						break
 					}
 				}
 			}
 
-			c.inflight[req.thumbID] = append(c.inflight[req.thumbID], req.ch)
 			// if there is a request happening on this already, simply add a reciever
 			// to the list and let them wait for it
-			if len(c.inflight[req.thumbID]) > 1 {
-				return
+			if reqs, ok := c.inflight[req.thumbID]; ok {
+				c.inflight[req.thumbID] = append(reqs, req)
+				break
 			}
+			c.inflight[req.thumbID] = []*request{req}
+
+			c.start(req.thumbID)
 
-			go c.getThumb(req.thumbID)
+		case req := <-c.detach:
+			// the thumbnail is still made for the ones that come later
+			reqs := c.inflight[req.thumbID]
+			for i, r := range reqs {
+				if r == req {
+					c.inflight[req.thumbID] = append(reqs[:i:i], reqs[i+1:]...)
+					break
+				}
+			}
 
 		case id := <-c.remove:
 			if id == "" {
 				c.resp <- c.doPurge()
 			} else {
-				c.resp <- c.doRemove(id)
+				c.resp <- c.doRemoveTree(id)
 			}
 
-		case th := <-c.add:
-			c.addSize(th.id, th.size)
+		case f := <-c.done:
+			atomic.AddInt64(&c.working, -1)
+			for _, req := range c.inflight[f.thumbID] {
+				req.ch <- f.result
+			}
+			delete(c.inflight, f.thumbID)
+			if f.err == nil {
+				c.addEntry(f.thumbID, f.bytes, time.Now())
+				c.evictToLimit()
+			}
+			c.startQueued()
 
-		case th := <-c.done:
-			delete(c.inflight, th)
+		case <-c.limits:
+			c.evictToLimit()
+			c.startQueued()
 		}
 	}
 }
 
-// Size returns the total size of all of the thumbnails contained in c in bytes.
-func (c *Cache) Size() int64 {
-	return c.size
-}
-
 func (c *Cache) thumbPath(th thumbID) string {
 	basename := fmt.Sprintf("%s_%d_%d", th.id, th.w, th.h)
-	return filepath.Join(c.dir, basename) + c.enc.Extension()
+	if th.cover {
+		basename += "_c"
+	}
+	return filepath.Join(c.dir, basename) + th.ext
+}
+
+// AddEncoder lets thumbnails be asked for in another format, by the
+// extension of enc. It must be called before Serve.
+func (c *Cache) AddEncoder(enc Encoder) {
+	if c.encoders[enc.Extension()] == nil {
+		c.exts = append(c.exts, enc.Extension())
+	}
+	c.encoders[enc.Extension()] = enc
 }
 
 // Get returns the file path to the thumbnail of the file with the given id,
 // generating it if it doesn't exist already. If concurrent requests are made
 // to the same non-existent thumbnail, it will only be generated once.
 //
-// TODO: error handling
-func (c *Cache) Get(id string, w, h int) string {
-	ch := make(chan string, 1)
-	c.req <- &request{thumbID{id, size{w, h}}, ch}
-	return <-ch
+// The error is ErrUnsupported, ErrSourceMissing, ErrTooLarge or a *DecodeError
+// if the thumbnail can't be made of the file, or ctx.Err() if ctx is done
+// first. The thumbnail is still made for other requests if ctx is canceled.
+func (c *Cache) Get(ctx context.Context, id string, w, h int) (string, error) {
+	return c.Transform(ctx, id, w, h, Options{})
 }
 
-func (c *Cache) getThumb(th thumbID) {
-	path := new(string)
+// Transform is like Get, but makes the thumbnail as opt says. A bound of zero
+// leaves that dimension free, unless opt.Cover is set.
+func (c *Cache) Transform(ctx context.Context, id string, w, h int, opt Options) (string, error) {
+	if w < 0 || h < 0 || w == 0 && h == 0 || opt.Cover && (w == 0 || h == 0) {
+		return "", ErrBadOptions
+	}
+	ext := c.format(id, opt)
+	if c.encoders[ext] == nil {
+		return "", ErrBadOptions
+	}
+	req := &request{thumbID{id, size{w, h, opt.Cover, ext}}, make(chan result, 1)}
+	select {
+	case c.req <- req:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	select {
+	case r := <-req.ch:
+		return r.path, r.err
+	case <-ctx.Done():
+		c.detach <- req
+		return "", ctx.Err()
+	}
+}
 
-	// once the work is done, send to all the receivers
-	defer func() {
-		for _, ch := range c.inflight[th] {
-			ch <- *path
-		}
-		//delete(c.inflight, req.id)
-		c.done <- th
-	}()
+// getThumb makes a thumbnail and sends it to the receivers waiting for it.
+func (c *Cache) getThumb(th thumbID) {
+	f := finished{thumbID: th}
+	f.path, f.bytes, f.err = c.makeThumb(th)
+	c.done <- f
+}
 
-	src := c.store.Get(th.id)
-	decoder := DecodeFunc(src)
-	if decoder == nil {
-		return
+// makeThumb writes a thumbnail to its path, returning the path and its size.
+func (c *Cache) makeThumb(th thumbID) (string, int64, error) {
+	f, err := c.store.Open(th.id)
+	if err == ErrSourceMissing || os.IsNotExist(err) {
+		return "", 0, ErrSourceMissing
+	} else if err != nil {
+		return "", 0, err
 	}
+	defer f.Close()
 
-	// generate thumb
-
-	f, err := os.Open(src)
+	if err := c.CheckPixels(f); err == ErrTooLarge {
+		return "", 0, err
+	} else if err != nil {
+		return "", 0, &DecodeError{th.id, err}
+	}
+	anim, err := c.decodeAnimation(f, th.size)
 	if err != nil {
-		log.Print("getThumb: ", err)
-		return
+		return "", 0, &DecodeError{th.id, err}
+	}
+	var img image.Image
+	if anim == nil {
+		if img, err = c.makeImage(f, th.id); err != nil {
+			return "", 0, err
+		}
 	}
-	defer f.Close()
 
 	p := c.thumbPath(th)
 	os.MkdirAll(filepath.Dir(p), 0755)
 	dst, err := os.Create(p)
 	if err != nil {
-		log.Print("getThumb: ", err)
-		return
+		return "", 0, err
 	}
 	defer dst.Close()
 
-	img, err := decoder(f)
-	if err != nil {
-		log.Print("getThumb: ", err)
-		return
+	if anim != nil {
+		err = c.produceAnimation(anim, th.size, dst)
+	} else {
+		err = c.produceThumbnail(img, th.size, dst)
 	}
-
-	if err = c.produceThumbnail(img, th.w, th.h, dst); err != nil {
+	if err != nil {
 		os.Remove(p)
-		log.Print("getThumb: ", err)
-		return
+		return "", 0, err
 	}
 
 	fi, err := dst.Stat()
 	if err != nil {
 		os.Remove(p)
-		log.Print("getThumb: ", err)
-		return
+		return "", 0, err
+	}
+
+	return p, fi.Size(), nil
+}
+
+// makeImage decodes the image in f, or if it isn't one, makes an image to
+// show for it with the registered generators. A file is taken to be an image
+// if its contents, or failing that the FileStore's MIME type for it, say so,
+// and one that doesn't decode never gets a generated image instead. The error
+// is ErrUnsupported if no generator makes one, ErrTooLarge if the one a
+// generator found is over the limit, or a *DecodeError if it couldn't be made.
+func (c *Cache) makeImage(f io.ReadSeeker, id string) (image.Image, error) {
+	// the contents tell the format, whatever the file is named
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(f, head)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
 	}
+	name := c.store.Name(id)
+	contentType := mediaType(c.store.ContentType(id))
 
-	c.size += fi.Size()
+	decode := DecodeFunc(Sniff(head[:n]), name)
+	if decode == nil {
+		decode = DecodeFunc(contentType, name)
+	}
+	if decode != nil {
+		o := orientation(f)
+		img, err := decode(f)
+		if err != nil {
+			return nil, &DecodeError{id, err}
+		}
+		return orient(img, o), nil
+	}
 
-	c.add <- th
-	*path = p
+	err := ErrNoPreview
+	for _, g := range generators {
+		if !g.match(contentType) {
+			continue
+		}
+		img, gerr := g.gen(f, name, contentType)
+		if gerr == nil {
+			return img, nil
+		}
+		if gerr == ErrTooLarge {
+			return nil, gerr
+		}
+		if err == ErrNoPreview {
+			err = gerr
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	if err == ErrNoPreview {
+		return nil, ErrUnsupported
+	}
+	return nil, &DecodeError{id, err}
 }
 
 // Purge removes all thumbnails from c.
@@ -332,6 +582,14 @@ func (c *Cache) Purge() error {
 }
 
 func (c *Cache) doPurge() error {
+	c.alpha.Range(func(id, _ interface{}) bool {
+		c.alpha.Delete(id)
+		return true
+	})
+	c.animated.Range(func(id, _ interface{}) bool {
+		c.animated.Delete(id)
+		return true
+	})
 	for id := range c.files {
 		if err := c.doRemove(id); err != nil {
 			return err
@@ -341,7 +599,8 @@ func (c *Cache) doPurge() error {
 	return nil
 }
 
-// Remove deletes all sizes of thumbnail of a given file.
+// Remove deletes all sizes of thumbnail of a given file and the files grouped
+// under it.
 func (c *Cache) Remove(id string) error {
 	c.remove <- id
 	err := <-c.resp
@@ -351,88 +610,94 @@ func (c *Cache) Remove(id string) error {
 	return nil
 }
 
+func (c *Cache) doRemoveTree(id string) error {
+	prefix := id + "/"
+	for other := range c.files {
+		if strings.HasPrefix(other, prefix) {
+			if err := c.doRemove(other); err != nil {
+				return err
+			}
+		}
+	}
+	// the directory the grouped files were in, if it's empty now
+	os.Remove(filepath.Join(c.dir, id))
+	return c.doRemove(id)
+}
+
 func (c *Cache) doRemove(id string) error {
+	c.alpha.Delete(id)
+	c.animated.Delete(id)
 	set, ok := c.files[id]
 	if !ok {
 		return nil
 	}
 
-	for s := range set {
-		path := c.thumbPath(thumbID{id, s})
-		fi, err := os.Stat(path)
-		if err != nil {
-			delete(c.files, id)
-			if os.IsNotExist(err) {
-				return nil
-			}
-			return err
-		}
-		size := fi.Size()
-		if err = os.Remove(path); err != nil {
+	for _, el := range set {
+		if err := c.removeEntry(el); err != nil {
 			return err
 		}
-		c.size -= size
 	}
 
-	delete(c.files, id)
-
 	return nil
 }
 
-var decodeFuncMap = map[string]func(io.Reader) (image.Image, error){
-	".jpg":  jpeg.Decode,
-	".jpeg": jpeg.Decode,
-	".png":  png.Decode,
-	".gif":  gif.Decode,
-	".tif":  tiff.Decode,
-	".tiff": tiff.Decode,
-	".webp": webp.Decode,
-	".bmp":  bmp.Decode,
-}
-
-// DecodeFunc returns a func that can be used to decode the image with the
-// given file name, or nil if it's not supported.
-// TODO: sniff magic number instead of only using file extension
-// TODO: allow externally registered format decoders
-func DecodeFunc(name string) func(io.Reader) (image.Image, error) {
-	ext := strings.ToLower(filepath.Ext(name))
-	return decodeFuncMap[ext]
-}
-
-// FormatSupported returns true if the given file extension belongs to an image
-// format that can be thumbnailed by this package.
-func FormatSupported(ext string) bool {
-	ext = strings.ToLower(ext)
-	for supportedExt := range decodeFuncMap {
-		if ext == supportedExt {
-			return true
-		}
-	}
-	return false
-}
-
 var thumbPool sync.Pool
 
+// thumbDimensions shrinks dst to the size of src scaled to fit inside it. A
+// side of dst with a length of zero doesn't bound it.
 func thumbDimensions(dst *image.Rectangle, src image.Rectangle) {
-	if src.Dx() > src.Dy() {
-		dst.Max.Y = dst.Min.Y + src.Dy()*dst.Dx()/src.Dx()
+	w, h := dst.Dx(), dst.Dy()
+	if h == 0 || w > 0 && src.Dx()*h > src.Dy()*w {
+		dst.Max.Y = dst.Min.Y + src.Dy()*w/src.Dx()
 	} else {
-		dst.Max.X = dst.Min.X + src.Dx()*dst.Dy()/src.Dy()
+		dst.Max.X = dst.Min.X + src.Dx()*h/src.Dy()
+	}
+}
+
+// coverRect returns the biggest part of src, centered, that has the same
+// aspect ratio as dst.
+func coverRect(src, dst image.Rectangle) image.Rectangle {
+	w, h := src.Dx(), src.Dy()
+	if w*dst.Dy() > h*dst.Dx() {
+		cw := h * dst.Dx() / dst.Dy()
+		x := src.Min.X + (w-cw)/2
+		return image.Rect(x, src.Min.Y, x+cw, src.Max.Y)
 	}
+	ch := w * dst.Dy() / dst.Dx()
+	y := src.Min.Y + (h-ch)/2
+	return image.Rect(src.Min.X, y, src.Max.X, y+ch)
 }
 
-func (c *Cache) produceThumbnail(src image.Image, w, h int, dst *os.File) error {
+func (c *Cache) produceThumbnail(src image.Image, s size, dst *os.File) error {
 	var (
-		dim   = image.Rect(0, 0, w, h)
-		thumb *image.NRGBA
-		ok    bool
+		dim     = image.Rect(0, 0, s.w, s.h)
+		srcRect = src.Bounds()
+		thumb   *image.NRGBA
+		ok      bool
 	)
 
-	if src.Bounds().In(dim) {
-		return c.enc.Encode(dst, src)
+	enc := c.encoders[s.ext]
+	if enc == nil {
+		return fmt.Errorf("no encoder for %s", s.ext)
 	}
 
-	thumbDimensions(&dim, src.Bounds())
+	if s.cover {
+		// the part of the image that's left after cropping is stretched
+		// over the whole size, even if that means enlarging it
+		srcRect = coverRect(srcRect, dim)
+	} else {
+		bounds := dim
+		if bounds.Dx() == 0 {
+			bounds.Max.X = srcRect.Max.X
+		}
+		if bounds.Dy() == 0 {
+			bounds.Max.Y = srcRect.Max.Y
+		}
+		if srcRect.In(bounds) {
+			return enc.Encode(dst, src)
+		}
+		thumbDimensions(&dim, srcRect)
+	}
 
 	item := thumbPool.Get()
 	if item != nil {
@@ -458,7 +723,7 @@ func (c *Cache) produceThumbnail(src image.Image, w, h int, dst *os.File) error
 	}
 	defer thumbPool.Put(thumb)
 
-	c.scaler.Scale(thumb, dim, src, src.Bounds(), draw.Src, nil)
-	err := c.enc.Encode(dst, thumb.SubImage(dim))
+	c.scaler.Scale(thumb, dim, src, srcRect, draw.Src, nil)
+	err := enc.Encode(dst, thumb.SubImage(dim))
 	return err
 }
@@ -0,0 +1,161 @@
+package thumb
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+)
+
+type memFile struct{ *bytes.Reader }
+
+func (memFile) Close() error { return nil }
+
+// memStore is a FileStore of files in memory, by ID.
+type memStore map[string]struct {
+	name, contentType string
+	b                 []byte
+}
+
+func (s memStore) Open(id string) (io.ReadSeekCloser, error) {
+	f, ok := s[id]
+	if !ok {
+		return nil, ErrSourceMissing
+	}
+	return memFile{bytes.NewReader(f.b)}, nil
+}
+
+func (s memStore) Name(id string) string        { return s[id].name }
+func (s memStore) ContentType(id string) string { return s[id].contentType }
+
+func TestMakeImageGenerators(t *testing.T) {
+	var generated []string
+	placeholder := image.NewGray(image.Rect(0, 0, 1, 1))
+	RegisterGenerator("", func(r io.ReadSeeker, name, contentType string) (image.Image, error) {
+		generated = append(generated, name)
+		switch contentType {
+		case "application/x-empty":
+			return nil, ErrNoPreview
+		case "audio/x-huge":
+			return nil, ErrTooLarge
+		}
+		return placeholder, nil
+	})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	good := buf.Bytes()
+	corrupt := append(append([]byte{}, good[:20]...), bytes.Repeat([]byte{0}, 40)...)
+	garbage := bytes.Repeat([]byte("garbage "), 16)
+
+	c := &Cache{store: memStore{
+		"good":      {"a.png", "image/png", good},
+		"corrupt":   {"a.png", "image/png", corrupt},
+		"misnamed":  {"a.txt", "image/png", corrupt},
+		"named":     {"a.png", "application/octet-stream", garbage},
+		"typed":     {"a.dat", "image/bmp", garbage},
+		"document":  {"a.txt", "text/plain", garbage},
+		"empty":     {"a", "application/x-empty", nil},
+		"huge":      {"a.mp3", "audio/x-huge", garbage},
+		"truncated": {"a.png", "image/png", good[:len(good)-20]},
+	}}
+
+	tests := []struct {
+		id        string
+		generated bool
+		err       error
+	}{
+		{"good", false, nil},
+		{"corrupt", false, &DecodeError{}},
+		{"misnamed", false, &DecodeError{}},
+		{"named", false, &DecodeError{}},
+		{"typed", false, &DecodeError{}},
+		{"truncated", false, &DecodeError{}},
+		{"document", true, nil},
+		{"empty", false, ErrUnsupported},
+		{"huge", false, ErrTooLarge},
+	}
+	for _, tt := range tests {
+		generated = nil
+		f, _ := c.store.Open(tt.id)
+		img, err := c.makeImage(f, tt.id)
+
+		var decodeErr *DecodeError
+		switch tt.err.(type) {
+		case nil:
+			if err != nil {
+				t.Errorf("%s: %v", tt.id, err)
+			}
+		case *DecodeError:
+			if !errors.As(err, &decodeErr) || decodeErr.ID != tt.id {
+				t.Errorf("%s: got %v, want a DecodeError", tt.id, err)
+			}
+		default:
+			if err != tt.err {
+				t.Errorf("%s: got %v, want %v", tt.id, err, tt.err)
+			}
+		}
+		if tt.generated != (img == placeholder) {
+			t.Errorf("%s: generated is %v, want %v", tt.id, img == placeholder, tt.generated)
+		}
+		if !tt.generated && tt.err != ErrUnsupported && tt.err != ErrTooLarge && len(generated) != 0 {
+			t.Errorf("%s: a generator was tried", tt.id)
+		}
+	}
+}
+
+func TestCheckPixels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 40, 25))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		max int64
+		err error
+	}{
+		{0, nil},
+		{1000, nil},
+		{999, ErrTooLarge},
+	}
+	for _, tt := range tests {
+		c := &Cache{pixels: tt.max}
+		f := bytes.NewReader(buf.Bytes())
+		if err := c.CheckPixels(f); err != tt.err {
+			t.Errorf("limit of %d: got %v, want %v", tt.max, err, tt.err)
+		}
+		if f.Len() != buf.Len() {
+			t.Errorf("limit of %d: left at %d", tt.max, buf.Len()-f.Len())
+		}
+	}
+
+	// what isn't an image is for the generators to look at
+	c := &Cache{pixels: 1}
+	if err := c.CheckPixels(bytes.NewReader([]byte("ID3 and so on"))); err != nil {
+		t.Errorf("file that isn't an image: %v", err)
+	}
+
+	// but an image whose size can't be told doesn't pass
+	if err := c.CheckPixels(bytes.NewReader(buf.Bytes()[:20])); err == nil {
+		t.Error("truncated image passed")
+	}
+}
+
+func TestCheckPixelsRegistered(t *testing.T) {
+	defer func(f []format) { formats = f }(formats)
+	RegisterFormat("image/x-test", "XTEST", []string{".xt"},
+		func(io.Reader) (image.Image, error) { return nil, errors.New("not decoded") },
+		func(io.Reader) (image.Config, error) { return image.Config{Width: 100, Height: 100}, nil },
+	)
+	c := &Cache{pixels: 9999}
+	if err := c.CheckPixels(bytes.NewReader([]byte("XTEST and so on"))); err != ErrTooLarge {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+	c.pixels = 10000
+	if err := c.CheckPixels(bytes.NewReader([]byte("XTEST and so on"))); err != nil {
+		t.Errorf("image within the limit: %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package thumb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// An animated WebP file is a canvas that its frames are drawn on in turn.
+// Each frame is either blended with what's already there or replaces it, and
+// is either left for the next frame or cleared once it has been shown. The
+// webp package only decodes still images, so the frames are taken out of the
+// file one at a time and decoded as images of their own.
+
+// webpFlagAnimation is the flag in the VP8X chunk of animated files.
+const webpFlagAnimation = 0x02
+
+var errWebP = errors.New("thumb: malformed webp")
+
+// webpFrame is where a frame of an animated WebP file goes and what's done
+// with it.
+type webpFrame struct {
+	bounds   image.Rectangle // on the canvas
+	duration int             // in milliseconds
+	blend    bool            // drawn over the canvas instead of replacing it
+	dispose  bool            // cleared to transparent once it has been shown
+	off, n   int64           // where the frame's image chunks are in the file
+}
+
+// webpAnimation is the layout of an animated WebP file.
+type webpAnimation struct {
+	canvas image.Rectangle
+	loops  int // 0 means forever
+	frames []webpFrame
+}
+
+func le24(b []byte) int { return int(b[0]) | int(b[1])<<8 | int(b[2])<<16 }
+
+func putLE24(b []byte, n int) { b[0], b[1], b[2] = byte(n), byte(n>>8), byte(n>>16) }
+
+// readWebPAnimation finds the frames of the animated WebP file that r is at
+// the start of, without decoding them. It returns nil if the file isn't
+// animated, and stops looking once it has found more than max frames.
+func readWebPAnimation(r io.ReadSeeker, max int) (*webpAnimation, error) {
+	base, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	var head [12]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	if string(head[:4]) != "RIFF" || string(head[8:]) != "WEBP" {
+		return nil, errWebP
+	}
+	end := int64(binary.LittleEndian.Uint32(head[4:])) + 8
+
+	var anim *webpAnimation
+	for pos := int64(len(head)); pos+8 <= end; {
+		var ch [8]byte
+		if _, err := io.ReadFull(r, ch[:]); err != nil {
+			return nil, err
+		}
+		typ := string(ch[:4])
+		length := int64(binary.LittleEndian.Uint32(ch[4:]))
+		next := pos + 8 + length + length&1 // chunks are padded to an even size
+
+		// the extended header always comes first
+		if anim == nil && typ != "VP8X" {
+			return nil, nil
+		}
+		var need int64 // how much of the chunk is read
+		switch typ {
+		case "VP8X":
+			need = 10
+		case "ANIM":
+			need = 6
+		case "ANMF":
+			need = 16
+		}
+		if length < need {
+			return nil, errWebP
+		}
+		data := make([]byte, need)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "VP8X":
+			if anim != nil {
+				return nil, errWebP
+			}
+			if data[0]&webpFlagAnimation == 0 {
+				return nil, nil
+			}
+			anim = &webpAnimation{canvas: image.Rect(0, 0, le24(data[4:])+1, le24(data[7:])+1)}
+		case "ANIM":
+			anim.loops = int(binary.LittleEndian.Uint16(data[4:]))
+		case "ANMF":
+			x, y := 2*le24(data), 2*le24(data[3:])
+			f := webpFrame{
+				bounds:   image.Rect(x, y, x+le24(data[6:])+1, y+le24(data[9:])+1),
+				duration: le24(data[12:]),
+				blend:    data[15]&0x02 == 0,
+				dispose:  data[15]&0x01 != 0,
+				off:      base + pos + 8 + need,
+				n:        length - need,
+			}
+			// the canvas is what the pixel limit was checked against
+			if !f.bounds.In(anim.canvas) {
+				return nil, errWebP
+			}
+			anim.frames = append(anim.frames, f)
+			if len(anim.frames) > max {
+				return anim, nil
+			}
+		}
+		if _, err := r.Seek(base+next, io.SeekStart); err != nil {
+			return nil, err
+		}
+		pos = next
+	}
+	if anim == nil || len(anim.frames) == 0 {
+		return nil, errWebP
+	}
+	return anim, nil
+}
+
+// decodeWebPFrame decodes the image of a frame of an animated WebP file, by
+// wrapping its chunks up as a still WebP file.
+func decodeWebPFrame(r io.ReadSeeker, f webpFrame) (image.Image, error) {
+	if _, err := r.Seek(f.off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, f.n))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != f.n {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := []byte("RIFF\x00\x00\x00\x00WEBP")
+	if bytes.HasPrefix(data, []byte("ALPH")) {
+		// the alpha channel of a lossy frame needs the extended header
+		vp8x := []byte("VP8X\x0a\x00\x00\x00\x10\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+		putLE24(vp8x[12:], f.bounds.Dx()-1)
+		putLE24(vp8x[15:], f.bounds.Dy()-1)
+		b = append(b, vp8x...)
+	}
+	b = append(b, data...)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	conf, err := webp.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if conf.Width != f.bounds.Dx() || conf.Height != f.bounds.Dy() {
+		return nil, errWebP
+	}
+	return webp.Decode(bytes.NewReader(b))
+}
+
+// drawWebPFrame draws a frame on the canvas the way the file says to.
+func drawWebPFrame(canvas *image.RGBA, r io.ReadSeeker, f webpFrame) error {
+	img, err := decodeWebPFrame(r, f)
+	if err != nil {
+		return err
+	}
+	op := draw.Over
+	if !f.blend {
+		op = draw.Src
+	}
+	draw.Draw(canvas, f.bounds.Intersect(canvas.Rect), img, img.Bounds().Min, op)
+	return nil
+}
+
+// decodeWebP decodes a WebP image, or the first frame of an animated one.
+// Animated ones can only be decoded from an io.ReadSeeker.
+func decodeWebP(r io.Reader) (image.Image, error) {
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		return webp.Decode(r)
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	anim, err := readWebPAnimation(rs, 1)
+	if _, serr := rs.Seek(start, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+	if err != nil || anim == nil {
+		return webp.Decode(rs)
+	}
+	canvas := image.NewRGBA(anim.canvas)
+	if err := drawWebPFrame(canvas, rs, anim.frames[0]); err != nil {
+		return nil, err
+	}
+	return canvas, nil
+}
+
+// decodeWebPAnimation decodes every frame of anim into a GIF that shows the
+// whole canvas at each of them.
+func decodeWebPAnimation(r io.ReadSeeker, anim *webpAnimation) (*gif.GIF, error) {
+	out := &gif.GIF{
+		Image:    make([]*image.Paletted, len(anim.frames)),
+		Delay:    make([]int, len(anim.frames)),
+		Disposal: make([]byte, len(anim.frames)),
+		Config:   image.Config{Width: anim.canvas.Dx(), Height: anim.canvas.Dy()},
+	}
+	switch anim.loops {
+	case 0:
+		out.LoopCount = 0 // forever
+	case 1:
+		out.LoopCount = -1
+	default:
+		out.LoopCount = anim.loops - 1
+	}
+
+	canvas := image.NewRGBA(anim.canvas)
+	for i, f := range anim.frames {
+		if err := drawWebPFrame(canvas, r, f); err != nil {
+			return nil, err
+		}
+		out.Image[i] = gifFrame(canvas)
+		out.Delay[i] = (f.duration + 5) / 10
+		// every frame is the whole canvas, so none shows through another
+		out.Disposal[i] = gif.DisposalBackground
+		if f.dispose {
+			draw.Draw(canvas, f.bounds, image.Transparent, image.Point{}, draw.Src)
+		}
+	}
+	return out, nil
+}
+
+// gifFrame copies the canvas into a paletted image. A GIF's pixels are either
+// transparent or opaque, so the ones that are less than half opaque become
+// transparent and the rest lose their transparency.
+func gifFrame(canvas *image.RGBA) *image.Paletted {
+	b := canvas.Bounds()
+	img := image.NewNRGBA(b)
+	opaque := true
+	for i := 0; i < len(canvas.Pix); i += 4 {
+		a := canvas.Pix[i+3]
+		if a < 0x80 {
+			opaque = false
+			continue
+		}
+		for j := 0; j < 3; j++ {
+			img.Pix[i+j] = uint8(int(canvas.Pix[i+j]) * 0xff / int(a))
+		}
+		img.Pix[i+3] = 0xff
+	}
+	if p := paletted(img); p != nil {
+		return p
+	}
+
+	pal := color.Palette(palette.Plan9)
+	if !opaque {
+		pal = append(color.Palette{color.Transparent}, palette.WebSafe...)
+	}
+	p := image.NewPaletted(b, pal)
+	draw.FloydSteinberg.Draw(p, b, img, b.Min)
+	return p
+}
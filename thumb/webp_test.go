@@ -0,0 +1,271 @@
+package thumb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// solidVP8L makes the VP8L chunk of a 1×1 image of the color c, encoded the
+// simplest way there is: with a prefix code of one symbol for each channel,
+// so that the pixel itself takes no bits.
+func solidVP8L(c color.NRGBA) []byte {
+	var (
+		b []byte
+		n uint // bits written
+	)
+	put := func(v uint32, width uint) {
+		for i := uint(0); i < width; i++ {
+			if n%8 == 0 {
+				b = append(b, 0)
+			}
+			b[n/8] |= byte(v>>i&1) << (n % 8)
+			n++
+		}
+	}
+	put(0x2f, 8) // signature
+	put(0, 14)   // width - 1
+	put(0, 14)   // height - 1
+	put(1, 1)    // alpha is used
+	put(0, 3)    // version
+	put(0, 1)    // no transforms
+	put(0, 1)    // no color cache
+	put(0, 1)    // no meta prefix codes
+	for _, v := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		put(1, 1) // simple code
+		put(0, 1) // of one symbol
+		put(1, 1) // of 8 bits
+		put(uint32(v), 8)
+	}
+	return webpChunk("VP8L", b)
+}
+
+// pixel is the VP8L chunk of a 1×1 opaque image.
+var pixel = solidVP8L(color.NRGBA{0xff, 0x80, 0, 0xff})
+
+// webpChunk makes a WebP chunk, padded to an even size.
+func webpChunk(typ string, data []byte) []byte {
+	c := make([]byte, 8, 9+len(data))
+	copy(c, typ)
+	binary.LittleEndian.PutUint32(c[4:], uint32(len(data)))
+	c = append(c, data...)
+	if len(data)&1 != 0 {
+		c = append(c, 0)
+	}
+	return c
+}
+
+// webpFile makes a WebP file of the given chunks.
+func webpFile(chunks ...[]byte) []byte {
+	b := []byte("RIFF\x00\x00\x00\x00WEBP")
+	for _, c := range chunks {
+		b = append(b, c...)
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	return b
+}
+
+// animatedWebP makes an animated WebP file with a w×h canvas and frames made
+// by anmf.
+func animatedWebP(w, h, loops int, frames ...[]byte) []byte {
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpFlagAnimation
+	putLE24(vp8x[4:], w-1)
+	putLE24(vp8x[7:], h-1)
+	anim := make([]byte, 6)
+	binary.LittleEndian.PutUint16(anim[4:], uint16(loops))
+	return webpFile(append([][]byte{webpChunk("VP8X", vp8x), webpChunk("ANIM", anim)}, frames...)...)
+}
+
+// anmf makes a frame of a 1×1 image at x, y, which must be even.
+func anmf(x, y, duration int, flags byte) []byte {
+	head := make([]byte, 16)
+	putLE24(head[0:], x/2)
+	putLE24(head[3:], y/2)
+	putLE24(head[12:], duration)
+	head[15] = flags
+	return webpChunk("ANMF", append(head, pixel...))
+}
+
+// drawn shows which pixels of img aren't transparent.
+func drawn(img image.Image) string {
+	b := img.Bounds()
+	s := ""
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a >= 0x8000 {
+				s += "X"
+			} else {
+				s += "."
+			}
+		}
+	}
+	return s
+}
+
+func TestReadWebPAnimation(t *testing.T) {
+	b := animatedWebP(4, 1, 3,
+		anmf(0, 0, 100, 0),
+		anmf(2, 0, 40, 0x01),
+		anmf(0, 0, 250, 0x02),
+	)
+	anim, err := readWebPAnimation(bytes.NewReader(b), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anim.canvas != image.Rect(0, 0, 4, 1) || anim.loops != 3 {
+		t.Errorf("canvas is %v looped %d times, want 4×1 looped 3 times", anim.canvas, anim.loops)
+	}
+	want := []webpFrame{
+		{bounds: image.Rect(0, 0, 1, 1), duration: 100, blend: true},
+		{bounds: image.Rect(2, 0, 3, 1), duration: 40, blend: true, dispose: true},
+		{bounds: image.Rect(0, 0, 1, 1), duration: 250},
+	}
+	if len(anim.frames) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(anim.frames), len(want))
+	}
+	for i, f := range anim.frames {
+		w := want[i]
+		if f.bounds != w.bounds || f.duration != w.duration || f.blend != w.blend || f.dispose != w.dispose {
+			t.Errorf("frame %d is %+v, want %+v", i, f, w)
+		}
+		if f.n != int64(len(pixel)) || !bytes.Equal(b[f.off:f.off+f.n], pixel) {
+			t.Errorf("frame %d isn't where its image is", i)
+		}
+	}
+
+	// it stops counting once there are too many
+	if anim, err := readWebPAnimation(bytes.NewReader(b), 1); err != nil || len(anim.frames) != 2 {
+		t.Errorf("with a limit of 1, got %v and %v", anim, err)
+	}
+}
+
+func TestReadWebPAnimationStill(t *testing.T) {
+	files := []struct {
+		name string
+		b    []byte
+	}{
+		{"simple", webpFile(pixel)},
+		{"extended", webpFile(webpChunk("VP8X", make([]byte, 10)), pixel)},
+	}
+	for _, f := range files {
+		if anim, err := readWebPAnimation(bytes.NewReader(f.b), 10); anim != nil || err != nil {
+			t.Errorf("%s: got %v and %v, want nothing", f.name, anim, err)
+		}
+	}
+}
+
+func TestReadWebPAnimationMalformed(t *testing.T) {
+	files := []struct {
+		name string
+		b    []byte
+	}{
+		{"no frames", animatedWebP(4, 1, 0)},
+		{"frame outside the canvas", animatedWebP(2, 1, 0, anmf(2, 0, 0, 0))},
+		{"truncated", animatedWebP(4, 1, 0, anmf(0, 0, 0, 0))[:40]},
+	}
+	for _, f := range files {
+		if _, err := readWebPAnimation(bytes.NewReader(f.b), 10); err == nil {
+			t.Errorf("%s: no error", f.name)
+		}
+	}
+}
+
+func TestDecodeWebPAnimation(t *testing.T) {
+	b := animatedWebP(4, 1, 3,
+		anmf(0, 0, 100, 0),
+		anmf(2, 0, 40, 0x01),
+		anmf(0, 0, 250, 0x02),
+	)
+	r := bytes.NewReader(b)
+	anim, err := readWebPAnimation(r, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := decodeWebPAnimation(r, anim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Config.Width != 4 || g.Config.Height != 1 {
+		t.Errorf("GIF is %d×%d, want 4×1", g.Config.Width, g.Config.Height)
+	}
+	if g.LoopCount != 2 {
+		t.Errorf("loop count is %d, want 2", g.LoopCount)
+	}
+	// the second frame is cleared before the third is drawn
+	wantDrawn := []string{"X...", "X.X.", "X..."}
+	wantDelay := []int{10, 4, 25}
+	for i, img := range g.Image {
+		if d := drawn(img); d != wantDrawn[i] {
+			t.Errorf("frame %d is %s, want %s", i, d, wantDrawn[i])
+		}
+		if g.Delay[i] != wantDelay[i] {
+			t.Errorf("frame %d has a delay of %d, want %d", i, g.Delay[i], wantDelay[i])
+		}
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecodeWebPFirstFrame(t *testing.T) {
+	b := animatedWebP(4, 1, 0, anmf(2, 0, 100, 0), anmf(0, 0, 100, 0))
+	img, err := decodeWebP(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := drawn(img); d != "..X." {
+		t.Errorf("got %s, want ..X.", d)
+	}
+
+	// still images are decoded as they are
+	if img, err := decodeWebP(bytes.NewReader(webpFile(pixel))); err != nil || img.Bounds().Dx() != 1 {
+		t.Errorf("still image: got %v and %v", img, err)
+	}
+}
+
+func TestWebPAnimationLimits(t *testing.T) {
+	b := animatedWebP(4, 1, 0, anmf(0, 0, 100, 0), anmf(2, 0, 100, 0), anmf(0, 0, 100, 0))
+	c := &Cache{
+		store: memStore{
+			"anim":  {"a.webp", "image/webp", b},
+			"still": {"b.webp", "image/webp", webpFile(pixel)},
+		},
+		encoders: map[string]Encoder{".gif": GIFEncoder{}, ".jpg": JPEGEncoder{}},
+	}
+	gifSize := size{w: 2, h: 2, ext: ".gif"}
+
+	tests := []struct {
+		frames   int
+		pixels   int64
+		size     size
+		animated bool
+	}{
+		{300, 12, gifSize, true},
+		{2, 12, gifSize, false},
+		{300, 11, gifSize, false},
+		{300, 12, size{w: 2, h: 2, ext: ".jpg"}, false},
+		{300, 12, size{w: 2, h: 2, cover: true, ext: ".gif"}, false},
+	}
+	for _, tt := range tests {
+		c.maxFrames, c.maxPixels = tt.frames, tt.pixels
+		f, _ := c.store.Open("anim")
+		g, err := c.decodeAnimation(f, tt.size)
+		if err != nil {
+			t.Errorf("%d frames, %d pixels, %v: %v", tt.frames, tt.pixels, tt.size, err)
+		} else if (g != nil) != tt.animated {
+			t.Errorf("%d frames, %d pixels, %v: animated is %v, want %v", tt.frames, tt.pixels, tt.size, g != nil, tt.animated)
+		}
+	}
+
+	if !c.Animated("anim") {
+		t.Error("animated image isn't Animated")
+	}
+	if c.Animated("still") {
+		t.Error("still image is Animated")
+	}
+}